@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gocarina/gocsv"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/chainprofile"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/ingest"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+// classification verdicts written by classify
+const (
+	verdictFOT       = "fot"
+	verdictNotFOT    = "not fot"
+	verdictUndecided = "could not decide"
+)
+
+func runClassify(ctx context.Context, args []string) error {
+	var (
+		fs             = flag.NewFlagSet("classify", flag.ContinueOnError)
+		cfg            = defaultConfig()
+		inputKind      = fs.String("input-kind", inputCalls, "kind of the input rows, calls or txs")
+		swapBackOutput = fs.String("swap-back-output", "", "file the swap back report is written to, txs input only")
+		dbPath         = fs.String("db", "", "SQLite database the decided verdicts are recorded in, with the transfers they are based on, and overrides are read from")
+		chain          = fs.String("chain", "ethereum", "profile of the chain of the RPC endpoints, the verdicts are recorded and the overrides looked up for it")
+		onlyTokens     = fs.String("tokens", "", "comma separated tokens the input rows are filtered on, all tokens if empty")
+		skipInvalid    = fs.Bool("skip-invalid", false, "skip the invalid input rows with a warning instead of failing")
+	)
+	cfg.Input = "erc20_transfer_calls.csv"
+	cfg.Output = "output.csv"
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	profile, err := chainprofile.Get(*chain)
+	if err != nil {
+		return usageErrorf("invalid -chain: %v", err)
+	}
+	if err := profile.CheckClassification(); err != nil {
+		return err
+	}
+	if *inputKind != inputCalls && *inputKind != inputTxs {
+		return usageErrorf("invalid -input-kind %q", *inputKind)
+	}
+	if *swapBackOutput != "" && *inputKind != inputTxs {
+		return usageErrorf("-swap-back-output needs -input-kind %s", inputTxs)
+	}
+	opts := ingest.Options{SkipInvalid: *skipInvalid}
+	if opts.Tokens, err = parseAddresses("tokens", *onlyTokens); err != nil {
+		return err
+	}
+
+	var (
+		calls    []*ingest.TransferCall
+		txHashes []common.Hash
+	)
+	if *inputKind == inputCalls {
+		calls, err = readTransferCalls(ctx, &cfg, opts)
+	} else {
+		txHashes, err = readTxHashes(ctx, &cfg, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	rpcClient, closeClient, err := dial(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	if *inputKind == inputTxs {
+		logger.Infow("extracting transfer calls", "txs", len(txHashes))
+		f := fetcher.NewFetcher(rpcClient, cfg.fetcherConfig())
+		var callFrames map[common.Hash]*jsonrpc.CallFrame
+		if calls, callFrames, err = extractTransferCalls(ctx, f, txHashes); err != nil {
+			return err
+		}
+		if *swapBackOutput != "" {
+			if err := writeSwapBackReports(*swapBackOutput, calls, callFrames); err != nil {
+				return err
+			}
+		}
+	}
+
+	var store server.Store
+	if *dbPath != "" {
+		if store, err = server.NewSQLStore(*dbPath); err != nil {
+			return err
+		}
+		defer store.Close()
+	}
+	overrides, err := loadOverrides(&cfg, store)
+	if err != nil {
+		return err
+	}
+
+	// overridden tokens are not simulated
+	var (
+		scenarios  = make([]*jsonrpc.TransferScenario, 0, len(calls))
+		overridden = make(map[common.Address]*server.Override)
+		now        = time.Now()
+	)
+	for _, call := range calls {
+		if _, ok := overridden[call.Token]; ok {
+			continue
+		}
+		override, err := server.ActiveOverride(overrides, *chain, call.Token, now)
+		if err != nil {
+			return err
+		}
+		if override != nil {
+			overridden[call.Token] = override
+			continue
+		}
+		s, err := call.Scenario()
+		if err != nil {
+			return err
+		}
+		// simulate on the state before the block of the transfer, so the sender most likely has enough balance
+		blockNumber := hexutil.MustDecodeBig(s.BlockNumber)
+		s.BlockNumber = hexutil.EncodeBig(new(big.Int).Sub(blockNumber, big.NewInt(1)))
+		scenarios = append(scenarios, s)
+	}
+	logger.Infow("classifying", "scenarios", len(scenarios), "overridden", len(overridden))
+
+	// don't need erc20BalanceSlotProbe
+	batch := classifier.NewBatchClassifier(classifier.NewClassifierWithGas(rpcClient, nil, profile.GasConfig()), cfg.batchClassifierConfig())
+	results := batch.ClassifyNewTokens(ctx, scenarios)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	out, err := createOutput(cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := gocsv.DefaultCSVWriter(out)
+	writer.Write([]string{"token", "verdict", "num_equal", "num_less", "num_failed", "override"})
+
+	tokens := make(map[common.Address]struct{}, len(results)+len(overridden))
+	for token := range results {
+		tokens[token] = struct{}{}
+	}
+	for token := range overridden {
+		tokens[token] = struct{}{}
+	}
+	var undecided int
+	for _, token := range sortedTokens(tokens) {
+		if override, ok := overridden[token]; ok {
+			verdict := verdictNotFOT
+			if override.IsFeeOnTransfer {
+				verdict = verdictFOT
+			}
+			writer.Write([]string{token.String(), verdict, "0", "0", "0", override.Reason})
+			continue
+		}
+		result := results[token]
+		verdict := verdictNotFOT
+		switch {
+		case result.Err != nil:
+			logger.Infow("could not classify token", "token", token, "error", result.Err)
+			verdict = verdictUndecided
+		case result.NumEqual+result.NumLess < cfg.MinScenarios:
+			logger.Infow("too few transfers simulated", "token", token, "simulated", result.NumEqual+result.NumLess)
+			verdict = verdictUndecided
+		case result.IsFeeOnTransfer:
+			verdict = verdictFOT
+		}
+		if verdict == verdictUndecided {
+			undecided++
+		} else if store != nil {
+			if err := store.PutVerdict(scenariosVerdict(*chain, token, result, calls)); err != nil {
+				return fmt.Errorf("could not record verdict of %s: %w", token, err)
+			}
+		}
+		writer.Write([]string{
+			token.String(),
+			verdict,
+			strconv.Itoa(result.NumEqual),
+			strconv.Itoa(result.NumLess),
+			strconv.Itoa(result.NumFailed),
+			"",
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("could not write output: %w", err)
+	}
+	if undecided > 0 {
+		return fmt.Errorf("%w: %d/%d", errUndecided, undecided, len(results))
+	}
+	return nil
+}
+
+// scenariosVerdict is the verdict of a token classified by simulating the transfer calls, which are its evidence.
+func scenariosVerdict(chain string, token common.Address, result *classifier.BatchResult, calls []*ingest.TransferCall) *server.Verdict {
+	verdict := &server.Verdict{
+		Chain:           chain,
+		Token:           token,
+		IsErc20:         true,
+		IsFeeOnTransfer: result.IsFeeOnTransfer,
+		FeeBps:          result.FeeBps,
+		Method:          server.MethodScenarios,
+		UpdatedAt:       time.Now(),
+	}
+	for _, call := range calls {
+		if call.Token != token {
+			continue
+		}
+		// the transfers are simulated on the state before their block
+		if blockNumber, ok := new(big.Int).SetString(call.BlockNumber, 0); ok && blockNumber.Uint64()-1 > verdict.BlockNumber {
+			verdict.BlockNumber = blockNumber.Uint64() - 1
+		}
+		verdict.Evidence = append(verdict.Evidence, "tx:"+call.TxHash.Hex())
+	}
+	return verdict
+}
+
+// writeSwapBackReports flags tokens that swap back or add liquidity inside transfer, which changes pool reserves mid-swap.
+func writeSwapBackReports(path string, calls []*ingest.TransferCall, callFrames map[common.Hash]*jsonrpc.CallFrame) error {
+	out, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := gocsv.DefaultCSVWriter(out)
+	writer.Write([]string{"token", "num_transfers", "num_transfers_with_swap_back", "threshold", "liquidity_threshold"})
+
+	tokens := make(map[common.Address]struct{})
+	for _, call := range calls {
+		tokens[call.Token] = struct{}{}
+	}
+	for _, token := range sortedTokens(tokens) {
+		report := classifier.DetectSwapBacks(token, callFrames)
+		var threshold, liquidityThreshold string
+		if report.Threshold != nil {
+			threshold = report.Threshold.String()
+		}
+		if report.LiquidityThreshold != nil {
+			liquidityThreshold = report.LiquidityThreshold.String()
+		}
+		writer.Write([]string{
+			token.String(),
+			strconv.Itoa(report.NumTransfers),
+			strconv.Itoa(report.NumTransfersWithSwapBack),
+			threshold,
+			liquidityThreshold,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func runProbeSlot(ctx context.Context, args []string) error {
+	var (
+		fs     = flag.NewFlagSet("probe-slot", flag.ContinueOnError)
+		cfg    = defaultConfig()
+		token  = fs.String("token", "", "token address")
+		wallet = fs.String("wallet", "", "wallet address")
+	)
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	if !common.IsHexAddress(*token) {
+		return usageErrorf("invalid -token %q", *token)
+	}
+	if !common.IsHexAddress(*wallet) {
+		return usageErrorf("invalid -wallet %q", *wallet)
+	}
+
+	rpcClient, closeClient, err := dial(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	slot, err := classifier.NewProbe(rpcClient).ProbeBalanceSlot(common.HexToAddress(*token), common.HexToAddress(*wallet))
+	if err != nil {
+		return fmt.Errorf("could not probe balance slot: %w", err)
+	}
+	out, err := createOutput(cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = fmt.Fprintln(out, slot.Hex())
+	return err
+}
+
+func runIsErc20(ctx context.Context, args []string) error {
+	var (
+		fs     = flag.NewFlagSet("is-erc20", flag.ContinueOnError)
+		cfg    = defaultConfig()
+		tokens = fs.String("tokens", "", "comma separated contract addresses, also read from the arguments")
+		block  = fs.String("block", "latest", "block the code is read at")
+	)
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	var addresses []common.Address
+	for _, a := range append(strings.Split(*tokens, ","), fs.Args()...) {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		if !common.IsHexAddress(a) {
+			return usageErrorf("invalid address %q", a)
+		}
+		addresses = append(addresses, common.HexToAddress(a))
+	}
+	if len(addresses) == 0 {
+		return usageErrorf("no contract address")
+	}
+
+	rpcClient, closeClient, err := dial(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	out, err := createOutput(cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := gocsv.DefaultCSVWriter(out)
+	writer.Write([]string{"token", "is_erc20"})
+	for _, address := range addresses {
+		code, err := jsonrpc.GetCode(rpcClient, address, *block)
+		if err != nil {
+			return fmt.Errorf("could not get code of %s: %w", address, err)
+		}
+		writer.Write([]string{address.String(), strconv.FormatBool(len(code) > 0 && classifier.IsErc20(code))})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func runFetch(ctx context.Context, args []string) error {
+	var (
+		fs         = flag.NewFlagSet("fetch", flag.ContinueOnError)
+		cfg        = defaultConfig()
+		withDiffs  = fs.Bool("prestate-diffs", false, "also fetch the prestateTracer diffs of the txs")
+		withFrames = fs.Bool("call-frames", true, "fetch the call frames of the txs")
+	)
+	cfg.Input = "erc20_transfer_tx.csv"
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	if cfg.CachePath == "" {
+		return usageErrorf("fetch needs a cache to fetch into, set -cache")
+	}
+
+	txHashes, err := readTxHashes(ctx, &cfg, ingest.Options{})
+	if err != nil {
+		return err
+	}
+	rpcClient, closeClient, err := dial(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	logger.Infow("fetching", "txs", len(txHashes))
+	f := fetcher.NewFetcher(rpcClient, cfg.fetcherConfig())
+	if _, err := f.Transactions(ctx, txHashes); err != nil {
+		return fmt.Errorf("could not fetch transactions: %w", err)
+	}
+	if _, err := f.Receipts(ctx, txHashes); err != nil {
+		return fmt.Errorf("could not fetch receipts: %w", err)
+	}
+	if *withFrames {
+		if _, err := f.CallFrames(ctx, txHashes); err != nil {
+			return fmt.Errorf("could not fetch call frames: %w", err)
+		}
+	}
+	if *withDiffs {
+		if _, err := f.PrestateDiffs(ctx, txHashes); err != nil {
+			return fmt.Errorf("could not fetch prestate diffs: %w", err)
+		}
+	}
+	return nil
+}
+
+func runConvert(ctx context.Context, args []string) error {
+	var (
+		fs  = flag.NewFlagSet("convert", flag.ContinueOnError)
+		cfg = defaultConfig()
+	)
+	cfg.Input = "erc20_transfer_tx.csv"
+	cfg.Output = "erc20_transfer_calls.csv"
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+
+	txHashes, err := readTxHashes(ctx, &cfg, ingest.Options{})
+	if err != nil {
+		return err
+	}
+	rpcClient, closeClient, err := dial(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	f := fetcher.NewFetcher(rpcClient, cfg.fetcherConfig())
+	calls, _, err := extractTransferCalls(ctx, f, txHashes)
+	if err != nil {
+		return err
+	}
+	logger.Infow("extracted transfer calls", "txs", len(txHashes), "calls", len(calls))
+
+	out, err := createOutput(cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := gocsv.Marshal(calls, out); err != nil {
+		return fmt.Errorf("could not write output: %w", err)
+	}
+	return nil
+}
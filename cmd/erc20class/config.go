@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/chainprofile"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/ingest"
+)
+
+// Config is the configuration shared by the subcommands. Each setting is read, by increasing precedence, from its
+// default, the JSON config file given by -config or ERC20CLASS_CONFIG, its environment variable and its flag.
+type Config struct {
+	// RPCURLs are the endpoints requests are spread over, trace requests are only sent to the endpoints supporting them
+	RPCURLs []string `json:"rpc_urls"`
+	// RPCRateLimit is the maximum number of requests per second sent to each endpoint
+	RPCRateLimit float64 `json:"rpc_rate_limit"`
+	// CachePath is the bbolt file RPC responses are cached in, shared by runs and tokens. Empty disables the cache.
+	CachePath string `json:"cache_path"`
+	Input     string `json:"input"`
+	// InputFormat is the format of Input: csv, jsonl or parquet, found from its extension if empty
+	InputFormat string `json:"input_format"`
+	// InputColumns maps the fields of the input records to its columns, e.g. token=contract_address, for exports whose
+	// columns are not the usual ones
+	InputColumns string `json:"input_columns"`
+	// Output is the file results are written to, - for stdout
+	Output string `json:"output"`
+	// Workers is the number of requests or simulations run concurrently
+	Workers int `json:"workers"`
+	// MaxRetries is the number of times a request is retried on transient RPC errors
+	MaxRetries int `json:"max_retries"`
+	// MinScenarios is the number of transfers of a token that must be simulated to classify it
+	MinScenarios int `json:"min_scenarios"`
+	// Overrides is a JSON file of server.Override pinning the verdicts of tokens, empty for none
+	Overrides string `json:"overrides"`
+	// Chains maps the names of chain profiles to their endpoints, for serve to classify the tokens of several chains.
+	// It is only read from the config file, RPCURLs are used for the single chain given by -chain if it is empty.
+	Chains map[string][]string `json:"chains"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		RPCURLs:      []string{"http://localhost:8545"},
+		RPCRateLimit: 50,
+		CachePath:    "rpc_cache.db",
+		Output:       "-",
+		Workers:      classifier.DefaultBatchClassifierConfig.Workers,
+		MaxRetries:   classifier.DefaultBatchClassifierConfig.MaxRetries,
+		MinScenarios: 1,
+	}
+}
+
+// option is a setting of Config that can be set by a flag and an environment variable.
+type option struct {
+	name  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{"rpc", "ERC20CLASS_RPC_URLS", "comma separated JSON-RPC endpoints", (*stringsValue)(&c.RPCURLs)},
+		{"rpc-rate-limit", "ERC20CLASS_RPC_RATE_LIMIT", "maximum requests per second to each endpoint", (*floatValue)(&c.RPCRateLimit)},
+		{"cache", "ERC20CLASS_CACHE", "RPC response cache file, empty to disable", (*stringValue)(&c.CachePath)},
+		{"input", "ERC20CLASS_INPUT", "input file", (*stringValue)(&c.Input)},
+		{"input-format", "ERC20CLASS_INPUT_FORMAT", "format of the input file: csv, jsonl or parquet, empty to find it from its extension", (*stringValue)(&c.InputFormat)},
+		{"input-columns", "ERC20CLASS_INPUT_COLUMNS", "comma separated field=column pairs mapping the input columns", (*stringValue)(&c.InputColumns)},
+		{"output", "ERC20CLASS_OUTPUT", "output file, - for stdout", (*stringValue)(&c.Output)},
+		{"workers", "ERC20CLASS_WORKERS", "number of concurrent requests or simulations", (*intValue)(&c.Workers)},
+		{"max-retries", "ERC20CLASS_MAX_RETRIES", "retries of requests failing with transient errors", (*intValue)(&c.MaxRetries)},
+		{"min-scenarios", "ERC20CLASS_MIN_SCENARIOS", "simulated transfers needed to classify a token", (*intValue)(&c.MinScenarios)},
+		{"overrides", "ERC20CLASS_OVERRIDES", "JSON file of verdict overrides, empty for none", (*stringValue)(&c.Overrides)},
+	}
+}
+
+// parseConfig parses the flags of a subcommand into cfg, which holds the subcommand's defaults. The flags of the
+// subcommand must be registered on fs already.
+func parseConfig(fs *flag.FlagSet, cfg *Config, args []string) error {
+	configPath := fs.String("config", os.Getenv("ERC20CLASS_CONFIG"), "JSON config file")
+	options := cfg.options()
+	for _, o := range options {
+		fs.Var(o.value, o.name, fmt.Sprintf("%s (env %s)", o.usage, o.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// flags are parsed into cfg first, keep them to apply them again over the config file and env
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	if *configPath != "" {
+		encoded, err := os.ReadFile(*configPath)
+		if err != nil {
+			return fmt.Errorf("could not read config: %w", err)
+		}
+		if err := json.Unmarshal(encoded, cfg); err != nil {
+			return fmt.Errorf("could not decode config %s: %w", *configPath, err)
+		}
+	}
+	for _, o := range options {
+		if env, ok := os.LookupEnv(o.env); ok {
+			if err := o.value.Set(env); err != nil {
+				return fmt.Errorf("invalid %s: %w", o.env, err)
+			}
+		}
+		if value, ok := flags[o.name]; ok {
+			if err := o.value.Set(value); err != nil {
+				return fmt.Errorf("invalid -%s: %w", o.name, err)
+			}
+		}
+	}
+	return cfg.validate()
+}
+
+func (c *Config) validate() error {
+	if len(c.RPCURLs) == 0 {
+		return fmt.Errorf("no RPC endpoint")
+	}
+	if c.RPCRateLimit <= 0 {
+		return fmt.Errorf("rpc rate limit must be positive")
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("workers must be positive")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+	switch ingest.Format(c.InputFormat) {
+	case "", ingest.FormatCSV, ingest.FormatJSONL, ingest.FormatParquet:
+	default:
+		return fmt.Errorf("unknown input format %q", c.InputFormat)
+	}
+	if _, err := ingest.ParseMapping(c.InputColumns); err != nil {
+		return err
+	}
+	for name, urls := range c.Chains {
+		if _, err := chainprofile.Get(name); err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			return fmt.Errorf("no RPC endpoint for chain %s", name)
+		}
+	}
+	return nil
+}
+
+// chainConfig returns the config of chain name of Chains, with its endpoints and a cache file of its own since a cache
+// file can only be opened once, e.g. rpc_cache.bsc.db.
+func (c *Config) chainConfig(name string) Config {
+	config := *c
+	config.RPCURLs = c.Chains[name]
+	if config.CachePath != "" {
+		ext := filepath.Ext(config.CachePath)
+		config.CachePath = strings.TrimSuffix(config.CachePath, ext) + "." + name + ext
+	}
+	return config
+}
+
+func (c *Config) fetcherConfig() fetcher.Config {
+	config := fetcher.DefaultConfig
+	config.Workers = c.Workers
+	config.MaxRetries = c.MaxRetries
+	return config
+}
+
+func (c *Config) batchClassifierConfig() classifier.BatchClassifierConfig {
+	config := classifier.DefaultBatchClassifierConfig
+	config.Workers = c.Workers
+	config.MaxRetries = c.MaxRetries
+	return config
+}
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+
+type stringsValue []string
+
+func (v *stringsValue) String() string { return strings.Join(*v, ",") }
+func (v *stringsValue) Set(s string) error {
+	*v = nil
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*v = append(*v, part)
+		}
+	}
+	return nil
+}
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) Set(s string) error {
+	i, err := strconv.Atoi(s)
+	*v = intValue(i)
+	return err
+}
+
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	*v = floatValue(f)
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rpc_urls": ["http://file"], "workers": 2, "max_retries": 7}`), 0644))
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want func(*Config)
+	}{
+		{
+			name: "defaults",
+			want: func(c *Config) {},
+		},
+		{
+			name: "config file",
+			args: []string{"-config", path},
+			want: func(c *Config) {
+				c.RPCURLs = []string{"http://file"}
+				c.Workers = 2
+				c.MaxRetries = 7
+			},
+		},
+		{
+			name: "env over config file",
+			env:  map[string]string{"ERC20CLASS_CONFIG": path, "ERC20CLASS_WORKERS": "4"},
+			want: func(c *Config) {
+				c.RPCURLs = []string{"http://file"}
+				c.Workers = 4
+				c.MaxRetries = 7
+			},
+		},
+		{
+			name: "flags over env",
+			env:  map[string]string{"ERC20CLASS_CONFIG": path, "ERC20CLASS_WORKERS": "4"},
+			args: []string{"-workers", "16", "-rpc", "http://a, http://b", "-cache", ""},
+			want: func(c *Config) {
+				c.RPCURLs = []string{"http://a", "http://b"}
+				c.Workers = 16
+				c.MaxRetries = 7
+				c.CachePath = ""
+			},
+		},
+		{
+			name: "input format and columns",
+			args: []string{"-input-format", "parquet", "-input-columns", "token=contract_address"},
+			want: func(c *Config) {
+				c.InputFormat = "parquet"
+				c.InputColumns = "token=contract_address"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"ERC20CLASS_CONFIG", "ERC20CLASS_WORKERS"} {
+				t.Setenv(env, tt.env[env])
+				if _, ok := tt.env[env]; !ok {
+					os.Unsetenv(env)
+				}
+			}
+			want := defaultConfig()
+			tt.want(&want)
+
+			cfg := defaultConfig()
+			require.NoError(t, parseConfig(flag.NewFlagSet(tt.name, flag.ContinueOnError), &cfg, tt.args))
+			assert.Equal(t, want, cfg)
+		})
+	}
+
+	for _, args := range [][]string{{"-input-format", "xlsx"}, {"-input-columns", "token"}} {
+		cfg := defaultConfig()
+		assert.Error(t, parseConfig(flag.NewFlagSet("invalid", flag.ContinueOnError), &cfg, args), args)
+	}
+}
+
+func TestChainConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"chains": {"ethereum": ["http://eth"], "bsc": ["http://bsc"]}}`), 0644))
+	cfg := defaultConfig()
+	require.NoError(t, parseConfig(flag.NewFlagSet("chains", flag.ContinueOnError), &cfg, []string{"-config", path}))
+
+	bsc := cfg.chainConfig("bsc")
+	assert.Equal(t, []string{"http://bsc"}, bsc.RPCURLs)
+	assert.Equal(t, "rpc_cache.bsc.db", bsc.CachePath)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"chains": {"goerli": ["http://goerli"]}}`), 0644))
+	cfg = defaultConfig()
+	assert.Error(t, parseConfig(flag.NewFlagSet("unknown chain", flag.ContinueOnError), &cfg, []string{"-config", path}))
+}
+
+func TestExitCode(t *testing.T) {
+	stderr := io.Discard
+	assert.Equal(t, exitOK, exitCode(nil, stderr))
+	assert.Equal(t, exitUsage, exitCode(usageErrorf("invalid -token"), stderr))
+	assert.Equal(t, exitUndecided, exitCode(errUndecided, stderr))
+	assert.Equal(t, exitError, exitCode(os.ErrNotExist, stderr))
+}
@@ -0,0 +1,178 @@
+// Command erc20class classifies ERC20 tokens from the transfers found in CSV exports, or serves classifications over
+// HTTP and gRPC. Run it with -h for the subcommands and their flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/cache"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// exit codes
+const (
+	exitOK = 0
+	// exitError is returned when a subcommand fails
+	exitError = 1
+	// exitUsage is returned on invalid flags, config or arguments
+	exitUsage = 2
+	// exitUndecided is returned when a subcommand completes but some tokens could not be classified
+	exitUndecided = 3
+)
+
+// errUndecided is returned by subcommands that complete but could not classify some tokens
+var errUndecided = errors.New("some tokens could not be classified")
+
+// usageError is returned by subcommands on invalid arguments
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, args ...interface{}) error {
+	return usageError{err: fmt.Errorf(format, args...)}
+}
+
+type command struct {
+	name    string
+	summary string
+	// run parses args and runs the subcommand
+	run func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"classify", "classify tokens as fee-on-transfer by simulating their transfers", runClassify},
+	{"probe-slot", "find the storage slot of the balance of a wallet", runProbeSlot},
+	{"is-erc20", "check that contracts implement ERC20", runIsErc20},
+	{"fetch", "fetch the transactions, receipts and traces of transfers into the RPC cache", runFetch},
+	{"convert", "convert transfer transactions to the transfer calls classify reads", runConvert},
+	{"serve", "serve classifications over HTTP and gRPC", runServe},
+	{"watch", "classify new tokens as they are deployed and paired", runWatch},
+	{"override", "pin the verdicts of tokens the classifiers get wrong", runOverride},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		printUsage(stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return exitCode(c.run(ctx, args[1:]), stderr)
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+	printUsage(stderr)
+	return exitUsage
+}
+
+func exitCode(err error, stderr io.Writer) int {
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "%s\n", err)
+		return exitUsage
+	case errors.Is(err, errUndecided):
+		fmt.Fprintf(stderr, "%s\n", err)
+		return exitUndecided
+	default:
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return exitError
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: erc20class <command> [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun erc20class <command> -h for the flags of a command.\n")
+	fmt.Fprintf(w, "\nexit codes: %d ok, %d error, %d usage, %d some tokens could not be classified\n",
+		exitOK, exitError, exitUsage, exitUndecided)
+}
+
+// parseFlags parses the flags of a subcommand, fs holds the flags specific to it. Invalid flags are usage errors.
+func parseFlags(fs *flag.FlagSet, cfg *Config, args []string) error {
+	fs.SetOutput(os.Stderr)
+	err := parseConfig(fs, cfg, args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return usageError{err: err}
+}
+
+// dialPool connects to the RPC endpoints of cfg, without the RPC cache.
+func dialPool(ctx context.Context, cfg *Config) (*jsonrpc.Pool, error) {
+	burst := int(cfg.RPCRateLimit)
+	if burst < 1 {
+		burst = 1
+	}
+	pool, err := jsonrpc.DialPool(ctx, cfg.RPCURLs, cfg.RPCRateLimit, burst, jsonrpc.DefaultPoolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not dial rpc: %w", err)
+	}
+	return pool, nil
+}
+
+// dial connects to the RPC endpoints of cfg, through the RPC cache if one is configured. close must be called once
+// done with the client.
+func dial(ctx context.Context, cfg *Config) (client jsonrpc.Client, close func(), err error) {
+	pool, err := dialPool(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.CachePath == "" {
+		return pool, func() {}, nil
+	}
+	rpcCache, err := cache.NewBoltCache(cfg.CachePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open rpc cache: %w", err)
+	}
+	cachedClient, err := cache.Dial(pool, rpcCache, cache.DefaultPolicy)
+	if err != nil {
+		rpcCache.Close()
+		return nil, nil, err
+	}
+	return cachedClient, func() { rpcCache.Close() }, nil
+}
+
+// createOutput opens the output file of cfg, - is stdout.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not create output: %w", err)
+	}
+	return f, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+func runOverride(ctx context.Context, args []string) error {
+	var (
+		fs              = flag.NewFlagSet("override", flag.ContinueOnError)
+		cfg             = defaultConfig()
+		dbPath          = fs.String("db", "", "SQLite database the overrides are kept in")
+		chain           = fs.String("chain", "ethereum", "name of the chain of the token")
+		tokenHex        = fs.String("token", "", "token overridden")
+		isFeeOnTransfer = fs.Bool("fot", false, "pin the token as fee-on-transfer")
+		feeBps          = fs.Int("fee-bps", 0, "fee charged to us in basis points, with -fot")
+		reason          = fs.String("reason", "", "why the classification is overridden")
+		author          = fs.String("author", os.Getenv("USER"), "who pins the verdict")
+		expires         = fs.Duration("expires", 0, "time the override applies for, forever if 0")
+		remove          = fs.Bool("delete", false, "delete the override of -token instead")
+		list            = fs.Bool("list", false, "write the current overrides to -output as JSON lines instead")
+	)
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	if *dbPath == "" {
+		return usageErrorf("missing -db")
+	}
+	if !*list && !common.IsHexAddress(*tokenHex) {
+		return usageErrorf("invalid -token %q", *tokenHex)
+	}
+	if *expires < 0 {
+		return usageErrorf("-expires must not be negative")
+	}
+
+	store, err := server.NewSQLStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if *list {
+		overrides, err := store.Overrides()
+		if err != nil {
+			return fmt.Errorf("could not list overrides: %w", err)
+		}
+		out, err := createOutput(cfg.Output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		enc := json.NewEncoder(out)
+		for _, override := range overrides {
+			if err := enc.Encode(override); err != nil {
+				return fmt.Errorf("could not write override: %w", err)
+			}
+		}
+		return nil
+	}
+
+	token := common.HexToAddress(*tokenHex)
+	now := time.Now()
+	if *remove {
+		if err := store.DeleteOverride(*chain, token, now); err != nil {
+			return fmt.Errorf("could not delete override of %s: %w", token, err)
+		}
+		logger.Infow("deleted override", "chain", *chain, "token", token)
+		return nil
+	}
+	override := &server.Override{
+		Chain:           *chain,
+		Token:           token,
+		IsFeeOnTransfer: *isFeeOnTransfer,
+		FeeBps:          *feeBps,
+		Reason:          *reason,
+		Author:          *author,
+		CreatedAt:       now,
+	}
+	if *expires > 0 {
+		expiresAt := now.Add(*expires)
+		override.ExpiresAt = &expiresAt
+	}
+	if err := store.PutOverride(override); err != nil {
+		return fmt.Errorf("could not override %s: %w", token, err)
+	}
+	logger.Infow("overrode verdict", "chain", *chain, "token", token, "isFeeOnTransfer", *isFeeOnTransfer, "feeBps", *feeBps)
+	return nil
+}
+
+// loadOverrides returns the overrides of the -overrides file, then the ones of store if it keeps overrides. It returns
+// nil if there are none.
+func loadOverrides(cfg *Config, store server.Store) (server.Overrides, error) {
+	var overrides server.MultiOverrides
+	if cfg.Overrides != "" {
+		list, err := server.LoadOverrides(cfg.Overrides)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, list)
+	}
+	if o, ok := store.(server.Overrides); ok {
+		overrides = append(overrides, o)
+	}
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	return overrides, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/chainprofile"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/grpcapi"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/grpcapi/classifierpb"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+func runServe(ctx context.Context, args []string) error {
+	var (
+		fs           = flag.NewFlagSet("serve", flag.ContinueOnError)
+		cfg          = defaultConfig()
+		addr         = fs.String("addr", ":8080", "address the HTTP API listens on")
+		grpcAddr     = fs.String("grpc-addr", "", "address the gRPC API listens on, disabled if empty")
+		dbPath       = fs.String("db", "server.db", "file verdicts and queued jobs are kept in, a SQLite database keeping the history of verdicts if it ends with .sqlite")
+		chain        = fs.String("chain", "ethereum", "profile of the chain of the RPC endpoints, its name in the API paths, ignored if the config file has chains")
+		jobs         = fs.Int("jobs", server.DefaultConfig.Workers, "number of jobs run concurrently")
+		jobTimeout   = fs.Duration("job-timeout", server.DefaultConfig.JobTimeout, "timeout of a job")
+		txsThreshold = fs.Int("txs-threshold", 100, "recent Transfer events fetched to classify a token")
+		recheck      = fs.Duration("recheck-interval", 0, "base interval classified tokens are checked again at, shorter for risky and popular tokens, 0 to disable")
+		webhook      = fs.String("webhook", "", "URL verdict changes found by the checks are POSTed to, they are only logged if empty")
+	)
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	if *jobs < 1 || *txsThreshold < 1 {
+		return usageErrorf("-jobs and -txs-threshold must be positive")
+	}
+
+	chains, closeChains, err := dialChains(ctx, &cfg, *chain, *txsThreshold)
+	if err != nil {
+		return err
+	}
+	defer closeChains()
+	store, err := openStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	overrides, err := loadOverrides(&cfg, store)
+	if err != nil {
+		return err
+	}
+
+	srvConfig := server.DefaultConfig
+	srvConfig.Workers = *jobs
+	srvConfig.JobTimeout = *jobTimeout
+	srvConfig.Overrides = overrides
+	srv := server.NewServer(store, chains, srvConfig)
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return fmt.Errorf("could not listen: %w", err)
+		}
+		grpcServer := grpc.NewServer()
+		grpcConfig := grpcapi.DefaultConfig
+		grpcConfig.Overrides = overrides
+		classifierpb.RegisterClassifierServer(grpcServer, grpcapi.NewService(chains, grpcConfig))
+		go func() {
+			<-ctx.Done()
+			grpcServer.GracefulStop()
+		}()
+		go func() {
+			logger.Infow("serving gRPC", "addr", *grpcAddr, "chains", chainNames(chains))
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Errorw("could not serve gRPC", "error", err)
+			}
+		}()
+	}
+
+	if *recheck > 0 {
+		var sink server.ChangeSink = server.ChangeSinkFunc(func(context.Context, *server.ChangeEvent) error { return nil })
+		if *webhook != "" {
+			sink = server.NewWebhookSink(*webhook, nil)
+		}
+		schedulerConfig := server.DefaultSchedulerConfig
+		schedulerConfig.Interval = *recheck
+		if schedulerConfig.MinInterval > *recheck {
+			schedulerConfig.MinInterval = *recheck
+		}
+		go server.NewScheduler(srv, sink, schedulerConfig).Run(ctx)
+	}
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- srv.Run(ctx)
+	}()
+	httpServer := &http.Server{Addr: *addr, Handler: srv.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	logger.Infow("serving", "addr", *addr, "chains", chainNames(chains))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("could not serve: %w", err)
+	}
+	// jobs interrupted by the shutdown stay queued in the store
+	return <-runErr
+}
+
+// dialChains dials the endpoints of each chain of cfg.Chains, or of cfg.RPCURLs for chain name if it has none, and
+// checks they are on the chain of its profile.
+func dialChains(ctx context.Context, cfg *Config, name string, txsThreshold int) (map[string]*server.Chain, func(), error) {
+	configs := map[string]Config{name: *cfg}
+	if len(cfg.Chains) > 0 {
+		configs = make(map[string]Config, len(cfg.Chains))
+		for name := range cfg.Chains {
+			configs[name] = cfg.chainConfig(name)
+		}
+	}
+
+	var (
+		chains  = make(map[string]*server.Chain, len(configs))
+		closers []func()
+	)
+	closeAll := func() {
+		for _, closeClient := range closers {
+			closeClient()
+		}
+	}
+	for name, chainCfg := range configs {
+		profile, err := chainprofile.Get(name)
+		if err != nil {
+			closeAll()
+			return nil, nil, usageErrorf("invalid -chain: %v", err)
+		}
+		rpcClient, closeClient, err := dial(ctx, &chainCfg)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("could not dial %s: %w", name, err)
+		}
+		closers = append(closers, closeClient)
+		chain := server.NewChain(rpcClient, profile, chainCfg.fetcherConfig(), chainCfg.batchClassifierConfig(), txsThreshold)
+		if err := chain.CheckChainID(); err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		chains[name] = chain
+	}
+	return chains, closeAll, nil
+}
+
+func chainNames(chains map[string]*server.Chain) []string {
+	names := make([]string, 0, len(chains))
+	for name := range chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openStore opens a SQLStore if path ends with .sqlite or .sqlite3, a BoltStore otherwise.
+func openStore(path string) (server.Store, error) {
+	switch filepath.Ext(path) {
+	case ".sqlite", ".sqlite3":
+		return server.NewSQLStore(path)
+	default:
+		return server.NewBoltStore(path)
+	}
+}
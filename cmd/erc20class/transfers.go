@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/ingest"
+)
+
+// input kinds
+const (
+	// inputCalls are ingest.TransferCall rows
+	inputCalls = "calls"
+	// inputTxs are types.TransferRecord rows, the transfer calls are extracted from the traces of their txs
+	inputTxs = "txs"
+)
+
+// readInput reads the records of the input file with a stream of package ingest, e.g. ingest.TransferCalls.
+func readInput[T any](ctx context.Context, cfg *Config, opts ingest.Options, read func(context.Context, ingest.Reader, ingest.Options) (<-chan T, <-chan error)) ([]T, error) {
+	mapping, err := ingest.ParseMapping(cfg.InputColumns)
+	if err != nil {
+		return nil, err
+	}
+	opts.Mapping = mapping
+	r, err := ingest.Open(cfg.Input, ingest.Format(cfg.InputFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	records, err := ingest.Collect(read(ctx, r, opts))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", cfg.Input, err)
+	}
+	return records, nil
+}
+
+func readTransferCalls(ctx context.Context, cfg *Config, opts ingest.Options) ([]*ingest.TransferCall, error) {
+	return readInput(ctx, cfg, opts, ingest.TransferCalls)
+}
+
+// readTxHashes returns the distinct tx hashes of the types.TransferRecord rows of the input file.
+func readTxHashes(ctx context.Context, cfg *Config, opts ingest.Options) ([]common.Hash, error) {
+	transfers, err := readInput(ctx, cfg, opts, ingest.TransferRecords)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		seen     = make(map[common.Hash]struct{})
+		txHashes []common.Hash
+	)
+	for _, t := range transfers {
+		if _, ok := seen[t.TxHash]; ok {
+			continue
+		}
+		seen[t.TxHash] = struct{}{}
+		txHashes = append(txHashes, t.TxHash)
+	}
+	return txHashes, nil
+}
+
+// extractTransferCalls fetches the txs and returns the successful transfer and transferFrom calls found in their call
+// frames, and the call frames.
+func extractTransferCalls(ctx context.Context, f *fetcher.Fetcher, txHashes []common.Hash) ([]*ingest.TransferCall, map[common.Hash]*jsonrpc.CallFrame, error) {
+	txs, err := f.Transactions(ctx, txHashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch transactions: %w", err)
+	}
+	receipts, err := f.Receipts(ctx, txHashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch receipts: %w", err)
+	}
+	callFrames, err := f.CallFrames(ctx, txHashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch call frames: %w", err)
+	}
+
+	var calls []*ingest.TransferCall
+	for _, txHash := range txHashes {
+		tx, receipt, frame := txs[txHash], receipts[txHash], callFrames[txHash]
+		if tx == nil || receipt == nil || frame == nil {
+			continue
+		}
+		iterateCallFrames(frame, func(c *jsonrpc.CallFrame) {
+			if c.To == nil {
+				return
+			}
+			transfer, err := classifier.DecodeTransferCall(c.Input)
+			if errors.Is(err, classifier.ErrNotTransferCall) {
+				return
+			}
+			if err != nil {
+				logger.Debugw("could not decode transfer call", "txHash", txHash, "error", err)
+				return
+			}
+			if new(big.Int).SetBytes(c.Output).Cmp(big.NewInt(1)) != 0 {
+				// transfer returned false
+				return
+			}
+			calls = append(calls, &ingest.TransferCall{
+				MsgSender:            c.From,
+				Token:                *c.To,
+				IsTransferFrom:       transfer.IsTransferFrom,
+				Sender:               transfer.From,
+				Receiver:             transfer.To,
+				Amount:               transfer.Amount,
+				BlockNumber:          receipt.BlockNumber.String(),
+				MaxFeePerGas:         tx.GasFeeCap().String(),
+				MaxPriorityFeePerGas: tx.GasTipCap().String(),
+				TxHash:               txHash,
+				TxIndex:              uint64(receipt.TransactionIndex),
+			})
+		})
+	}
+	return calls, callFrames, nil
+}
+
+func iterateCallFrames(call *jsonrpc.CallFrame, callback func(*jsonrpc.CallFrame)) {
+	callback(call)
+	for i := range call.Calls {
+		iterateCallFrames(&call.Calls[i], callback)
+	}
+}
+
+// sortedTokens returns the tokens of a set sorted so outputs are stable across runs.
+func sortedTokens(tokens map[common.Address]struct{}) []common.Address {
+	sorted := make([]common.Address, 0, len(tokens))
+	for token := range tokens {
+		sorted = append(sorted, token)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Hex() < sorted[j].Hex()
+	})
+	return sorted
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/chainprofile"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/watcher"
+)
+
+func runWatch(ctx context.Context, args []string) error {
+	var (
+		fs            = flag.NewFlagSet("watch", flag.ContinueOnError)
+		cfg           = defaultConfig()
+		chain         = fs.String("chain", "ethereum", "profile of the chain of the RPC endpoints, its name in the results")
+		wsURL         = fs.String("ws", "", "websocket endpoint new heads are subscribed to, the RPC endpoints are polled if empty")
+		pollInterval  = fs.Duration("poll-interval", 0, "interval the RPC endpoints are polled for new heads at, the one of the chain profile if 0")
+		fromBlock     = fs.Uint64("from-block", 0, "first block processed, the next confirmed head if 0")
+		confirmations = fs.Uint64("confirmations", watcher.DefaultConfig.Confirmations, "blocks on top of a block before it is processed, so the tokens of reorged blocks are not reported")
+		factories     = fs.String("factories", "", "comma separated factories whose PairCreated and PoolCreated events are watched, known for the ones of the chain profile, any if empty")
+		ignoreTokens  = fs.String("ignore-tokens", "", "comma separated tokens not classified when paired, the wrapped native token and stablecoins of the chain profile if empty")
+		dbPath        = fs.String("db", "", "server database verdicts are also stored in, serve must not have a bolt database open")
+	)
+	if err := parseFlags(fs, &cfg, args); err != nil {
+		return err
+	}
+	profile, err := chainprofile.Get(*chain)
+	if err != nil {
+		return usageErrorf("invalid -chain: %v", err)
+	}
+	watcherConfig := watcher.DefaultConfig
+	watcherConfig.FromBlock = *fromBlock
+	watcherConfig.Confirmations = *confirmations
+	if *factories == "known" {
+		watcherConfig.Factories = profile.FactoryAddresses()
+	} else if watcherConfig.Factories, err = parseAddresses("factories", *factories); err != nil {
+		return err
+	}
+	if watcherConfig.IgnoreTokens, err = parseAddresses("ignore-tokens", *ignoreTokens); err != nil {
+		return err
+	}
+	if len(watcherConfig.IgnoreTokens) == 0 {
+		watcherConfig.IgnoreTokens = profile.QuoteTokens()
+	}
+	if *pollInterval < 0 {
+		return usageErrorf("-poll-interval must not be negative")
+	}
+	if *pollInterval == 0 {
+		*pollInterval = profile.PollInterval
+	}
+
+	// the watcher only reads the head blocks, whose responses must not outlive a reorg, so it doesn't use the RPC cache
+	rpcClient, err := dialPool(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+	serverChain := server.NewChain(rpcClient, profile, cfg.fetcherConfig(), cfg.batchClassifierConfig(), 0)
+	if err := serverChain.CheckChainID(); err != nil {
+		return err
+	}
+
+	var heads watcher.Heads = watcher.NewPollHeads(rpcClient, *pollInterval)
+	if *wsURL != "" {
+		wsClient, err := rpc.DialContext(ctx, *wsURL)
+		if err != nil {
+			return fmt.Errorf("could not dial websocket: %w", err)
+		}
+		defer wsClient.Close()
+		heads = watcher.NewSubscribeHeads(wsClient)
+	}
+
+	out, err := createOutput(cfg.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	sink := watcher.MultiSink{watcher.NewJSONSink(out)}
+	var store server.Store
+	if *dbPath != "" {
+		if store, err = openStore(*dbPath); err != nil {
+			return err
+		}
+		defer store.Close()
+		sink = append(sink, watcher.NewStoreSink(store))
+	}
+	if watcherConfig.Overrides, err = loadOverrides(&cfg, store); err != nil {
+		return err
+	}
+
+	w := watcher.NewWatcher(*chain, serverChain, heads, sink, watcherConfig)
+	logger.Infow("watching new tokens", "chain", *chain, "subscribe", *wsURL != "")
+	return w.Run(ctx)
+}
+
+// parseAddresses parses the comma separated addresses of a flag.
+func parseAddresses(name, list string) ([]common.Address, error) {
+	var addresses []common.Address
+	for _, a := range strings.Split(list, ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		if !common.IsHexAddress(a) {
+			return nil, usageErrorf("invalid address %q in -%s", a, name)
+		}
+		addresses = append(addresses, common.HexToAddress(a))
+	}
+	return addresses, nil
+}
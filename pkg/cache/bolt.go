@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("rpc")
+
+// BoltCache keeps entries in a single-file bbolt database. Only one process can open the file at a time.
+type BoltCache struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// NewBoltCache opens or creates the database at path.
+func NewBoltCache(path string) (*BoltCache, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open cache %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create cache bucket: %w", err)
+	}
+	return &BoltCache{db: db, now: time.Now}, nil
+}
+
+func (c *BoltCache) Get(key string) ([]byte, bool, error) {
+	var (
+		value   []byte
+		expired bool
+	)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		e := tx.Bucket(boltBucket).Get([]byte(key))
+		if e == nil {
+			return nil
+		}
+		v, exp, err := entry(e).decode(c.now())
+		if err != nil {
+			return err
+		}
+		// the value is only valid during the transaction
+		value, expired = append([]byte(nil), v...), exp
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if expired {
+		return nil, false, c.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(boltBucket).Delete([]byte(key))
+		})
+	}
+	return value, value != nil, nil
+}
+
+func (c *BoltCache) Set(key string, value []byte, ttl time.Duration) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), newEntry(value, ttl, c.now()))
+	})
+}
+
+func (c *BoltCache) Close() error {
+	return c.db.Close()
+}
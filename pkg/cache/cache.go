@@ -0,0 +1,103 @@
+// Package cache caches JSON-RPC responses in a key-value store shared between tokens and runs. Responses are keyed by
+// chain ID, method and params (see Key). Immutable data, like traces of mined txs and the state of final blocks, is kept
+// forever while data depending on the head block or on recent blocks, which may be reorged, expires (see Policy).
+// Wrap a jsonrpc.Client with NewClient to cache its responses.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache is a key-value store whose entries may expire.
+type Cache interface {
+	// Get returns ok false if there is no entry for key or it expired
+	Get(key string) (value []byte, ok bool, err error)
+	// Set stores value for key, it never expires if ttl is 0
+	Set(key string, value []byte, ttl time.Duration) error
+	Close() error
+}
+
+// Key returns the key of the response of a request: the chain ID and method, then a hash of the params.
+func Key(chainID uint64, method string, params ...interface{}) (string, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("could not encode params: %w", err)
+	}
+	hash := sha256.Sum256(encoded)
+	return fmt.Sprintf("%d/%s/%s", chainID, method, hex.EncodeToString(hash[:])), nil
+}
+
+// entry is the encoding of a value with its expiry: 8 bytes of expiry time in unix nanoseconds, 0 if it never expires,
+// then the value.
+type entry []byte
+
+var errCorruptedEntry = errors.New("corrupted cache entry")
+
+func newEntry(value []byte, ttl time.Duration, now time.Time) entry {
+	e := make(entry, 8+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(e, uint64(now.Add(ttl).UnixNano()))
+	}
+	copy(e[8:], value)
+	return e
+}
+
+func (e entry) decode(now time.Time) (value []byte, expired bool, err error) {
+	if len(e) < 8 {
+		return nil, false, errCorruptedEntry
+	}
+	expiry := binary.BigEndian.Uint64(e)
+	if expiry != 0 && now.UnixNano() >= int64(expiry) {
+		return nil, true, nil
+	}
+	return e[8:], false, nil
+}
+
+// MemoryCache keeps entries in memory, it is safe for concurrent use.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+	now     func() time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]entry), now: time.Now}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool, error) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	value, expired, err := e.decode(c.now())
+	if err != nil || expired {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = newEntry(value, ttl, c.now())
+	return nil
+}
+
+func (c *MemoryCache) Close() error {
+	return nil
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaches(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(t *testing.T, now func() time.Time) Cache
+	}{
+		{
+			name: "memory",
+			new: func(t *testing.T, now func() time.Time) Cache {
+				c := NewMemoryCache()
+				c.now = now
+				return c
+			},
+		},
+		{
+			name: "bolt",
+			new: func(t *testing.T, now func() time.Time) Cache {
+				c, err := NewBoltCache(filepath.Join(t.TempDir(), "cache.db"))
+				require.NoError(t, err)
+				c.now = now
+				return c
+			},
+		},
+		{
+			name: "fs",
+			new: func(t *testing.T, now func() time.Time) Cache {
+				c, err := NewFSCache(t.TempDir())
+				require.NoError(t, err)
+				c.now = now
+				return c
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			c := tt.new(t, func() time.Time { return now })
+			defer c.Close()
+
+			_, ok, err := c.Get("missing")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			require.NoError(t, c.Set("forever", []byte(`"receipt"`), 0))
+			require.NoError(t, c.Set("head", []byte(`"0x10"`), 10*time.Second))
+
+			value, ok, err := c.Get("head")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte(`"0x10"`), value)
+
+			now = now.Add(time.Minute)
+			_, ok, err = c.Get("head")
+			require.NoError(t, err)
+			assert.False(t, ok)
+			value, ok, err = c.Get("forever")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte(`"receipt"`), value)
+
+			// overwritten
+			require.NoError(t, c.Set("forever", []byte(`"trace"`), 0))
+			value, _, err = c.Get("forever")
+			require.NoError(t, err)
+			assert.Equal(t, []byte(`"trace"`), value)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	a, err := Key(1, "eth_getTransactionReceipt", hash)
+	require.NoError(t, err)
+	b, err := Key(1, "eth_getTransactionReceipt", hash)
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	other, err := Key(56, "eth_getTransactionReceipt", hash)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, other)
+	other, err = Key(1, "eth_getTransactionByHash", hash)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, other)
+}
+
+func TestPolicyTTL(t *testing.T) {
+	var (
+		p     = Policy{HeadTTL: time.Second, FinalityDepth: 64}
+		head  = func() uint64 { return 0x100 }
+		mined = json.RawMessage(`{"blockNumber":"0x10"}`)
+		// tx 0x1 is mined in a recent block, tx 0x2 is pending
+		txBlock = func(txHash interface{}) (uint64, bool) {
+			switch txHash {
+			case common.HexToHash("0x1"):
+				return 0xff, true
+			case common.HexToHash("0x2"):
+				return 0, false
+			}
+			return 0x10, true
+		}
+	)
+	tests := []struct {
+		name     string
+		method   string
+		params   []interface{}
+		response json.RawMessage
+		head     func() uint64
+		wantTTL  time.Duration
+		wantOK   bool
+	}{
+		{"receipt", "eth_getTransactionReceipt", []interface{}{common.Hash{}}, mined, head, 0, true},
+		{"receipt of a recent block", "eth_getTransactionReceipt", []interface{}{common.Hash{}}, json.RawMessage(`{"blockNumber":"0xff"}`), head, time.Second, true},
+		{"pending tx", "eth_getTransactionByHash", []interface{}{common.Hash{}}, json.RawMessage(`{"blockNumber":null}`), head, 0, false},
+		{"trace", "debug_traceTransaction", []interface{}{common.Hash{}, nil}, nil, head, 0, true},
+		{"trace of a recent tx", "debug_traceTransaction", []interface{}{common.HexToHash("0x1"), nil}, nil, head, time.Second, true},
+		{"trace of a pending tx", "debug_traceTransaction", []interface{}{common.HexToHash("0x2"), nil}, nil, head, 0, false},
+		{"block number", "eth_blockNumber", nil, nil, head, time.Second, true},
+		{"code at block", "eth_getCode", []interface{}{common.Address{}, "0x10"}, nil, head, 0, true},
+		{"code at a recent block", "eth_getCode", []interface{}{common.Address{}, "0xff"}, nil, head, time.Second, true},
+		{"code with unknown head", "eth_getCode", []interface{}{common.Address{}, "0x10"}, nil, func() uint64 { return 0 }, time.Second, true},
+		{"code at latest", "eth_getCode", []interface{}{common.Address{}, "latest"}, nil, head, time.Second, true},
+		{"code at block hash", "eth_getCode", []interface{}{common.Address{}, common.Hash{}.Hex()}, nil, head, 0, true},
+		{"storage at block", "eth_getStorageAt", []interface{}{common.Address{}, common.Hash{}, hexutil.Uint64(16)}, nil, head, 0, true},
+		{"call at block", "eth_call", []interface{}{nil, "0x10", nil}, nil, head, 0, true},
+		{"call without block", "eth_call", []interface{}{nil}, nil, head, time.Second, true},
+		{"logs of a range", "eth_getLogs", []interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x10"}}, nil, head, 0, true},
+		{"logs to a recent block", "eth_getLogs", []interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x100"}}, nil, head, time.Second, true},
+		{"logs to latest", "eth_getLogs", []interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "latest"}}, nil, head, time.Second, true},
+		{"send", "eth_sendRawTransaction", []interface{}{"0x"}, nil, head, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantOK {
+				assert.True(t, p.Cacheable(tt.method))
+			}
+			ttl, ok := p.TTL(tt.method, tt.params, tt.response, tt.head, txBlock)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantTTL, ttl)
+		})
+	}
+	assert.False(t, p.Cacheable("eth_sendRawTransaction"))
+}
+
+// countingClient returns the method name of each request, null for eth_getTransactionReceipt and a tx mined in block
+// 0x10 for eth_getTransactionByHash. The head is block 0x100, eth_blockNumber requests are not counted.
+type countingClient struct {
+	requests int
+}
+
+func (c *countingClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if method == "eth_blockNumber" {
+		return json.Unmarshal([]byte(`"0x100"`), result)
+	}
+	c.requests++
+	if method == "eth_getTransactionReceipt" {
+		return json.Unmarshal([]byte("null"), result)
+	}
+	if method == "eth_getTransactionByHash" {
+		return json.Unmarshal([]byte(`{"blockNumber":"0x10"}`), result)
+	}
+	if method == "eth_sendRawTransaction" {
+		return errors.New("nonce too low")
+	}
+	encoded, _ := json.Marshal(method)
+	return json.Unmarshal(encoded, result)
+}
+
+func (c *countingClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for i := range b {
+		b[i].Error = c.CallContext(ctx, b[i].Result, b[i].Method, b[i].Args...)
+	}
+	return nil
+}
+
+func TestClient(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		counter = &countingClient{}
+		client  = NewClient(counter, NewMemoryCache(), 1, DefaultPolicy)
+		result  string
+	)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.CallContext(ctx, &result, "eth_getCode", common.Address{}, "0x10"))
+		assert.Equal(t, "eth_getCode", result)
+	}
+	assert.Equal(t, 1, counter.requests)
+
+	// null responses and errors are not cached
+	var receipt *struct{}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.CallContext(ctx, &receipt, "eth_getTransactionReceipt", common.Hash{}))
+		assert.Error(t, client.CallContext(ctx, nil, "eth_sendRawTransaction", "0x"))
+	}
+	assert.Equal(t, 5, counter.requests)
+
+	// only misses are sent in batches
+	var results [2]string
+	batch := []rpc.BatchElem{
+		{Method: "eth_getCode", Args: []interface{}{common.Address{}, "0x10"}, Result: &results[0]},
+		{Method: "eth_getCode", Args: []interface{}{common.Address{}, "0x11"}, Result: &results[1]},
+	}
+	require.NoError(t, client.BatchCallContext(ctx, batch))
+	assert.Equal(t, [2]string{"eth_getCode", "eth_getCode"}, results)
+	assert.Equal(t, 6, counter.requests)
+
+	// a trace is cached once its tx, fetched once, is final
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.CallContext(ctx, &result, "debug_traceTransaction", common.Hash{}, nil))
+		assert.Equal(t, "debug_traceTransaction", result)
+	}
+	assert.Equal(t, 8, counter.requests)
+}
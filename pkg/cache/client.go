@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// Client caches the responses of a jsonrpc.Client. Errors and null responses are not cached.
+type Client struct {
+	client  jsonrpc.Client
+	cache   Cache
+	chainID uint64
+	policy  Policy
+
+	// head is the last head block number, fetched at headAt
+	headMu sync.Mutex
+	head   uint64
+	headAt time.Time
+}
+
+var _ jsonrpc.Client = (*Client)(nil)
+
+// NewClient rpcClient is either a *rpc.Client or a *jsonrpc.Pool to use several endpoints. The chain ID is part of the
+// keys so one cache can be shared by clients of several chains.
+func NewClient(rpcClient jsonrpc.Client, cache Cache, chainID uint64, policy Policy) *Client {
+	return &Client{
+		client:  rpcClient,
+		cache:   cache,
+		chainID: chainID,
+		policy:  policy,
+	}
+}
+
+// Dial gets the chain ID of rpcClient and returns a client caching its responses in cache.
+func Dial(rpcClient jsonrpc.Client, cache Cache, policy Policy) (*Client, error) {
+	chainID, err := jsonrpc.ChainID(rpcClient)
+	if err != nil {
+		return nil, fmt.Errorf("could not get chain id: %w", err)
+	}
+	return NewClient(rpcClient, cache, chainID, policy), nil
+}
+
+// headBlock returns the head block number, refreshed every HeadTTL, 0 if it could not be fetched.
+func (c *Client) headBlock() uint64 {
+	c.headMu.Lock()
+	defer c.headMu.Unlock()
+	if c.head != 0 && time.Since(c.headAt) < c.policy.HeadTTL {
+		return c.head
+	}
+	head, err := jsonrpc.BlockNumber(c.client)
+	if err != nil {
+		logger.Warnw("could not get head block, caching recent blocks until it is known", "error", err)
+		return 0
+	}
+	c.head, c.headAt = head, time.Now()
+	return head
+}
+
+// txBlock returns the block number of a mined tx, ok false if it is pending or could not be fetched. The tx is
+// cached like any other, so it is usually fetched once.
+func (c *Client) txBlock(txHash interface{}) (uint64, bool) {
+	var tx *struct {
+		BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+	}
+	if err := c.CallContext(context.Background(), &tx, "eth_getTransactionByHash", txHash); err != nil {
+		logger.Warnw("could not get tx, not caching its trace", "tx", txHash, "error", err)
+		return 0, false
+	}
+	if tx == nil || tx.BlockNumber == nil {
+		return 0, false
+	}
+	return uint64(*tx.BlockNumber), true
+}
+
+// lookup returns the key of a request and its cached response if any. key is empty if the request must not be cached.
+func (c *Client) lookup(method string, args []interface{}) (key string, cached []byte, err error) {
+	if !c.policy.Cacheable(method) {
+		return "", nil, nil
+	}
+	if key, err = Key(c.chainID, method, args...); err != nil {
+		return "", nil, err
+	}
+	cached, ok, err := c.cache.Get(key)
+	if err != nil {
+		logger.Warnw("could not read cache", "method", method, "error", err)
+		return key, nil, nil
+	}
+	if !ok {
+		cached = nil
+	}
+	return key, cached, nil
+}
+
+func (c *Client) store(key, method string, args []interface{}, response json.RawMessage) {
+	if key == "" || len(response) == 0 || string(response) == "null" {
+		return
+	}
+	ttl, ok := c.policy.TTL(method, args, response, c.headBlock, c.txBlock)
+	if !ok {
+		return
+	}
+	if err := c.cache.Set(key, response, ttl); err != nil {
+		logger.Warnw("could not write cache", "key", key, "error", err)
+	}
+}
+
+func (c *Client) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	key, cached, err := c.lookup(method, args)
+	if err != nil {
+		return err
+	}
+	if cached != nil {
+		return json.Unmarshal(cached, result)
+	}
+	var response json.RawMessage
+	if err := c.client.CallContext(ctx, &response, method, args...); err != nil {
+		return err
+	}
+	c.store(key, method, args, response)
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response, result)
+}
+
+func (c *Client) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	type miss struct {
+		index int
+		key   string
+	}
+	var (
+		misses []miss
+		elems  []rpc.BatchElem
+	)
+	for i := range b {
+		key, cached, err := c.lookup(b[i].Method, b[i].Args)
+		if err != nil {
+			b[i].Error = err
+			continue
+		}
+		if cached != nil {
+			b[i].Error = json.Unmarshal(cached, b[i].Result)
+			continue
+		}
+		misses = append(misses, miss{index: i, key: key})
+		elems = append(elems, rpc.BatchElem{Method: b[i].Method, Args: b[i].Args, Result: new(json.RawMessage)})
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+	if err := c.client.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	for j, m := range misses {
+		if elems[j].Error != nil {
+			b[m.index].Error = elems[j].Error
+			continue
+		}
+		response := *elems[j].Result.(*json.RawMessage)
+		c.store(m.key, b[m.index].Method, b[m.index].Args, response)
+		b[m.index].Error = json.Unmarshal(response, b[m.index].Result)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FSCache keeps each entry in a file of dir named after the hash of its key, in a sub directory named after the first
+// byte of the hash so directories stay small. Several processes can share it, writes are atomic.
+type FSCache struct {
+	dir string
+	now func() time.Time
+}
+
+func NewFSCache(dir string) (*FSCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create cache dir: %w", err)
+	}
+	return &FSCache{dir: dir, now: time.Now}, nil
+}
+
+func (c *FSCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(hash[:])
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+func (c *FSCache) Get(key string) ([]byte, bool, error) {
+	path := c.path(key)
+	e, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	value, expired, err := entry(e).decode(c.now())
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", path, err)
+	}
+	if expired {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+	return value, true, nil
+}
+
+func (c *FSCache) Set(key string, value []byte, ttl time.Duration) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// write then rename so readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(newEntry(value, ttl, c.now())); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *FSCache) Close() error {
+	return nil
+}
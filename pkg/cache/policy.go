@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Policy decides which responses are cached and for how long. Requests on blocks given by hash, or by a number at
+// least FinalityDepth blocks below the head, are cached forever; requests on more recent blocks, which may be reorged,
+// and on block tags like "latest" expire after HeadTTL. Requests on a tx given by hash, its receipt and traces, are
+// cached like requests on the block of the tx.
+type Policy struct {
+	HeadTTL time.Duration
+	// FinalityDepth is the number of blocks below the head after which a block is assumed final
+	FinalityDepth uint64
+}
+
+var DefaultPolicy = Policy{
+	HeadTTL:       12 * time.Second,
+	FinalityDepth: 64,
+}
+
+// immutableMethods are the methods whose response never changes once it is not null
+var immutableMethods = map[string]bool{
+	"eth_chainId":        true,
+	"net_version":        true,
+	"eth_getBlockByHash": true,
+}
+
+// minedMethods are the methods whose response has the blockNumber of a tx, null while the tx is pending
+var minedMethods = map[string]bool{
+	"eth_getTransactionByHash":  true,
+	"eth_getTransactionReceipt": true,
+}
+
+// txMethods are the methods on a tx given by hash whose response has no blockNumber, it is found with txBlock
+var txMethods = map[string]bool{
+	"debug_traceTransaction": true,
+}
+
+// headMethods are the methods whose response changes with the head block
+var headMethods = map[string]bool{
+	"eth_blockNumber":          true,
+	"eth_gasPrice":             true,
+	"eth_maxPriorityFeePerGas": true,
+}
+
+// blockParams is the index of the block param of the methods reading the state at a block
+var blockParams = map[string]int{
+	"eth_getCode":             1,
+	"eth_getStorageAt":        2,
+	"eth_getBalance":          1,
+	"eth_getTransactionCount": 1,
+	"eth_call":                1,
+	"debug_traceCall":         1,
+	"eth_getBlockByNumber":    0,
+}
+
+// Cacheable returns false if the responses of method are never cached.
+func (p Policy) Cacheable(method string) bool {
+	_, ok := blockParams[method]
+	return ok || immutableMethods[method] || minedMethods[method] || txMethods[method] || headMethods[method] ||
+		method == "eth_getLogs"
+}
+
+// TTL returns how long the response of a request is cached, 0 for ever, ok is false if it must not be cached.
+// head returns the head block number, it is only called for requests on block numbers and 0 means it is unknown.
+// txBlock returns the block number of a tx given by hash, ok false if it is pending or unknown.
+func (p Policy) TTL(
+	method string, params []interface{}, response json.RawMessage, head func() uint64,
+	txBlock func(txHash interface{}) (number uint64, ok bool),
+) (ttl time.Duration, ok bool) {
+	if immutableMethods[method] {
+		return 0, true
+	}
+	if headMethods[method] {
+		return p.HeadTTL, true
+	}
+	if minedMethods[method] {
+		var mined struct {
+			BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+		}
+		if err := json.Unmarshal(response, &mined); err != nil || mined.BlockNumber == nil {
+			return 0, false
+		}
+		return p.blockTTL(uint64(*mined.BlockNumber), head), true
+	}
+	if txMethods[method] {
+		if len(params) == 0 {
+			return 0, false
+		}
+		// a trace of a tx in a block that may be reorged is cached like the tx
+		number, ok := txBlock(params[0])
+		if !ok {
+			return 0, false
+		}
+		return p.blockTTL(number, head), true
+	}
+	if method == "eth_getLogs" {
+		if len(params) != 1 {
+			return p.HeadTTL, true
+		}
+		return p.logFilterTTL(params[0], head), true
+	}
+	i, ok := blockParams[method]
+	if !ok {
+		return 0, false
+	}
+	// a missing block param is "latest"
+	if i >= len(params) {
+		return p.HeadTTL, true
+	}
+	return p.blockParamTTL(params[i], head), true
+}
+
+// blockTTL returns 0 if block number is final, HeadTTL otherwise.
+func (p Policy) blockTTL(number uint64, head func() uint64) time.Duration {
+	h := head()
+	if h >= p.FinalityDepth && number <= h-p.FinalityDepth {
+		return 0
+	}
+	return p.HeadTTL
+}
+
+func (p Policy) blockParamTTL(param interface{}, head func() uint64) time.Duration {
+	number, byHash, ok := blockRef(param)
+	switch {
+	case byHash:
+		return 0
+	case ok:
+		return p.blockTTL(number, head)
+	}
+	return p.HeadTTL
+}
+
+// logFilterTTL returns 0 if an eth_getLogs filter is on a block hash or a range of final block numbers.
+func (p Policy) logFilterTTL(param interface{}, head func() uint64) time.Duration {
+	filter, ok := param.(map[string]interface{})
+	if !ok {
+		return p.HeadTTL
+	}
+	if _, ok := filter["blockHash"]; ok {
+		return 0
+	}
+	from, okFrom := filter["fromBlock"]
+	to, okTo := filter["toBlock"]
+	if !okFrom || !okTo {
+		return p.HeadTTL
+	}
+	if _, _, ok := blockRef(from); !ok {
+		return p.HeadTTL
+	}
+	return p.blockParamTTL(to, head)
+}
+
+// blockRef returns the number of a block param, or byHash true if it is a hash. ok is false for tags like "latest".
+func blockRef(param interface{}) (number uint64, byHash bool, ok bool) {
+	switch b := param.(type) {
+	case string:
+		if !strings.HasPrefix(b, "0x") {
+			return 0, false, false
+		}
+		if len(b) == 66 {
+			return 0, true, true
+		}
+		n, err := hexutil.DecodeUint64(b)
+		return n, false, err == nil
+	case hexutil.Uint64:
+		return uint64(b), false, true
+	case uint64:
+		return b, false, true
+	case *hexutil.Big:
+		if b == nil || !b.ToInt().IsUint64() {
+			return 0, false, false
+		}
+		return b.ToInt().Uint64(), false, true
+	case *big.Int:
+		if b == nil || !b.IsUint64() {
+			return 0, false, false
+		}
+		return b.Uint64(), false, true
+	case rpc.BlockNumber:
+		return uint64(b), false, b >= 0
+	case rpc.BlockNumberOrHash:
+		if _, ok := b.Hash(); ok {
+			return 0, true, true
+		}
+		n, ok := b.Number()
+		return uint64(n), false, ok && n >= 0
+	case map[string]interface{}:
+		if _, ok := b["blockHash"]; ok {
+			return 0, true, true
+		}
+		if n, ok := b["blockNumber"]; ok {
+			return blockRef(n)
+		}
+	}
+	return 0, false, false
+}
@@ -0,0 +1,143 @@
+// Package chainprofile describes the chains tokens are classified on: their chain ID, how their nodes and the usual
+// providers behave and the DEXes tokens are traded on. The other packages read their chain specific settings from a
+// Profile rather than assuming Ethereum mainnet.
+package chainprofile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+)
+
+var (
+	// ErrUnknownChain is returned for a chain without a profile
+	ErrUnknownChain = errors.New("unknown chain")
+	// ErrNoPrestateTracer is returned to classify tokens on a chain whose nodes lack the prestateTracer
+	ErrNoPrestateTracer = errors.New("the nodes of the chain do not support the prestateTracer")
+)
+
+// FactoryKind is the interface of a DEX factory, the events it emits on pool creation
+type FactoryKind string
+
+const (
+	// UniswapV2 factories emit PairCreated
+	UniswapV2 FactoryKind = "uniswap-v2"
+	// UniswapV3 factories emit PoolCreated
+	UniswapV3 FactoryKind = "uniswap-v3"
+)
+
+// Factory is a DEX factory creating pools of token pairs.
+type Factory struct {
+	Name    string
+	Kind    FactoryKind
+	Address common.Address
+}
+
+// Tracers are the debug tracers the nodes of a chain support.
+type Tracers struct {
+	// CallTracer is debug_traceBlockByNumber with callTracer, to find the contracts created in a block
+	CallTracer bool
+	// PrestateTracer is debug_traceCall and debug_traceTransaction with prestateTracer and state overrides, to simulate
+	// transfers and read the balances of historical ones. Tokens are not classified without it.
+	PrestateTracer bool
+	// FromBlock is the first block the tracers work on, earlier blocks were executed by a legacy client
+	FromBlock uint64
+}
+
+// Profile holds the settings of a chain.
+type Profile struct {
+	// Name is the name of the chain in the API paths and the results
+	Name    string
+	ChainID uint64
+	// MaxLogsBlockRange is the largest block range of an eth_getLogs request the usual providers of the chain accept
+	MaxLogsBlockRange uint64
+	Tracers           Tracers
+	// LegacyGas is set for chains whose txs carry a gas price only, without EIP-1559 fee fields
+	LegacyGas bool
+	// Rollup is the kind of rollup of the chain, whose txs also pay for their L1 data, empty for L1 chains
+	Rollup classifier.Rollup
+	// GasLimit is the gas of the simulated calls other than transfers
+	GasLimit uint64
+	// PollInterval is how often new heads are polled, about the block time
+	PollInterval  time.Duration
+	WrappedNative common.Address
+	// Stablecoins are the major stablecoins, like the wrapped native token they are paired with every new token
+	Stablecoins []common.Address
+	Factories   []Factory
+}
+
+// GasConfig returns the gas handling of the classifiers on the chain.
+func (p *Profile) GasConfig() classifier.GasConfig {
+	return classifier.GasConfig{
+		GasLimit: p.GasLimit,
+		Legacy:   p.LegacyGas,
+		Rollup:   p.Rollup,
+	}
+}
+
+// FetcherConfig returns config with its eth_getLogs windows capped to the block range limit of the chain.
+func (p *Profile) FetcherConfig(config fetcher.Config) fetcher.Config {
+	if p.MaxLogsBlockRange == 0 {
+		return config
+	}
+	if config.Logs.MaxWindow == 0 || config.Logs.MaxWindow > p.MaxLogsBlockRange {
+		config.Logs.MaxWindow = p.MaxLogsBlockRange
+	}
+	if config.Logs.InitialWindow == 0 || config.Logs.InitialWindow > p.MaxLogsBlockRange {
+		config.Logs.InitialWindow = p.MaxLogsBlockRange
+	}
+	return config
+}
+
+// FactoryAddresses returns the addresses of the known factories.
+func (p *Profile) FactoryAddresses() []common.Address {
+	addresses := make([]common.Address, 0, len(p.Factories))
+	for _, f := range p.Factories {
+		addresses = append(addresses, f.Address)
+	}
+	return addresses
+}
+
+// QuoteTokens returns the wrapped native token and the stablecoins, the tokens new tokens are paired with.
+func (p *Profile) QuoteTokens() []common.Address {
+	return append([]common.Address{p.WrappedNative}, p.Stablecoins...)
+}
+
+// CanTrace returns whether the tracers work on a block.
+func (p *Profile) CanTrace(blockNumber uint64) bool {
+	return blockNumber >= p.Tracers.FromBlock
+}
+
+// CheckClassification fails with ErrNoPrestateTracer if the nodes of the chain can't classify tokens.
+func (p *Profile) CheckClassification() error {
+	if !p.Tracers.PrestateTracer {
+		return fmt.Errorf("%w %s", ErrNoPrestateTracer, p.Name)
+	}
+	return nil
+}
+
+// Get returns the profile of a chain by name.
+func Get(name string) (*Profile, error) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q, known chains are %v", ErrUnknownChain, name, Names())
+}
+
+// Names returns the names of the chains with a profile, sorted.
+func Names() []string {
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
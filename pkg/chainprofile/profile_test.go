@@ -0,0 +1,63 @@
+package chainprofile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+)
+
+func TestProfiles(t *testing.T) {
+	var (
+		names    = make(map[string]bool)
+		chainIDs = make(map[uint64]bool)
+	)
+	for _, p := range profiles {
+		assert.False(t, names[p.Name], "name %s used twice", p.Name)
+		assert.False(t, chainIDs[p.ChainID], "chain ID %d used twice", p.ChainID)
+		names[p.Name], chainIDs[p.ChainID] = true, true
+
+		assert.NotZero(t, p.ChainID, p.Name)
+		assert.NotZero(t, p.MaxLogsBlockRange, p.Name)
+		assert.NotZero(t, p.GasLimit, p.Name)
+		assert.NotZero(t, p.PollInterval, p.Name)
+		assert.NotZero(t, p.WrappedNative, p.Name)
+		assert.NotEmpty(t, p.Factories, p.Name)
+
+		got, err := Get(p.Name)
+		require.NoError(t, err)
+		assert.Same(t, p, got)
+	}
+
+	_, err := Get("goerli")
+	assert.ErrorIs(t, err, ErrUnknownChain)
+	assert.Equal(t, []string{"arbitrum", "avalanche", "base", "bsc", "ethereum", "optimism", "polygon"}, Names())
+}
+
+func TestFetcherConfig(t *testing.T) {
+	config := fetcher.DefaultConfig
+	config.Logs.InitialWindow = 1_000
+	config.Logs.MaxWindow = 50_000
+
+	got := Avalanche.FetcherConfig(config)
+	assert.Equal(t, uint64(1_000), got.Logs.InitialWindow)
+	assert.Equal(t, uint64(2_048), got.Logs.MaxWindow)
+
+	got = Ethereum.FetcherConfig(config)
+	assert.Equal(t, config.Logs, got.Logs)
+}
+
+func TestCanTrace(t *testing.T) {
+	assert.False(t, Arbitrum.CanTrace(22_207_817))
+	assert.True(t, Arbitrum.CanTrace(22_207_818))
+	assert.True(t, Ethereum.CanTrace(0))
+}
+
+func TestCheckClassification(t *testing.T) {
+	assert.NoError(t, Ethereum.CheckClassification())
+	p := *Ethereum
+	p.Tracers.PrestateTracer = false
+	assert.ErrorIs(t, p.CheckClassification(), ErrNoPrestateTracer)
+}
@@ -0,0 +1,160 @@
+package chainprofile
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+)
+
+var (
+	Ethereum = &Profile{
+		Name:              "ethereum",
+		ChainID:           1,
+		MaxLogsBlockRange: 100_000,
+		Tracers:           Tracers{CallTracer: true, PrestateTracer: true},
+		GasLimit:          500_000,
+		PollInterval:      3 * time.Second,
+		WrappedNative:     common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"), // USDC
+			common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"), // USDT
+			common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F"), // DAI
+		},
+		Factories: []Factory{
+			{Name: "Uniswap V2", Kind: UniswapV2, Address: common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")},
+			{Name: "SushiSwap", Kind: UniswapV2, Address: common.HexToAddress("0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac")},
+			{Name: "Uniswap V3", Kind: UniswapV3, Address: common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")},
+		},
+	}
+
+	BSC = &Profile{
+		Name:              "bsc",
+		ChainID:           56,
+		MaxLogsBlockRange: 5_000,
+		Tracers:           Tracers{CallTracer: true, PrestateTracer: true},
+		// BSC txs pay a fixed gas price, validators don't burn a base fee
+		LegacyGas:     true,
+		GasLimit:      500_000,
+		PollInterval:  time.Second,
+		WrappedNative: common.HexToAddress("0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0x55d398326f99059fF775485246999027B3197955"), // USDT
+			common.HexToAddress("0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d"), // USDC
+			common.HexToAddress("0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56"), // BUSD
+		},
+		Factories: []Factory{
+			{Name: "PancakeSwap V2", Kind: UniswapV2, Address: common.HexToAddress("0xcA143Ce32Fe78f1f7019d7d551a6402fC5350c73")},
+			{Name: "PancakeSwap V3", Kind: UniswapV3, Address: common.HexToAddress("0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865")},
+		},
+	}
+
+	Polygon = &Profile{
+		Name:              "polygon",
+		ChainID:           137,
+		MaxLogsBlockRange: 3_000,
+		Tracers:           Tracers{CallTracer: true, PrestateTracer: true},
+		GasLimit:          500_000,
+		PollInterval:      2 * time.Second,
+		WrappedNative:     common.HexToAddress("0x0d500B1d8E8eF31E21C99d1Db9A6444d3ADf1270"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359"), // USDC
+			common.HexToAddress("0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174"), // USDC.e
+			common.HexToAddress("0xc2132D05D31c914a87C6611C10748AEb04B58e8F"), // USDT
+			common.HexToAddress("0x8f3Cf7ad23Cd3CaDbD9735AFf958023239c6A063"), // DAI
+		},
+		Factories: []Factory{
+			{Name: "QuickSwap", Kind: UniswapV2, Address: common.HexToAddress("0x5757371414417b8C6CAad45bAeF941aBc7d3Ab32")},
+			{Name: "SushiSwap", Kind: UniswapV2, Address: common.HexToAddress("0xc35DADB65012eC5796536bD9864eD8773aBc74C4")},
+			{Name: "Uniswap V3", Kind: UniswapV3, Address: common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")},
+		},
+	}
+
+	Arbitrum = &Profile{
+		Name:              "arbitrum",
+		ChainID:           42161,
+		MaxLogsBlockRange: 100_000,
+		// blocks before Nitro can only be traced with arbtrace_ on a classic node
+		Tracers: Tracers{CallTracer: true, PrestateTracer: true, FromBlock: 22_207_818},
+		Rollup:  classifier.RollupArbitrum,
+		// the L1 data of a call paying gas is charged in L2 gas, calls need more gas than on Ethereum
+		GasLimit:      2_000_000,
+		PollInterval:  time.Second,
+		WrappedNative: common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0xaf88d065e77c8cC2239327C5EDb3A432268e5831"), // USDC
+			common.HexToAddress("0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8"), // USDC.e
+			common.HexToAddress("0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9"), // USDT
+		},
+		Factories: []Factory{
+			{Name: "Uniswap V3", Kind: UniswapV3, Address: common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")},
+			{Name: "SushiSwap", Kind: UniswapV2, Address: common.HexToAddress("0xc35DADB65012eC5796536bD9864eD8773aBc74C4")},
+			{Name: "Camelot", Kind: UniswapV2, Address: common.HexToAddress("0x6EcCab422D763aC031210895C81787E87B43A652")},
+		},
+	}
+
+	Optimism = &Profile{
+		Name:              "optimism",
+		ChainID:           10,
+		MaxLogsBlockRange: 10_000,
+		// blocks before Bedrock can only be traced by a legacy l2geth node
+		Tracers:       Tracers{CallTracer: true, PrestateTracer: true, FromBlock: 105_235_063},
+		Rollup:        classifier.RollupOPStack,
+		GasLimit:      500_000,
+		PollInterval:  2 * time.Second,
+		WrappedNative: common.HexToAddress("0x4200000000000000000000000000000000000006"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0x0b2C639c533813f4Aa9D7837CAf62653d097Ff85"), // USDC
+			common.HexToAddress("0x7F5c764cBc14f9669B88837ca1490cCa17c31607"), // USDC.e
+			common.HexToAddress("0x94b008aA00579c1307B0EF2c499aD98a8ce58e58"), // USDT
+			common.HexToAddress("0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1"), // DAI
+		},
+		Factories: []Factory{
+			{Name: "Uniswap V3", Kind: UniswapV3, Address: common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")},
+			{Name: "Uniswap V2", Kind: UniswapV2, Address: common.HexToAddress("0x0c3c1c532F1e39EdF36BE9Fe0bE1410313E074Bf")},
+		},
+	}
+
+	Base = &Profile{
+		Name:              "base",
+		ChainID:           8453,
+		MaxLogsBlockRange: 10_000,
+		Tracers:           Tracers{CallTracer: true, PrestateTracer: true},
+		Rollup:            classifier.RollupOPStack,
+		GasLimit:          500_000,
+		PollInterval:      2 * time.Second,
+		WrappedNative:     common.HexToAddress("0x4200000000000000000000000000000000000006"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), // USDC
+			common.HexToAddress("0xd9aAEc86B65D86f6A7B5B1b0c42FFA531710b6CA"), // USDbC
+			common.HexToAddress("0x50c5725949A6F0c72E6C4a641F24049A917DB0Cb"), // DAI
+		},
+		Factories: []Factory{
+			{Name: "Uniswap V3", Kind: UniswapV3, Address: common.HexToAddress("0x33128a8fC17869897dcE68Ed026d694621f6FDfD")},
+			{Name: "Uniswap V2", Kind: UniswapV2, Address: common.HexToAddress("0x8909Dc15e40173Ff4699343b6eB8132c65e18eC6")},
+		},
+	}
+
+	Avalanche = &Profile{
+		Name:              "avalanche",
+		ChainID:           43114,
+		MaxLogsBlockRange: 2_048,
+		Tracers:           Tracers{CallTracer: true, PrestateTracer: true},
+		GasLimit:          500_000,
+		PollInterval:      2 * time.Second,
+		WrappedNative:     common.HexToAddress("0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7"),
+		Stablecoins: []common.Address{
+			common.HexToAddress("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"), // USDC
+			common.HexToAddress("0xA7D7079b0FEaD91F3e65f86E8915Cb59c1a4C664"), // USDC.e
+			common.HexToAddress("0x9702230A8Ea53601f5cD2dc00fDBc13d4dF4A8c7"), // USDT
+		},
+		Factories: []Factory{
+			{Name: "Trader Joe", Kind: UniswapV2, Address: common.HexToAddress("0x9Ad6C38BE94206cA50bb0d90783181662f0Cfa10")},
+			{Name: "Pangolin", Kind: UniswapV2, Address: common.HexToAddress("0xefa94DE7a4656D787667C749f7E1223D71E9FD88")},
+			{Name: "Uniswap V3", Kind: UniswapV3, Address: common.HexToAddress("0x740b1c1de25031C31FF4fC9A62f554A55cdC1baD")},
+		},
+	}
+)
+
+var profiles = []*Profile{Ethereum, BSC, Polygon, Arbitrum, Optimism, Base, Avalanche}
@@ -7,7 +7,9 @@ import (
 )
 
 var (
-	ERC20 abi.ABI
+	ERC20            abi.ABI
+	UniswapV2Factory abi.ABI
+	UniswapV3Factory abi.ABI
 )
 
 func init() {
@@ -16,6 +18,8 @@ func init() {
 		data []byte
 	}{
 		{&ERC20, erc20},
+		{&UniswapV2Factory, uniswapV2Factory},
+		{&UniswapV3Factory, uniswapV3Factory},
 	}
 
 	for _, b := range builder {
@@ -4,3 +4,9 @@ import _ "embed"
 
 //go:embed ERC20.json
 var erc20 []byte
+
+//go:embed UniswapV2Factory.json
+var uniswapV2Factory []byte
+
+//go:embed UniswapV3Factory.json
+var uniswapV3Factory []byte
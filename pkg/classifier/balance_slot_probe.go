@@ -7,7 +7,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/vm"
-	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
@@ -22,17 +21,20 @@ func randomizeHash() common.Hash {
 	return h
 }
 
-const (
-	gasLimit = "0x7a120"
-)
-
 type Probe struct {
-	rpcClient *rpc.Client
+	rpcClient jsonrpc.Client
+	gasLimit  string
+}
+
+func NewProbe(rpcClient jsonrpc.Client) *Probe {
+	return NewProbeWithGas(rpcClient, DefaultGasConfig)
 }
 
-func NewProbe(rpcClient *rpc.Client) *Probe {
+// NewProbeWithGas probes with the gas limit of a chain.
+func NewProbeWithGas(rpcClient jsonrpc.Client, gas GasConfig) *Probe {
 	return &Probe{
 		rpcClient: rpcClient,
+		gasLimit:  gas.gasLimitHex(),
 	}
 }
 
@@ -48,26 +50,22 @@ func (p *Probe) ProbeBalanceSlot(token, wallet common.Address) (common.Hash, err
 	if err != nil {
 		return common.Hash{}, err
 	}
-	tracingResult := new(tracingResult)
-	err = jsonrpc.DebugTraceCall(
+	trace, err := traceStorageOps(
 		p.rpcClient,
 		&jsonrpc.DebugTraceCallCalldataParam{
 			From: common.Address{}.String(),
 			To:   token.String(),
-			Gas:  gasLimit,
+			Gas:  p.gasLimit,
 			Data: hexutil.Encode(data),
 		},
 		"latest",
-		&jsonrpc.DebugTraceCallTracerConfigParam{
-			Tracer: string(storageTracerMinified),
-		},
-		tracingResult,
+		nil,
 	)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	// encoded, _ := json.MarshalIndent(tracingResult, "", "  ")
+	// encoded, _ := json.MarshalIndent(trace, "", "  ")
 	// fmt.Printf("tracing result = %s\n", string(encoded))
 
 	/*
@@ -79,38 +77,57 @@ func (p *Probe) ProbeBalanceSlot(token, wallet common.Address) (common.Hash, err
 			If there is only 1 instruction whose output of balanceOf(wallet) is the same as v, its slot is the slot we are finding.
 			Otherwise, we could not find the slot we are finding.
 	*/
-	var possibleSlots []common.Hash
-	for _, sload := range tracingResult.ops {
-		if (sload.Op == vm.SLOAD) && common.HexToHash(sload.Value) != common.HexToHash(tracingResult.Output) {
+	var (
+		candidateSlots []common.Hash
+		testValues     []common.Hash
+		requests       []jsonrpc.EthCallRequest
+	)
+	var (
+		output  = common.BytesToHash(trace.Output)
+		checked = make(map[common.Hash]bool)
+	)
+	for _, op := range trace.Ops {
+		if op.Op != vm.SLOAD || op.Address != token || op.OldValue != output || checked[op.Slot] {
 			continue
 		}
+		checked[op.Slot] = true
 
 		testValue := randomizeHash()
-		logger.Debugf("    probing slot %s with test value %s\n", common.HexToHash(sload.Slot), testValue)
-		result, err := jsonrpc.EthCall(
-			p.rpcClient,
-			&jsonrpc.EthCallCalldataParam{
+		logger.Debugf("    probing slot %s with test value %s\n", op.Slot, testValue)
+		candidateSlots = append(candidateSlots, op.Slot)
+		testValues = append(testValues, testValue)
+		requests = append(requests, jsonrpc.EthCallRequest{
+			Calldata: &jsonrpc.EthCallCalldataParam{
 				From: common.Address{}.String(),
 				To:   token.String(),
-				Gas:  gasLimit,
+				Gas:  p.gasLimit,
 				Data: hexutil.Encode(data),
 			},
-			"latest",
-			map[common.Address]jsonrpc.OverrideAccount{
+			BlockNumber: "latest",
+			Override: map[common.Address]jsonrpc.OverrideAccount{
 				token: {
 					StateDiff: map[common.Hash]string{
-						common.HexToHash(sload.Slot): utils.RemoveLeadingZerosFromHash(testValue),
+						op.Slot: utils.RemoveLeadingZerosFromHash(testValue),
 					},
 				},
 			},
-		)
-		if err != nil {
-			return common.Hash{}, err
+		})
+	}
+
+	// all candidates are checked in one batch request
+	results, err := jsonrpc.BatchEthCall(p.rpcClient, requests)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var possibleSlots []common.Hash
+	for i, result := range results {
+		if result.Err != nil {
+			return common.Hash{}, result.Err
 		}
-		logger.Debugf("    result = %+v\n", *result)
-		if common.HexToHash(*result) == testValue {
-			logger.Debugf("        slot %s is a candidate\n", common.HexToHash(sload.Slot))
-			possibleSlots = append(possibleSlots, common.HexToHash(sload.Slot))
+		logger.Debugf("    result = %+v\n", result.Result)
+		if common.HexToHash(result.Result) == testValues[i] {
+			logger.Debugf("        slot %s is a candidate\n", candidateSlots[i])
+			possibleSlots = append(possibleSlots, candidateSlots[i])
 		}
 	}
 
@@ -0,0 +1,189 @@
+package classifier
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// BatchClassifierConfig configures a BatchClassifier.
+type BatchClassifierConfig struct {
+	// Workers is the number of scenarios simulated concurrently
+	Workers int
+	// RetryConfig retries scenarios failing with transient RPC errors
+	jsonrpc.RetryConfig
+	// OnProgress is called each time a scenario is done, from the workers so possibly concurrently. If nil, progress
+	// is logged periodically.
+	OnProgress func(BatchProgress)
+}
+
+// DefaultBatchClassifierConfig simulates 8 scenarios at a time.
+var DefaultBatchClassifierConfig = BatchClassifierConfig{
+	Workers:     8,
+	RetryConfig: jsonrpc.DefaultRetryConfig,
+}
+
+// BatchProgress is the progress of a batch classification.
+type BatchProgress struct {
+	TotalTokens    int
+	DoneTokens     int
+	TotalScenarios int
+	DoneScenarios  int
+	Elapsed        time.Duration
+}
+
+// BatchResult is the classification result of a token in a batch.
+type BatchResult struct {
+	Token           common.Address
+	IsFeeOnTransfer bool
+	NumScenarios    int
+	NumEqual        int
+	NumLess         int
+	NumFailed       int
+	// FeeBps is the largest fee taken by the simulated transfers, in basis points of their amount
+	FeeBps int
+	// Err is ErrCouldNotDecide if no scenario could be simulated
+	Err error
+}
+
+// BatchClassifier classifies many new tokens concurrently with a StorageTraceClassifier.
+// Requests are rate limited by the classifier's RPC client, see jsonrpc.DialRateLimited.
+type BatchClassifier struct {
+	config BatchClassifierConfig
+	// simulate returns the balance the receiver of a scenario gets
+	simulate func(*jsonrpc.TransferScenario) (*big.Int, error)
+}
+
+func NewBatchClassifier(classifier *StorageTraceClassifier, config BatchClassifierConfig) *BatchClassifier {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	return &BatchClassifier{
+		config:   config,
+		simulate: classifier.getActualBalanceReceivedAfterTransfer,
+	}
+}
+
+// actualBalanceReceived simulates a scenario, retrying on transient RPC errors.
+func (b *BatchClassifier) actualBalanceReceived(ctx context.Context, scenario *jsonrpc.TransferScenario) (*big.Int, error) {
+	var actualAmount *big.Int
+	err := b.config.Retry(ctx, jsonrpc.IsRetryable, func() (err error) {
+		actualAmount, err = b.simulate(scenario)
+		return err
+	}, "token", scenario.Token)
+	return actualAmount, err
+}
+
+// ClassifyNewTokens checks if the tokens of the scenarios are FOT, like IsFeeOnTransferNewToken,
+// simulating the scenarios of all tokens concurrently.
+func (b *BatchClassifier) ClassifyNewTokens(ctx context.Context, scenarios []*jsonrpc.TransferScenario) map[common.Address]*BatchResult {
+	var (
+		results   = make(map[common.Address]*BatchResult)
+		remaining = make(map[common.Address]int)
+		progress  BatchProgress
+		mu        sync.Mutex
+		start     = time.Now()
+		jobs      = make(chan *jsonrpc.TransferScenario)
+		wg        sync.WaitGroup
+	)
+	for _, s := range scenarios {
+		if results[s.Token] == nil {
+			results[s.Token] = &BatchResult{Token: s.Token}
+		}
+		results[s.Token].NumScenarios++
+		remaining[s.Token]++
+	}
+	progress.TotalTokens = len(results)
+	progress.TotalScenarios = len(scenarios)
+
+	logEvery := progress.TotalScenarios / 100
+	if logEvery < 1 {
+		logEvery = 1
+	}
+	reportProgress := func(p BatchProgress) {
+		if b.config.OnProgress != nil {
+			b.config.OnProgress(p)
+			return
+		}
+		if p.DoneScenarios%logEvery == 0 || p.DoneScenarios == p.TotalScenarios {
+			logger.Infow("batch progress",
+				"tokens", p.DoneTokens, "totalTokens", p.TotalTokens,
+				"scenarios", p.DoneScenarios, "totalScenarios", p.TotalScenarios,
+				"elapsed", p.Elapsed)
+		}
+	}
+
+	for i := 0; i < b.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range jobs {
+				actualAmount, err := b.actualBalanceReceived(ctx, s)
+
+				mu.Lock()
+				result := results[s.Token]
+				switch {
+				case err != nil:
+					logger.Debugw("could not getActualBalanceReceivedAfterTransfer", "token", s.Token, "error", err)
+					result.NumFailed++
+				case actualAmount.Cmp(s.Amount) < 0:
+					result.NumLess++
+					if feeBps := feeBps(s.Amount, actualAmount); feeBps > result.FeeBps {
+						result.FeeBps = feeBps
+					}
+				case actualAmount.Cmp(s.Amount) == 0:
+					result.NumEqual++
+				}
+				remaining[s.Token]--
+				if remaining[s.Token] == 0 {
+					result.IsFeeOnTransfer, result.Err = decideFeeOnTransferNewToken(result.NumEqual, result.NumLess)
+					progress.DoneTokens++
+				}
+				progress.DoneScenarios++
+				progress.Elapsed = time.Since(start)
+				snapshot := progress
+				mu.Unlock()
+				// a slow callback must not hold up the other workers
+				reportProgress(snapshot)
+			}
+		}()
+	}
+
+feed:
+	for _, s := range scenarios {
+		// select picks randomly among ready cases, don't feed a waiting worker once ctx is done
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- s:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// tokens whose scenarios were not all simulated because ctx is done
+	for token, result := range results {
+		if remaining[token] > 0 {
+			result.Err = ctx.Err()
+		}
+	}
+	return results
+}
+
+// feeBps returns the part of amount that was not received, in basis points.
+func feeBps(amount, received *big.Int) int {
+	if amount.Sign() <= 0 {
+		return 0
+	}
+	fee := new(big.Int).Sub(amount, received)
+	fee.Mul(fee, big.NewInt(10000))
+	return int(fee.Div(fee, amount).Int64())
+}
@@ -0,0 +1,179 @@
+package classifier
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+var testBatchConfig = BatchClassifierConfig{
+	Workers:     4,
+	RetryConfig: jsonrpc.RetryConfig{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+}
+
+func newTestBatchClassifier(config BatchClassifierConfig, simulate func(*jsonrpc.TransferScenario) (*big.Int, error)) *BatchClassifier {
+	b := NewBatchClassifier(nil, config)
+	b.simulate = simulate
+	return b
+}
+
+func transferScenario(token common.Address, amount int64) *jsonrpc.TransferScenario {
+	return &jsonrpc.TransferScenario{Token: token, To: common.HexToAddress("0xbeef"), Amount: big.NewInt(amount)}
+}
+
+func TestBatchClassifier_ClassifyNewTokens(t *testing.T) {
+	var (
+		plain    = common.HexToAddress("0x01")
+		fot      = common.HexToAddress("0x02")
+		reverted = common.HexToAddress("0x03")
+		flaky    = common.HexToAddress("0x04")
+		throttle = rpc.HTTPError{StatusCode: http.StatusServiceUnavailable}
+	)
+	var (
+		mu       sync.Mutex
+		attempts = make(map[common.Address]int)
+	)
+	b := newTestBatchClassifier(testBatchConfig, func(s *jsonrpc.TransferScenario) (*big.Int, error) {
+		mu.Lock()
+		attempts[s.Token]++
+		attempt := attempts[s.Token]
+		mu.Unlock()
+		switch s.Token {
+		case fot:
+			// 2% fee on transfers of 1000, none on smaller ones
+			if s.Amount.Int64() >= 1000 {
+				return new(big.Int).Div(new(big.Int).Mul(s.Amount, big.NewInt(98)), big.NewInt(100)), nil
+			}
+		case reverted:
+			return nil, ErrTransferNotSuccess
+		case flaky:
+			if attempt == 1 {
+				return nil, throttle
+			}
+		}
+		return new(big.Int).Set(s.Amount), nil
+	})
+
+	var (
+		progressMu sync.Mutex
+		last       BatchProgress
+	)
+	b.config.OnProgress = func(p BatchProgress) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if p.DoneScenarios > last.DoneScenarios {
+			last = p
+		}
+	}
+
+	results := b.ClassifyNewTokens(context.Background(), []*jsonrpc.TransferScenario{
+		transferScenario(plain, 1000), transferScenario(plain, 5000),
+		transferScenario(fot, 100), transferScenario(fot, 1000), transferScenario(fot, 5000),
+		transferScenario(reverted, 1000), transferScenario(reverted, 2000),
+		transferScenario(flaky, 1000),
+	})
+	require.Len(t, results, 4)
+
+	tests := []struct {
+		token         common.Address
+		wantFOT       bool
+		wantEqual     int
+		wantLess      int
+		wantFailed    int
+		wantFeeBps    int
+		wantErr       error
+		wantScenarios int
+	}{
+		{plain, false, 2, 0, 0, 0, nil, 2},
+		{fot, true, 1, 2, 0, 200, nil, 3},
+		// the failures of a token don't affect the others
+		{reverted, false, 0, 0, 2, 0, ErrCouldNotDecide, 2},
+		// a transient error is retried for the scenario only
+		{flaky, false, 1, 0, 0, 0, nil, 1},
+	}
+	for _, tt := range tests {
+		result := results[tt.token]
+		require.NotNil(t, result, tt.token)
+		assert.Equal(t, tt.wantFOT, result.IsFeeOnTransfer, tt.token)
+		assert.Equal(t, tt.wantEqual, result.NumEqual, tt.token)
+		assert.Equal(t, tt.wantLess, result.NumLess, tt.token)
+		assert.Equal(t, tt.wantFailed, result.NumFailed, tt.token)
+		assert.Equal(t, tt.wantFeeBps, result.FeeBps, tt.token)
+		assert.Equal(t, tt.wantScenarios, result.NumScenarios, tt.token)
+		assert.ErrorIs(t, result.Err, tt.wantErr, tt.token)
+	}
+	// reverts are not retried, the throttled scenario is retried once
+	assert.Equal(t, 2, attempts[reverted])
+	assert.Equal(t, 2, attempts[flaky])
+
+	assert.Equal(t, BatchProgress{TotalTokens: 4, DoneTokens: 4, TotalScenarios: 8, DoneScenarios: 8, Elapsed: last.Elapsed}, last)
+}
+
+func TestBatchClassifier_ClassifyNewTokensCanceled(t *testing.T) {
+	var (
+		done    = common.HexToAddress("0x01")
+		pending = common.HexToAddress("0x02")
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := testBatchConfig
+	config.Workers = 1
+	b := newTestBatchClassifier(config, func(s *jsonrpc.TransferScenario) (*big.Int, error) {
+		if s.Token == pending {
+			cancel()
+		}
+		return new(big.Int).Set(s.Amount), nil
+	})
+	results := b.ClassifyNewTokens(ctx, []*jsonrpc.TransferScenario{
+		transferScenario(done, 1000),
+		transferScenario(pending, 1000), transferScenario(pending, 2000), transferScenario(pending, 3000),
+	})
+
+	require.NoError(t, results[done].Err)
+	assert.False(t, results[done].IsFeeOnTransfer)
+	// the scenarios left when ctx is done are not simulated
+	assert.ErrorIs(t, results[pending].Err, context.Canceled)
+	assert.Less(t, results[pending].NumEqual, 3)
+}
+
+func TestBatchClassifier_SlowProgress(t *testing.T) {
+	// the first progress report waits for the second one, which a callback called with the results locked would block
+	var (
+		once   sync.Once
+		second = make(chan struct{})
+	)
+	config := testBatchConfig
+	config.Workers = 2
+	config.OnProgress = func(p BatchProgress) {
+		first := false
+		once.Do(func() { first = true })
+		if !first {
+			close(second)
+			return
+		}
+		select {
+		case <-second:
+		case <-time.After(5 * time.Second):
+			t.Error("progress of the other worker was blocked")
+		}
+	}
+	b := newTestBatchClassifier(config, func(s *jsonrpc.TransferScenario) (*big.Int, error) {
+		return new(big.Int).Set(s.Amount), nil
+	})
+	token := common.HexToAddress("0x01")
+	results := b.ClassifyNewTokens(context.Background(), []*jsonrpc.TransferScenario{
+		transferScenario(token, 1000), transferScenario(token, 2000),
+	})
+	assert.Equal(t, 2, results[token].NumEqual)
+}
@@ -1,26 +1,30 @@
 package data
 
 import (
-	"os"
+	"context"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/gocarina/gocsv"
 
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/ingest"
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
 )
 
+// ReadDataFromCSV returns the transfer events of contractAddress in a CSV export, all of them if it's the zero address.
 func ReadDataFromCSV(csv_file string, contractAddress common.Address) ([]*types.TxFromTransferEvent, error) {
-	f, err := os.Open(csv_file)
+	r, err := ingest.Open(csv_file, ingest.FormatCSV)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer f.Close()
-	var (
-		transfers []*types.TxFromTransferEvent
-	)
+	defer r.Close()
 
-	if err := gocsv.UnmarshalFile(f, &transfers); err != nil {
-		return nil, err
+	var opts ingest.Options
+	if contractAddress != (common.Address{}) {
+		opts.Tokens = []common.Address{contractAddress}
+	}
+	transfers, err := ingest.Collect(ingest.TransferEvents(context.Background(), r, opts))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", csv_file, err)
 	}
 	return transfers, nil
 }
@@ -7,11 +7,11 @@ import (
 	"math/big"
 
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
 
 	"github.com/sajari/regression"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -20,13 +20,16 @@ import (
 
 type EventFilterClassifier struct {
 	ethClient    *ethclient.Client
+	logFetcher   *fetcher.LogFetcher
 	TxsThreshold int //TxsThreshold is the limitation of tx we should get for historical txs
 	RegressR2    float64
 }
 
 func NewEventFiterClassifier(rpcClient *rpc.Client, txsThreshold int, regressR2 float64) *EventFilterClassifier {
+	ethClient := ethclient.NewClient(rpcClient)
 	return &EventFilterClassifier{
-		ethClient:    ethclient.NewClient(rpcClient),
+		ethClient:    ethClient,
+		logFetcher:   fetcher.NewLogFetcher(ethClient, fetcher.DefaultLogFetcherConfig, nil),
 		TxsThreshold: txsThreshold,
 		RegressR2:    regressR2,
 	}
@@ -43,50 +46,12 @@ func (c *EventFilterClassifier) IsErc20(contractAddress common.Address, codes []
 	return IsErc20(codes)
 }
 
+// FetchLogs fetches the most recent Transfer events of a contract, until TxsThreshold events or its creation block.
 func (c *EventFilterClassifier) FetchLogs(contractAddress common.Address) []ethtypes.Log {
-	eventSignature := abis.ERC20.Events["Transfer"].ID
-	blockNumber, err := c.ethClient.BlockNumber(context.Background())
+	topics := [][]common.Hash{{abis.ERC20.Events["Transfer"].ID}}
+	logs, err := c.logFetcher.Recent(context.Background(), contractAddress, topics, c.TxsThreshold)
 	if err != nil {
-		logger.Errorw("could not get block number", "error", err)
-		return nil
-	}
-	var (
-		topics = [][]common.Hash{{eventSignature}}
-		logs   []ethtypes.Log
-	)
-	for {
-		var from uint64
-		if blockNumber > 10000 {
-			from = blockNumber - 10000
-		}
-
-		query := ethereum.FilterQuery{
-			Addresses: []common.Address{contractAddress},
-			Topics:    topics,
-			FromBlock: big.NewInt(int64(from)),
-			ToBlock:   big.NewInt(int64(blockNumber)),
-		}
-		newLogs, lerr := c.ethClient.FilterLogs(context.Background(), query)
-		if err != nil {
-			logger.Errorw("could not get event log", "error", lerr)
-			break
-		}
-		if len(newLogs) == 0 {
-			code, cerr := c.ethClient.CodeAt(context.Background(), contractAddress, big.NewInt(int64(from)))
-			if cerr != nil {
-				logger.Warn("cannot get code", "error", err, "block", from)
-				break
-			}
-			if len(code) == 0 {
-				logger.Warnw("no more tx to get")
-				break
-			}
-		}
-		logs = append(logs, newLogs...)
-		if len(logs) >= c.TxsThreshold {
-			break
-		}
-		blockNumber = from
+		logger.Errorw("could not get event log", "error", err, "numLogs", len(logs))
 	}
 	return logs
 }
@@ -96,26 +61,13 @@ func (c *EventFilterClassifier) ProcessLogs(logs []ethtypes.Log) map[common.Hash
 		txToEventMap = make(map[common.Hash][]types.TxFromTransferEvent, len(logs))
 	)
 
-	for _, vLog := range logs {
-		//logger.Infow("data", "data", vLog.Data, "tx", vLog.TxHash, "topic", vLog.Topics)
-		event, eerr := abis.ERC20.Unpack("Transfer", vLog.Data)
+	for i := range logs {
+		event, eerr := DecodeTransferEvent(&logs[i])
 		if eerr != nil {
-			logger.Errorw("could not unpack event log", "error", eerr, "tx", vLog.TxHash)
-		}
-		//logger.Infow("event", "event", event)
-		tx := types.TxFromTransferEvent{
-			From:   common.HexToAddress(vLog.Topics[1].Hex()),
-			To:     common.HexToAddress(vLog.Topics[2].Hex()),
-			TxHash: vLog.TxHash,
-			Amount: event[0].(*big.Int),
-		}
-		if txToEventMap[vLog.TxHash] == nil {
-			txToEventMap[vLog.TxHash] = []types.TxFromTransferEvent{
-				tx,
-			}
-		} else {
-			txToEventMap[vLog.TxHash] = append(txToEventMap[vLog.TxHash], tx)
+			logger.Errorw("could not decode event log", "error", eerr, "tx", logs[i].TxHash)
+			continue
 		}
+		txToEventMap[event.TxHash] = append(txToEventMap[event.TxHash], *event)
 	}
 
 	return txToEventMap
@@ -0,0 +1,228 @@
+package classifier
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/tracing"
+)
+
+// FeeSetter is an owner-callable function writing a storage slot read during transfers.
+type FeeSetter struct {
+	// Method is the signature of the setter
+	Method string
+	// Slots are the slots written by the setter that are read during transfers
+	Slots []common.Hash
+	// Bounded set to true if the setter rejects some values
+	Bounded bool
+	// MaxValue is the largest value the setter accepts when it is bounded,
+	// or the largest accepted value found if the bound is too large to be searched within maxBoundSearchCalls
+	MaxValue *big.Int
+}
+
+// maxBoundSearchCalls caps the eth_calls made to bound a fee setter. It is enough for bounds below 2^32.
+const maxBoundSearchCalls = 64
+
+// FeeMutabilityResult stores whether the owner can change the fee of a token later.
+type FeeMutabilityResult struct {
+	// FeeMutable set to true if the owner can call a setter writing a slot read during transfers
+	FeeMutable bool
+	// FeeSlots are the token's slots read but not written during a transfer, they hold the transfer's configuration,
+	// including the fee
+	FeeSlots []common.Hash
+	Setters  []FeeSetter
+}
+
+// DetectMutableFee checks if the owner of the scenario's token can change its fee.
+// It traces the scenario's transfer with the storage tracer to find the slots the fee computation may read
+// (read, but not written, during the transfer), then traces each fee setter found in the bytecode, called by the owner,
+// to find the setters writing them. The largest value accepted by such setters is found by searchSetterBound.
+func (c *StorageTraceClassifier) DetectMutableFee(scenario *jsonrpc.TransferScenario) (*FeeMutabilityResult, error) {
+	_, blockNumberHex, err := c.resolveBlockNumber(scenario)
+	if err != nil {
+		return nil, err
+	}
+	transferData, err := packTransferData(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	/*
+		Step 1: find the slots holding the transfer's configuration.
+	*/
+	var transferTrace *tracing.StorageTrace
+	err = c.gas.traceTransfer(scenario, transferData, func(calldata *jsonrpc.DebugTraceCallCalldataParam) (err error) {
+		transferTrace, err = traceStorageOps(c.client, calldata, blockNumberHex, msgSenderBalanceOverride(scenario))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not trace transfer: %w", err)
+	}
+	var (
+		result   = new(FeeMutabilityResult)
+		feeSlots = make(map[common.Hash]bool)
+	)
+	for slot, access := range transferTrace.Slots(scenario.Token) {
+		if access.Loaded && !access.Stored {
+			feeSlots[slot] = true
+			result.FeeSlots = append(result.FeeSlots, slot)
+		}
+	}
+
+	/*
+		Step 2: find the fee setters the owner can call and the slots they write.
+	*/
+	owner, hasOwner, err := c.tokenOwner(scenario.Token, blockNumberHex)
+	if err != nil {
+		return nil, err
+	}
+	if !hasOwner || owner == (common.Address{}) || owner == deadAddress {
+		logger.Infow("token has no owner to change its fee", "token", scenario.Token)
+		return result, nil
+	}
+	selectors, _, err := c.tokenSelectors(scenario.Token, blockNumberHex)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, signature := range capabilityMethodSignatures[CapabilityUnboundedFee] {
+		if !selectors[getMethodHash(signature)] {
+			continue
+		}
+		setterData := func(value *big.Int) ([]byte, error) {
+			args, err := capabilityCallArgs(signature, owner, value)
+			if err != nil {
+				return nil, err
+			}
+			return packCall(signature, args...)
+		}
+		accepts := func(value *big.Int) (bool, error) {
+			data, err := setterData(value)
+			if err != nil {
+				return false, err
+			}
+			success, _, err := c.callSucceeds(owner, scenario.Token, data, blockNumberHex, nil)
+			return success, err
+		}
+
+		// the setter might reject 0, so look for the first accepted value
+		var accepted *big.Int
+		for _, value := range []*big.Int{big.NewInt(1), big.NewInt(0)} {
+			ok, err := accepts(value)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				accepted = value
+				break
+			}
+		}
+		if accepted == nil {
+			logger.Debugw("fee setter is not callable by the owner", "method", signature)
+			continue
+		}
+
+		data, err := setterData(accepted)
+		if err != nil {
+			return nil, err
+		}
+		setterTrace, err := traceStorageOps(
+			c.client,
+			&jsonrpc.DebugTraceCallCalldataParam{
+				From: owner.String(),
+				To:   scenario.Token.String(),
+				Gas:  c.gas.gasLimitHex(),
+				Data: hexutil.Encode(data),
+			},
+			blockNumberHex,
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not trace %s: %w", signature, err)
+		}
+		setter := FeeSetter{
+			Method: signature,
+		}
+		for slot, access := range setterTrace.Slots(scenario.Token) {
+			if access.Stored && feeSlots[slot] {
+				setter.Slots = append(setter.Slots, slot)
+			}
+		}
+		if len(setter.Slots) == 0 {
+			continue
+		}
+
+		/*
+			Step 3: bound the setter by simulation.
+		*/
+		ok, err := accepts(math.MaxBig256)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			setter.Bounded = true
+			setter.MaxValue, err = searchSetterBound(accepted, math.MaxBig256, maxBoundSearchCalls, accepts)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result.FeeMutable = true
+		result.Setters = append(result.Setters, setter)
+	}
+
+	return result, nil
+}
+
+// searchSetterBound searches the largest value in [lo, hi) accepted by a setter, given that it accepts lo and rejects hi.
+// Fee bounds are small, so the range is narrowed by doubling lo until a value is rejected before being binary searched.
+// It calls accepts at most maxCalls times, and returns the largest accepted value found when it runs out of calls.
+func searchSetterBound(lo, hi *big.Int, maxCalls int, accepts func(*big.Int) (bool, error)) (*big.Int, error) {
+	lo, hi = new(big.Int).Set(lo), new(big.Int).Set(hi)
+	var (
+		one   = big.NewInt(1)
+		calls int
+	)
+	for value := new(big.Int).Add(lo, lo); calls < maxCalls; value.Lsh(value, 1) {
+		if value.Cmp(lo) <= 0 {
+			value.Add(lo, one)
+		}
+		if value.Cmp(hi) >= 0 {
+			break
+		}
+		ok, err := accepts(value)
+		calls++
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			hi.Set(value)
+			break
+		}
+		lo.Set(value)
+	}
+	for new(big.Int).Sub(hi, lo).Cmp(one) > 0 {
+		if calls == maxCalls {
+			logger.Debugw("fee setter bound search ran out of calls", "lo", lo, "hi", hi)
+			break
+		}
+		mid := new(big.Int).Add(lo, hi)
+		mid.Rsh(mid, 1)
+		ok, err := accepts(mid)
+		calls++
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,237 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+var (
+	recordedOwner   = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	recordedFeeSlot = common.HexToHash("0x08")
+)
+
+// revertError is the error of a reverted call, as returned by geth.
+type revertError struct{}
+
+func (revertError) Error() string  { return "execution reverted" }
+func (revertError) ErrorCode() int { return 3 }
+
+// ownedTokenClient simulates recordedToken: the JS tracer returns the recorded trace of the called method, the
+// prestateTracer its diff, eth_calls are answered by the handler of the called method and eth_getCode returns PUSH4s
+// of the selectors of methods.
+type ownedTokenClient struct {
+	traces  map[string]string
+	diffs   map[string]*jsonrpc.PrestateTracerResult
+	methods map[string]func(args []byte, override jsonrpc.StateOverride) ([]byte, error)
+}
+
+func (c *ownedTokenClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	switch method {
+	case "eth_getCode":
+		var code []byte
+		for signature := range c.methods {
+			code = append(code, append([]byte{0x63}, hexutil.MustDecode(getMethodHash(signature))...)...)
+		}
+		*result.(*hexutil.Bytes) = code
+		return nil
+	case "eth_blockNumber":
+		*result.(*hexutil.Uint64) = 0x1036640
+		return nil
+	case "eth_getStorageAt":
+		*result.(*hexutil.Bytes) = common.Hash{}.Bytes()
+		return nil
+	case "eth_call":
+		data := hexutil.MustDecode(args[0].(*jsonrpc.EthCallCalldataParam).Data)
+		var override jsonrpc.StateOverride
+		if len(args) > 2 {
+			override = args[2].(jsonrpc.StateOverride)
+		}
+		for signature, handler := range c.methods {
+			if hexutil.Encode(data[:4]) == getMethodHash(signature) {
+				output, err := handler(data[4:], override)
+				if err != nil {
+					return err
+				}
+				*result.(*string) = hexutil.Encode(output)
+				return nil
+			}
+		}
+		return revertError{}
+	case "debug_traceCall":
+		data := args[0].(*jsonrpc.DebugTraceCallCalldataParam).Data
+		tracer, ok := args[2].(*jsonrpc.DebugTraceCallTracerConfigParam)
+		if ok && tracer.Tracer == "prestateTracer" {
+			for signature, diff := range c.diffs {
+				if strings.HasPrefix(data, getMethodHash(signature)) {
+					*result.(*jsonrpc.PrestateTracerResult) = *diff
+					return nil
+				}
+			}
+			return revertError{}
+		}
+		if !ok || tracer.Tracer != string(storageTracerMinified) {
+			return errors.New("not supported")
+		}
+		for signature, name := range c.traces {
+			if strings.HasPrefix(data, getMethodHash(signature)) {
+				encoded, err := os.ReadFile("testdata/" + name)
+				if err != nil {
+					return err
+				}
+				return json.Unmarshal(encoded, result)
+			}
+		}
+		return revertError{}
+	}
+	return errors.New("not supported")
+}
+
+func (c *ownedTokenClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("not supported")
+}
+
+func returnsAddress(address common.Address) func([]byte, jsonrpc.StateOverride) ([]byte, error) {
+	return func([]byte, jsonrpc.StateOverride) ([]byte, error) {
+		return common.BytesToHash(address.Bytes()).Bytes(), nil
+	}
+}
+
+func returnsTrue([]byte, jsonrpc.StateOverride) ([]byte, error) {
+	return common.BigToHash(big.NewInt(1)).Bytes(), nil
+}
+
+// acceptsUpTo is a setter reverting for values above max.
+func acceptsUpTo(max *big.Int) func([]byte, jsonrpc.StateOverride) ([]byte, error) {
+	return func(args []byte, _ jsonrpc.StateOverride) ([]byte, error) {
+		if new(big.Int).SetBytes(args[:32]).Cmp(max) > 0 {
+			return nil, revertError{}
+		}
+		return nil, nil
+	}
+}
+
+func TestDetectMutableFee(t *testing.T) {
+	scenario := &jsonrpc.TransferScenario{
+		MsgSender:   recordedSender,
+		Token:       recordedToken,
+		To:          recordedReceiver,
+		Amount:      big.NewInt(1000),
+		BlockNumber: "0x1036640",
+	}
+	traces := map[string]string{
+		"transfer(address,uint256)": "transfer_trace.json",
+		"setFee(uint256)":           "set_fee_trace.json",
+	}
+
+	tests := []struct {
+		name    string
+		methods map[string]func([]byte, jsonrpc.StateOverride) ([]byte, error)
+		want    *FeeMutabilityResult
+	}{
+		{
+			name: "bounded setter",
+			methods: map[string]func([]byte, jsonrpc.StateOverride) ([]byte, error){
+				"transfer(address,uint256)": returnsTrue,
+				"owner()":                   returnsAddress(recordedOwner),
+				"setFee(uint256)":           acceptsUpTo(big.NewInt(2500)),
+			},
+			want: &FeeMutabilityResult{
+				FeeMutable: true,
+				FeeSlots:   []common.Hash{recordedFeeSlot},
+				Setters: []FeeSetter{{
+					Method:   "setFee(uint256)",
+					Slots:    []common.Hash{recordedFeeSlot},
+					Bounded:  true,
+					MaxValue: big.NewInt(2500),
+				}},
+			},
+		},
+		{
+			name: "unbounded setter",
+			methods: map[string]func([]byte, jsonrpc.StateOverride) ([]byte, error){
+				"transfer(address,uint256)": returnsTrue,
+				"owner()":                   returnsAddress(recordedOwner),
+				"setFee(uint256)":           acceptsUpTo(new(big.Int).Lsh(big.NewInt(1), 256)),
+			},
+			want: &FeeMutabilityResult{
+				FeeMutable: true,
+				FeeSlots:   []common.Hash{recordedFeeSlot},
+				Setters: []FeeSetter{{
+					Method: "setFee(uint256)",
+					Slots:  []common.Hash{recordedFeeSlot},
+				}},
+			},
+		},
+		{
+			name: "ownership renounced",
+			methods: map[string]func([]byte, jsonrpc.StateOverride) ([]byte, error){
+				"transfer(address,uint256)": returnsTrue,
+				"owner()":                   returnsAddress(deadAddress),
+				"setFee(uint256)":           acceptsUpTo(big.NewInt(2500)),
+			},
+			want: &FeeMutabilityResult{
+				FeeSlots: []common.Hash{recordedFeeSlot},
+			},
+		},
+		{
+			name: "no fee setter",
+			methods: map[string]func([]byte, jsonrpc.StateOverride) ([]byte, error){
+				"transfer(address,uint256)": returnsTrue,
+				"owner()":                   returnsAddress(recordedOwner),
+			},
+			want: &FeeMutabilityResult{
+				FeeSlots: []common.Hash{recordedFeeSlot},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClassifier(&ownedTokenClient{traces: traces, methods: tt.methods}, nil)
+			got, err := c.DetectMutableFee(scenario)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_searchSetterBound(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 200)
+	tests := []struct {
+		name      string
+		lo        int64
+		bound     *big.Int
+		want      *big.Int
+		wantCalls int
+	}{
+		{name: "fee in bps", lo: 1, bound: big.NewInt(2500), want: big.NewInt(2500), wantCalls: 23},
+		{name: "fee in percent", lo: 0, bound: big.NewInt(25), want: big.NewInt(25), wantCalls: 10},
+		{name: "rejects all but lo", lo: 0, bound: big.NewInt(0), want: big.NewInt(0), wantCalls: 1},
+		// the largest accepted value found is reported once the calls run out
+		{name: "bound too large to search", lo: 1, bound: huge, want: new(big.Int).Lsh(big.NewInt(1), maxBoundSearchCalls), wantCalls: maxBoundSearchCalls},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			got, err := searchSetterBound(big.NewInt(tt.lo), new(big.Int).Lsh(big.NewInt(1), 256), maxBoundSearchCalls, func(value *big.Int) (bool, error) {
+				calls++
+				return value.Cmp(tt.bound) <= 0, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
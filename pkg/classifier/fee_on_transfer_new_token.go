@@ -1,11 +1,10 @@
 package classifier
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
@@ -13,105 +12,96 @@ import (
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
 )
 
+// resolveBlockNumber returns the block number the scenario should be simulated on.
+// If the scenario has no specific block number, the latest block number is used to make the following steps consistent.
+func (c *StorageTraceClassifier) resolveBlockNumber(scenario *jsonrpc.TransferScenario) (uint64, string, error) {
+	if scenario.BlockNumber != "" {
+		blockNumber, err := hexutil.DecodeUint64(scenario.BlockNumber)
+		if err != nil {
+			return 0, "", err
+		}
+		return blockNumber, scenario.BlockNumber, nil
+	}
+	blockNumber, err := jsonrpc.BlockNumber(c.client)
+	if err != nil {
+		return 0, "", fmt.Errorf("could not get block number: %w", err)
+	}
+	return blockNumber, hexutil.EncodeUint64(blockNumber), nil
+}
+
+// packTransferData returns the calldata of transfer(to, amount) or transferFrom(from, to, amount) of the scenario.
+func packTransferData(scenario *jsonrpc.TransferScenario) ([]byte, error) {
+	if scenario.IsTransferFrom {
+		return abis.ERC20.Pack("transferFrom", scenario.From, scenario.To, scenario.Amount)
+	}
+	return abis.ERC20.Pack("transfer", scenario.To, scenario.Amount)
+}
+
+// msgSenderBalanceOverride gives the scenario's msg.sender a very large native balance so it can always pay for gas.
+func msgSenderBalanceOverride(scenario *jsonrpc.TransferScenario) jsonrpc.StateOverride {
+	return jsonrpc.StateOverride{
+		scenario.MsgSender: {
+			// very large balance
+			Balance: (*hexutil.Big)(hexutil.MustDecodeBig("0xffffffffffffffffffffffffffffffff")),
+		},
+	}
+}
+
 func (c *StorageTraceClassifier) getActualBalanceReceivedAfterTransfer(scenario *jsonrpc.TransferScenario) (*big.Int, error) {
 	/*
 		Step 0: If not specific block number, get the latest block number to make the following step consistent.
 	*/
-	var (
-		blockNumber    uint64
-		blockNumberHex string
-		err            error
-	)
-	if scenario.BlockNumber != "" {
-		blockNumberHex = scenario.BlockNumber
-		blockNumber, err = hexutil.DecodeUint64(blockNumberHex)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		blockNumber, err = c.ethClient.BlockNumber(context.Background())
-		if err != nil {
-			return nil, fmt.Errorf("could not get block number: %w", err)
-		}
-		blockNumberHex = hexutil.EncodeUint64(blockNumber)
+	_, blockNumberHex, err := c.resolveBlockNumber(scenario)
+	if err != nil {
+		return nil, err
 	}
 
 	/*
 		Step 1: Trace a transfer(to, amount) (or transferFrom(from, to, amount)) tx and extract the statediff.
 	*/
-	var transferData []byte
-	if scenario.IsTransferFrom {
-		transferData, err = abis.ERC20.Pack("transferFrom", scenario.From, scenario.To, scenario.Amount)
-	} else {
-		transferData, err = abis.ERC20.Pack("transfer", scenario.To, scenario.Amount)
-	}
+	transferData, err := packTransferData(scenario)
 	if err != nil {
 		return nil, err
 	}
 
 	// make sure the tranfer tx is success
-	success, err := c.ethClient.CallContract(
-		context.Background(),
-		ethereum.CallMsg{
-			From: scenario.MsgSender,
-			To:   &scenario.Token,
-			Data: transferData,
+	success, err := jsonrpc.EthCall(
+		c.client,
+		&jsonrpc.EthCallCalldataParam{
+			From: scenario.MsgSender.String(),
+			To:   scenario.Token.String(),
+			Data: hexutil.Encode(transferData),
 		},
-		new(big.Int).SetUint64(blockNumber),
+		blockNumberHex,
+		nil,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not eth_call: %w", err)
 	}
-	if new(big.Int).SetBytes(success).Cmp(big.NewInt(1)) != 0 {
-		return nil, fmt.Errorf("transfer not success")
+	output, err := hexutil.Decode(*success)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode eth_call result: %w", err)
+	}
+	if new(big.Int).SetBytes(output).Cmp(big.NewInt(1)) != 0 {
+		return nil, ErrTransferNotSuccess
 	}
 
 	transferTraceResult := new(jsonrpc.PrestateTracerResult)
-
-	// some tracing fails if we don't specify maxFeePerGas and maxPriorityFeePerGas
-	var (
-		gasPrice             string
-		maxFeePerGas         string
-		maxPriorityFeePerGas string
-	)
-	if scenario.GasFeeCap != nil && scenario.GasFeeCap.Cmp(big.NewInt(0)) != 0 {
-		c := new(big.Int).Mul(scenario.GasFeeCap, big.NewInt(150))
-		c = c.Div(c, big.NewInt(100))
-		maxFeePerGas = hexutil.EncodeBig(c)
-
-		if scenario.GasTipCap != nil {
-			c := new(big.Int).Mul(scenario.GasTipCap, big.NewInt(150))
-			c = c.Div(c, big.NewInt(100))
-			maxPriorityFeePerGas = hexutil.EncodeBig(c)
-		}
-	} else {
-		gasPrice = hexutil.EncodeBig(scenario.GasPrice)
-	}
-	err = jsonrpc.DebugTraceCall(
-		c.client,
-		&jsonrpc.DebugTraceCallCalldataParam{
-			From:                 scenario.MsgSender.String(),
-			GasPrice:             gasPrice,
-			MaxFeePerGas:         maxFeePerGas,
-			MaxPriorityFeePerGas: maxPriorityFeePerGas,
-			To:                   scenario.Token.String(),
-			Data:                 hexutil.Encode(transferData),
-		},
-		blockNumberHex,
-		&jsonrpc.DebugTraceCallTracerConfigParam{
-			// we are using the builtin prestateTracer in go-ethereum
-			// https://github.com/ethereum/go-ethereum/blob/master/eth/tracers/native/prestate.go
-			Tracer:       "prestateTracer",
-			TracerConfig: jsonrpc.TransferTracerConfigEncoded,
-			StateOverrides: map[common.Address]jsonrpc.OverrideAccount{
-				scenario.MsgSender: {
-					// very large balance
-					Balance: (*hexutil.Big)(hexutil.MustDecodeBig("0xffffffffffffffffffffffffffffffff")),
-				},
+	err = c.gas.traceTransfer(scenario, transferData, func(calldata *jsonrpc.DebugTraceCallCalldataParam) error {
+		return jsonrpc.DebugTraceCall(
+			c.client,
+			calldata,
+			blockNumberHex,
+			&jsonrpc.DebugTraceCallTracerConfigParam{
+				// we are using the builtin prestateTracer in go-ethereum
+				// https://github.com/ethereum/go-ethereum/blob/master/eth/tracers/native/prestate.go
+				Tracer:         "prestateTracer",
+				TracerConfig:   jsonrpc.TransferTracerConfigEncoded,
+				StateOverrides: msgSenderBalanceOverride(scenario),
 			},
-		},
-		transferTraceResult,
-	)
+			transferTraceResult,
+		)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("could not debug_traceCall a transfer tx: %w", err)
 	}
@@ -161,11 +151,20 @@ func (c *StorageTraceClassifier) IsFeeOnTransferNewToken(token common.Address, s
 
 	fmt.Printf("    numEqual = %d, numLess = %d\n", numEqual, numLess)
 
+	return decideFeeOnTransferNewToken(numEqual, numLess)
+}
+
+// ErrCouldNotDecide is returned when no scenario of a token could be simulated.
+var ErrCouldNotDecide = errors.New("could not decide")
+
+// decideFeeOnTransferNewToken decides if a token is FOT given the number of simulated transfers whose receiver received
+// the same amount and less than the transfer amount.
+func decideFeeOnTransferNewToken(numEqual, numLess int) (bool, error) {
 	if numEqual > 0 && numLess == 0 {
 		return false, nil
 	}
 	if numLess > 0 {
 		return true, nil
 	}
-	return false, fmt.Errorf("could not decide")
+	return false, ErrCouldNotDecide
 }
@@ -0,0 +1,143 @@
+package classifier
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// Rollup is the kind of rollup a chain is, its txs pay for the L1 data they are posted with on top of their L2 gas.
+type Rollup string
+
+const (
+	// RollupArbitrum chains run ArbOS: the L1 data fee is charged in L2 gas taken from the gas limit, the priority fee
+	// is never paid and the base fee is at least the ArbOS floor
+	RollupArbitrum Rollup = "arbitrum"
+	// RollupOPStack chains charge the L1 data fee from the sender's balance on top of the gas, their base fee is a
+	// fraction of a gwei that moves several-fold within minutes when blocks fill up
+	RollupOPStack Rollup = "op-stack"
+)
+
+// GasConfig configures the gas of the calls a StorageTraceClassifier simulates, it depends on the chain.
+type GasConfig struct {
+	// GasLimit is the gas of the simulated calls other than the transfers, which get the gas cap of the node
+	GasLimit uint64
+	// Legacy is set for chains without EIP-1559, transfers are then traced with a gas price only
+	Legacy bool
+	// Rollup is the kind of rollup of the chain, empty for L1 chains
+	Rollup Rollup
+}
+
+// DefaultGasConfig is for Ethereum mainnet.
+var DefaultGasConfig = GasConfig{
+	GasLimit: 500_000,
+}
+
+func (g GasConfig) gasLimitHex() string {
+	return hexutil.EncodeUint64(g.GasLimit)
+}
+
+// feeBumpPercent is how much the fee cap of a transfer is raised, so a transfer simulated on a later block still pays
+// its base fee.
+func (g GasConfig) feeBumpPercent() int64 {
+	if g.Rollup == RollupOPStack {
+		return 300
+	}
+	return 150
+}
+
+// transferTraceCallParams returns the debug_traceCall calldata params of the scenario's transfer, in the order they are
+// tried: the fees of the transfer first, then no fees at all. A call paying no gas is neither checked against the base
+// fee nor charged the L1 data fee, but tokens charging a fee depending on tx.gasprice see a zero gas price.
+func (g GasConfig) transferTraceCallParams(scenario *jsonrpc.TransferScenario, transferData []byte) []*jsonrpc.DebugTraceCallCalldataParam {
+	withFees := g.transferTraceCallParam(scenario, transferData)
+	if withFees.GasPrice == "" && withFees.MaxFeePerGas == "" {
+		return []*jsonrpc.DebugTraceCallCalldataParam{withFees}
+	}
+	withoutFees := *withFees
+	withoutFees.GasPrice, withoutFees.MaxFeePerGas, withoutFees.MaxPriorityFeePerGas = "", "", ""
+	return []*jsonrpc.DebugTraceCallCalldataParam{withFees, &withoutFees}
+}
+
+// transferTraceCallParam returns the debug_traceCall calldata param of the scenario's transfer with its fees.
+func (g GasConfig) transferTraceCallParam(scenario *jsonrpc.TransferScenario, transferData []byte) *jsonrpc.DebugTraceCallCalldataParam {
+	// some tracing fails if we don't specify maxFeePerGas and maxPriorityFeePerGas
+	var (
+		gasPrice             string
+		maxFeePerGas         string
+		maxPriorityFeePerGas string
+		hasFeeCap            = scenario.GasFeeCap != nil && scenario.GasFeeCap.Sign() != 0
+	)
+	switch {
+	case hasFeeCap && !g.Legacy:
+		maxFeePerGas = hexutil.EncodeBig(g.bumpFee(scenario.GasFeeCap))
+		switch {
+		case g.Rollup == RollupArbitrum:
+			// ArbOS never pays the priority fee, a tip above the bumped fee cap would only fail the call
+			maxPriorityFeePerGas = "0x0"
+		case scenario.GasTipCap != nil:
+			maxPriorityFeePerGas = hexutil.EncodeBig(g.bumpFee(scenario.GasTipCap))
+		}
+	case scenario.GasPrice != nil:
+		gasPrice = hexutil.EncodeBig(scenario.GasPrice)
+	case hasFeeCap:
+		// the fee fields of a legacy chain's export can be set, its nodes reject them
+		gasPrice = hexutil.EncodeBig(scenario.GasFeeCap)
+	}
+	return &jsonrpc.DebugTraceCallCalldataParam{
+		From:                 scenario.MsgSender.String(),
+		GasPrice:             gasPrice,
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+		To:                   scenario.Token.String(),
+		Data:                 hexutil.Encode(transferData),
+	}
+}
+
+func (g GasConfig) bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(g.feeBumpPercent()))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// traceTransfer calls trace with the calldata params of the scenario's transfer, trying the next params while the
+// trace fails because of its gas or fees.
+func (g GasConfig) traceTransfer(
+	scenario *jsonrpc.TransferScenario,
+	transferData []byte,
+	trace func(calldata *jsonrpc.DebugTraceCallCalldataParam) error,
+) error {
+	var err error
+	for i, calldata := range g.transferTraceCallParams(scenario, transferData) {
+		if i > 0 {
+			logger.Debugw("tracing transfer again with other gas params", "token", scenario.Token, "attempt", i+1, "error", err)
+		}
+		if err = trace(calldata); err == nil || !isGasError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// gasErrors are the errors of calls rejected because of their gas or fees, by geth and the Arbitrum and OP-stack nodes.
+var gasErrors = []string{
+	"less than block base fee",
+	"max priority fee per gas higher than max fee per gas",
+	"insufficient funds for gas",
+	"insufficient funds for l1",
+	"intrinsic gas too low",
+	"gas required exceeds allowance",
+	"gas too low",
+}
+
+func isGasError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, e := range gasErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
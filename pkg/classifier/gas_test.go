@@ -0,0 +1,114 @@
+package classifier
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+func TestTransferTraceCallParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		gas      GasConfig
+		scenario jsonrpc.TransferScenario
+		want     jsonrpc.DebugTraceCallCalldataParam
+	}{
+		{
+			name:     "eip-1559 fees bumped",
+			gas:      DefaultGasConfig,
+			scenario: jsonrpc.TransferScenario{GasPrice: big.NewInt(90), GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(2)},
+			want:     jsonrpc.DebugTraceCallCalldataParam{MaxFeePerGas: "0x96", MaxPriorityFeePerGas: "0x3"},
+		},
+		{
+			name:     "arbitrum tip dropped",
+			gas:      GasConfig{GasLimit: 2_000_000, Rollup: RollupArbitrum},
+			scenario: jsonrpc.TransferScenario{GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(200)},
+			want:     jsonrpc.DebugTraceCallCalldataParam{MaxFeePerGas: "0x96", MaxPriorityFeePerGas: "0x0"},
+		},
+		{
+			name:     "op-stack fees bumped more",
+			gas:      GasConfig{GasLimit: 500_000, Rollup: RollupOPStack},
+			scenario: jsonrpc.TransferScenario{GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(2)},
+			want:     jsonrpc.DebugTraceCallCalldataParam{MaxFeePerGas: "0x12c", MaxPriorityFeePerGas: "0x6"},
+		},
+		{
+			name:     "gas price without fee cap",
+			gas:      DefaultGasConfig,
+			scenario: jsonrpc.TransferScenario{GasPrice: big.NewInt(90)},
+			want:     jsonrpc.DebugTraceCallCalldataParam{GasPrice: "0x5a"},
+		},
+		{
+			name:     "legacy gas price",
+			gas:      GasConfig{GasLimit: 500_000, Legacy: true},
+			scenario: jsonrpc.TransferScenario{GasPrice: big.NewInt(90), GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(2)},
+			want:     jsonrpc.DebugTraceCallCalldataParam{GasPrice: "0x5a"},
+		},
+		{
+			name:     "legacy fee cap only",
+			gas:      GasConfig{GasLimit: 500_000, Legacy: true},
+			scenario: jsonrpc.TransferScenario{GasFeeCap: big.NewInt(100)},
+			want:     jsonrpc.DebugTraceCallCalldataParam{GasPrice: "0x64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.gas.transferTraceCallParam(&tt.scenario, nil)
+			assert.Equal(t, tt.want.GasPrice, got.GasPrice)
+			assert.Equal(t, tt.want.MaxFeePerGas, got.MaxFeePerGas)
+			assert.Equal(t, tt.want.MaxPriorityFeePerGas, got.MaxPriorityFeePerGas)
+		})
+	}
+}
+
+func TestTraceTransfer(t *testing.T) {
+	scenario := &jsonrpc.TransferScenario{GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(2)}
+	tests := []struct {
+		name      string
+		scenario  *jsonrpc.TransferScenario
+		errs      []error
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name:      "traced with fees",
+			scenario:  scenario,
+			errs:      []error{nil},
+			wantCalls: []string{"0x96"},
+		},
+		{
+			name:      "retried without fees on gas error",
+			scenario:  scenario,
+			errs:      []error{errors.New("max fee per gas less than block base fee: address 0x01, maxFeePerGas: 150, baseFee: 200"), nil},
+			wantCalls: []string{"0x96", ""},
+		},
+		{
+			name:      "not retried on other errors",
+			scenario:  scenario,
+			errs:      []error{errors.New("execution reverted")},
+			wantCalls: []string{"0x96"},
+			wantErr:   true,
+		},
+		{
+			name:      "not retried without fees",
+			scenario:  &jsonrpc.TransferScenario{},
+			errs:      []error{errors.New("intrinsic gas too low")},
+			wantCalls: []string{""},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			err := DefaultGasConfig.traceTransfer(tt.scenario, nil, func(calldata *jsonrpc.DebugTraceCallCalldataParam) error {
+				calls = append(calls, calldata.MaxFeePerGas)
+				return tt.errs[len(calls)-1]
+			})
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package classifier
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// HoneypotSellFeeBps is the fee on selling back, in basis points, from which a token is a honeypot even though the
+// sell succeeds: most of what was bought can't be sold.
+const HoneypotSellFeeBps = 5000
+
+// HoneypotResult stores whether tokens can be transferred again by the wallet that received them.
+type HoneypotResult struct {
+	// IsHoneypot set to true if the receiver of the transfer could not transfer back what it received, or lost at
+	// least HoneypotSellFeeBps of it doing so
+	IsHoneypot bool
+	// Received is the balance of the receiver after the transfer
+	Received *big.Int
+	// SellFeeBps is the part of Received the msg sender did not get back, in basis points, 0 if the transfer back
+	// failed
+	SellFeeBps int
+}
+
+// ProbeHoneypot simulates the scenario's transfer then, on top of its state, the receiver transferring everything it
+// received back to the msg sender. When the msg sender is a pool, this is a buy followed by a sell.
+// ErrTransferNotSuccess is returned if the scenario's transfer itself fails, since nothing can be said then.
+func (c *StorageTraceClassifier) ProbeHoneypot(scenario *jsonrpc.TransferScenario) (*HoneypotResult, error) {
+	_, blockNumberHex, err := c.resolveBlockNumber(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	success, err := c.transferSucceeds(scenario, blockNumberHex, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		return nil, ErrTransferNotSuccess
+	}
+	transferData, err := packTransferData(scenario)
+	if err != nil {
+		return nil, err
+	}
+	override, err := c.simulateStateDiff(scenario.MsgSender, scenario.Token, transferData, blockNumberHex, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := c.balanceOf(scenario.Token, scenario.To, blockNumberHex, override)
+	if err != nil {
+		return nil, err
+	}
+	result := &HoneypotResult{Received: received}
+	if received.Sign() == 0 {
+		// the whole amount is taken on transfer
+		result.IsHoneypot = true
+		return result, nil
+	}
+
+	transferBack := &jsonrpc.TransferScenario{
+		MsgSender: scenario.To,
+		Token:     scenario.Token,
+		From:      scenario.To,
+		To:        scenario.MsgSender,
+		Amount:    received,
+	}
+	success, err = c.transferSucceeds(transferBack, blockNumberHex, override)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		result.IsHoneypot = true
+		return result, nil
+	}
+
+	// what the msg sender gets back tells the fee on selling
+	transferBackData, err := packTransferData(transferBack)
+	if err != nil {
+		return nil, err
+	}
+	sold, err := c.simulateStateDiff(transferBack.MsgSender, scenario.Token, transferBackData, blockNumberHex, override)
+	if err != nil {
+		return nil, err
+	}
+	before, err := c.balanceOf(scenario.Token, scenario.MsgSender, blockNumberHex, override)
+	if err != nil {
+		return nil, err
+	}
+	after, err := c.balanceOf(scenario.Token, scenario.MsgSender, blockNumberHex, jsonrpc.MergeStateOverrides(override, sold))
+	if err != nil {
+		return nil, err
+	}
+	result.SellFeeBps = feeBps(received, new(big.Int).Sub(after, before))
+	result.IsHoneypot = result.SellFeeBps >= HoneypotSellFeeBps
+	return result, nil
+}
+
+// balanceOf returns the token balance of holder on top of override.
+func (c *StorageTraceClassifier) balanceOf(
+	token, holder common.Address, blockNumberHex string, override jsonrpc.StateOverride,
+) (*big.Int, error) {
+	balanceOfData, err := abis.ERC20.Pack("balanceOf", holder)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := c.ethCallUint256(token, balanceOfData, blockNumberHex, override)
+	if err != nil {
+		return nil, fmt.Errorf("could not eth_call balanceOf(): %w", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,158 @@
+package classifier
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+var (
+	honeypotToken = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	honeypotPool  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	honeypotBuyer = common.HexToAddress("0x4444444444444444444444444444444444444444")
+)
+
+// poolTokenClient simulates a token keeping the balance of each holder in the slot of its address. Transfers to pool,
+// sells, revert if sellReverts, and sellFeeBps of them is burnt.
+type poolTokenClient struct {
+	balances    map[common.Address]*big.Int
+	sellReverts bool
+	sellFeeBps  int64
+}
+
+func balanceSlot(holder common.Address) common.Hash {
+	return common.BytesToHash(holder.Bytes())
+}
+
+func (c *poolTokenClient) balance(holder common.Address, override jsonrpc.StateOverride) *big.Int {
+	if val, ok := override[honeypotToken].StateDiff[balanceSlot(holder)]; ok {
+		return hexutil.MustDecodeBig(val)
+	}
+	if balance, ok := c.balances[holder]; ok {
+		return balance
+	}
+	return new(big.Int)
+}
+
+// transfer returns the balances changed by transfer(to, amount) called by from, nil if it reverts.
+func (c *poolTokenClient) transfer(from common.Address, data []byte, override jsonrpc.StateOverride) map[common.Address][2]*big.Int {
+	args, err := abis.ERC20.Methods["transfer"].Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil
+	}
+	to, amount := args[0].(common.Address), args[1].(*big.Int)
+	fromBalance := c.balance(from, override)
+	if fromBalance.Cmp(amount) < 0 || to == honeypotPool && c.sellReverts {
+		return nil
+	}
+	received := new(big.Int).Set(amount)
+	if to == honeypotPool {
+		fee := new(big.Int).Mul(amount, big.NewInt(c.sellFeeBps))
+		received.Sub(received, fee.Div(fee, big.NewInt(10000)))
+	}
+	toBalance := c.balance(to, override)
+	return map[common.Address][2]*big.Int{
+		from: {fromBalance, new(big.Int).Sub(fromBalance, amount)},
+		to:   {toBalance, new(big.Int).Add(toBalance, received)},
+	}
+}
+
+func (c *poolTokenClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	switch method {
+	case "eth_call":
+		call := args[0].(*jsonrpc.EthCallCalldataParam)
+		data := hexutil.MustDecode(call.Data)
+		var override jsonrpc.StateOverride
+		if len(args) > 2 {
+			override = args[2].(jsonrpc.StateOverride)
+		}
+		switch hexutil.Encode(data[:4]) {
+		case getMethodHash("balanceOf(address)"):
+			holder := common.BytesToAddress(data[4:36])
+			*result.(*string) = hexutil.Encode(common.BigToHash(c.balance(holder, override)).Bytes())
+			return nil
+		case getMethodHash("transfer(address,uint256)"):
+			if c.transfer(common.HexToAddress(call.From), data, override) == nil {
+				return revertError{}
+			}
+			*result.(*string) = hexutil.Encode(common.BigToHash(big.NewInt(1)).Bytes())
+			return nil
+		}
+		return revertError{}
+	case "debug_traceCall":
+		call := args[0].(*jsonrpc.DebugTraceCallCalldataParam)
+		tracer := args[2].(*jsonrpc.DebugTraceCallTracerConfigParam)
+		if tracer.Tracer != "prestateTracer" {
+			return errors.New("not supported")
+		}
+		changes := c.transfer(common.HexToAddress(call.From), hexutil.MustDecode(call.Data), tracer.StateOverrides)
+		if changes == nil {
+			return revertError{}
+		}
+		pre := jsonrpc.PrestateAccount{Storage: make(map[common.Hash]common.Hash)}
+		post := jsonrpc.PrestateAccount{Storage: make(map[common.Hash]common.Hash)}
+		for holder, change := range changes {
+			pre.Storage[balanceSlot(holder)] = common.BigToHash(change[0])
+			post.Storage[balanceSlot(holder)] = common.BigToHash(change[1])
+		}
+		*result.(*jsonrpc.PrestateTracerResult) = jsonrpc.PrestateTracerResult{
+			Pre:  map[common.Address]jsonrpc.PrestateAccount{honeypotToken: pre},
+			Post: map[common.Address]jsonrpc.PrestateAccount{honeypotToken: post},
+		}
+		return nil
+	}
+	return errors.New("not supported")
+}
+
+func (c *poolTokenClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("not supported")
+}
+
+func TestProbeHoneypot(t *testing.T) {
+	buy := &jsonrpc.TransferScenario{
+		MsgSender:   honeypotPool,
+		Token:       honeypotToken,
+		To:          honeypotBuyer,
+		Amount:      big.NewInt(1000),
+		BlockNumber: "0x1036640",
+	}
+	tests := []struct {
+		name           string
+		client         *poolTokenClient
+		wantErr        error
+		wantHoneypot   bool
+		wantSellFeeBps int
+	}{
+		{"normal token", &poolTokenClient{}, nil, false, 0},
+		{"low sell tax", &poolTokenClient{sellFeeBps: 500}, nil, false, 500},
+		{"reverts on sell", &poolTokenClient{sellReverts: true}, nil, true, 0},
+		{"high sell tax", &poolTokenClient{sellFeeBps: 9000}, nil, true, 9000},
+		{"pool holds none", &poolTokenClient{balances: map[common.Address]*big.Int{honeypotPool: new(big.Int)}}, ErrTransferNotSuccess, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.client.balances == nil {
+				tt.client.balances = map[common.Address]*big.Int{honeypotPool: big.NewInt(1_000_000)}
+			}
+			result, err := NewClassifier(tt.client, nil).ProbeHoneypot(buy)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHoneypot, result.IsHoneypot)
+			assert.Equal(t, tt.wantSellFeeBps, result.SellFeeBps)
+			assert.Equal(t, int64(1000), result.Received.Int64())
+		})
+	}
+}
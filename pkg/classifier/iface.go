@@ -1,6 +1,8 @@
 package classifier
 
 import (
+	"math"
+
 	"github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 )
@@ -13,12 +15,20 @@ type FeeOnTransferResult struct {
 	FeeReceiver common.Address
 	//Coefficients is the list of coefficient in fee formular
 	// For now we're using linear re-geression hence the coefficient is at the form
-	// fee= Coefficients[0]*amountIn + Coefficients[1]
+	// fee= Coefficients[0] + Coefficients[1]*amountIn
 	Coefficients []float64
 	//Formular is the string representation of  the fee formular
 	Formular string
 }
 
+// FeeBps returns the proportional fee of the fee formular in basis points, 0 if it could not be regressed.
+func (r FeeOnTransferResult) FeeBps() int {
+	if !r.IsFeeOnTransfer || len(r.Coefficients) < 2 {
+		return 0
+	}
+	return int(math.Round(r.Coefficients[1] * 10000))
+}
+
 // Classifier define required functionalities for a classifier.
 type Classifier interface {
 	// IsFeeOnTransfer returns if the contract is fee on transfer and its fomular
@@ -29,3 +39,35 @@ type Classifier interface {
 	// if codes[] is nil, the classifier will have to go fetch it
 	IsErc20(ercContract common.Address, codes []byte) bool
 }
+
+// CapabilityKind is a kind of owner-controlled capability of a token contract
+type CapabilityKind string
+
+const (
+	CapabilityBlacklist    CapabilityKind = "blacklist"
+	CapabilityPause        CapabilityKind = "pause"
+	CapabilityMint         CapabilityKind = "mint"
+	CapabilityUnboundedFee CapabilityKind = "unbounded-fee-setter"
+	CapabilityUpgradeable  CapabilityKind = "upgradeable"
+)
+
+// OwnerCapability store an owner-controlled capability found in a token contract
+type OwnerCapability struct {
+	Kind CapabilityKind
+	//Method is the signature of the function granting the capability
+	Method string
+	//Confirmed set to true if the capability was confirmed by simulating the owner calling Method
+	Confirmed bool
+	//Detail describes how the capability was confirmed or why it could not be
+	Detail string
+}
+
+// RiskReport store the owner privileges of a token contract
+type RiskReport struct {
+	//Owner is the result of owner(), zero address if the contract has no owner() method
+	Owner common.Address
+	//OwnershipRenounced set to true if owner() returns the zero address or the dead address
+	OwnershipRenounced bool
+	//Capabilities is the list of owner-controlled capabilities found in the bytecode
+	Capabilities []OwnerCapability
+}
@@ -1,13 +1,27 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// Client is the JSON-RPC client used by the helpers of this package, e.g. *rpc.Client.
+type Client interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// maxBatchSize is the maximum number of calls sent in one batch request, most providers reject larger batches
+const maxBatchSize = 100
+
 // EthCallCalldataParam eth_call's calldata param
 type EthCallCalldataParam struct {
 	From string `json:"from"`
@@ -17,17 +31,133 @@ type EthCallCalldataParam struct {
 }
 
 // EthCall eth_call wrapper
-func EthCall(client *rpc.Client, calldata *EthCallCalldataParam, blockNumber string, override StateOverride) (*string, error) {
+func EthCall(client Client, calldata *EthCallCalldataParam, blockNumber string, override StateOverride) (*string, error) {
 	resultHex := new(string)
+	err := client.CallContext(context.Background(), resultHex, "eth_call", ethCallArgs(calldata, blockNumber, override)...)
+	if err != nil {
+		return nil, err
+	}
+	return resultHex, nil
+}
+
+func ethCallArgs(calldata *EthCallCalldataParam, blockNumber string, override StateOverride) []interface{} {
 	args := []interface{}{calldata, blockNumber}
 	if override != nil {
 		args = append(args, override)
 	}
-	err := client.Call(resultHex, "eth_call", args...)
-	if err != nil {
+	return args
+}
+
+// BlockNumber eth_blockNumber wrapper
+func BlockNumber(client Client) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.CallContext(context.Background(), &result, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// ChainID eth_chainId wrapper
+func ChainID(client Client) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.CallContext(context.Background(), &result, "eth_chainId"); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// GetCode eth_getCode wrapper
+func GetCode(client Client, address common.Address, blockNumber string) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := client.CallContext(context.Background(), &result, "eth_getCode", address, blockNumber); err != nil {
 		return nil, err
 	}
-	return resultHex, nil
+	return result, nil
+}
+
+// GetStorageAt eth_getStorageAt wrapper
+func GetStorageAt(client Client, address common.Address, slot common.Hash, blockNumber string) (common.Hash, error) {
+	var result hexutil.Bytes
+	if err := client.CallContext(context.Background(), &result, "eth_getStorageAt", address, slot, blockNumber); err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(result), nil
+}
+
+// TransactionByHash eth_getTransactionByHash wrapper, it also returns the sender of the transaction
+func TransactionByHash(client Client, txHash common.Hash) (*ethtypes.Transaction, common.Address, error) {
+	var raw json.RawMessage
+	if err := client.CallContext(context.Background(), &raw, "eth_getTransactionByHash", txHash); err != nil {
+		return nil, common.Address{}, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, common.Address{}, ethereum.NotFound
+	}
+	tx := new(ethtypes.Transaction)
+	if err := json.Unmarshal(raw, tx); err != nil {
+		return nil, common.Address{}, err
+	}
+	var sender struct {
+		From common.Address `json:"from"`
+	}
+	if err := json.Unmarshal(raw, &sender); err != nil {
+		return nil, common.Address{}, err
+	}
+	return tx, sender.From, nil
+}
+
+// TransactionReceipt eth_getTransactionReceipt wrapper
+func TransactionReceipt(client Client, txHash common.Hash) (*ethtypes.Receipt, error) {
+	var receipt *ethtypes.Receipt
+	if err := client.CallContext(context.Background(), &receipt, "eth_getTransactionReceipt", txHash); err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+// EthCallRequest is an eth_call in a batch
+type EthCallRequest struct {
+	Calldata    *EthCallCalldataParam
+	BlockNumber string
+	Override    StateOverride
+}
+
+// EthCallResult is the result of an eth_call in a batch, Err is set if this call failed
+type EthCallResult struct {
+	Result string
+	Err    error
+}
+
+// BatchEthCall sends eth_calls in batch requests and maps results and errors back to each call.
+// The returned error is only set if a whole batch request failed.
+func BatchEthCall(client Client, requests []EthCallRequest) ([]EthCallResult, error) {
+	var (
+		results = make([]EthCallResult, len(requests))
+		elems   = make([]rpc.BatchElem, len(requests))
+	)
+	for i, r := range requests {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   ethCallArgs(r.Calldata, r.BlockNumber, r.Override),
+			Result: &results[i].Result,
+		}
+	}
+	for start := 0; start < len(elems); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(elems) {
+			end = len(elems)
+		}
+		if err := client.BatchCallContext(context.Background(), elems[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range elems {
+		results[i].Err = elems[i].Error
+	}
+	return results, nil
 }
 
 // DebugTraceCallCalldataParam debug_traceCall's calldata param
@@ -50,31 +180,67 @@ type DebugTraceCallTracerConfigParam struct {
 
 // DebugTraceCall debug_traceCall wrapper
 func DebugTraceCall(
-	client *rpc.Client,
+	client Client,
 	calldata *DebugTraceCallCalldataParam,
 	blockNumber string,
 	tracer *DebugTraceCallTracerConfigParam,
 	result interface{},
 ) error {
-	err := client.Call(result, "debug_traceCall", calldata, blockNumber, tracer)
+	err := client.CallContext(context.Background(), result, "debug_traceCall", calldata, blockNumber, tracer)
 	return err
 }
 
-//type DebugTraceTransactionConfigParam struct {
-//	disableStorage   bool   `json:"disableStorage"`
-//	disableStack     bool   `json:"disableStack"`
-//	enableMemory     bool   `json:"enableMemory"`
-//	enableReturnData bool   `json:"enableReturnData"`
-//	Tracer           string `json:"tracer"`
-//}
+// StructLoggerConfigParam debug_traceCall's and debug_traceTransaction's config param for the default struct logger,
+// used when no tracer is set
+type StructLoggerConfigParam struct {
+	DisableStorage   bool          `json:"disableStorage"`
+	DisableStack     bool          `json:"disableStack"`
+	EnableMemory     bool          `json:"enableMemory"`
+	EnableReturnData bool          `json:"enableReturnData"`
+	StateOverrides   StateOverride `json:"stateOverrides,omitempty"`
+}
+
+// StructLog similar to eth/tracers/logger.StructLogRes
+type StructLog struct {
+	Pc      uint64            `json:"pc"`
+	Op      string            `json:"op"`
+	Gas     uint64            `json:"gas"`
+	GasCost uint64            `json:"gasCost"`
+	Depth   int               `json:"depth"`
+	Error   string            `json:"error,omitempty"`
+	Stack   []string          `json:"stack,omitempty"`
+	Storage map[string]string `json:"storage,omitempty"`
+}
+
+// StructLoggerResult similar to eth/tracers/logger.ExecutionResult
+type StructLoggerResult struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// DebugTraceCallStructLogger debug_traceCall wrapper with the default struct logger
+func DebugTraceCallStructLogger(
+	client Client,
+	calldata *DebugTraceCallCalldataParam,
+	blockNumber string,
+	config *StructLoggerConfigParam,
+) (*StructLoggerResult, error) {
+	result := new(StructLoggerResult)
+	if err := client.CallContext(context.Background(), result, "debug_traceCall", calldata, blockNumber, config); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
 func DebugTraceTransaction(
-	client *rpc.Client,
+	client Client,
 	txHash common.Hash,
 	tracer *DebugTraceCallTracerConfigParam,
 	result interface{},
 ) error {
-	return client.Call(result, "debug_traceTransaction", txHash, tracer)
+	return client.CallContext(context.Background(), result, "debug_traceTransaction", txHash, tracer)
 }
 
 // OverrideAccount similar to ethapi.OverrideAccount
@@ -111,3 +277,25 @@ type CallFrame struct {
 	Logs         []CallLog       `json:"logs,omitempty"`
 	Value        *hexutil.Big    `json:"value,omitempty"`
 }
+
+// TxTraceResult similar to eth/tracers.txTraceResult, debug_traceBlockByNumber returns one per transaction
+type TxTraceResult struct {
+	TxHash common.Hash     `json:"txHash"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// IsExecutionReverted returns true if err is a JSON-RPC error caused by the call's execution failing
+// (reverted, out of gas, ...), as opposed to a transport or provider error.
+func IsExecutionReverted(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	// geth returns code 3 for reverts with a reason
+	if rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	msg := strings.ToLower(rpcErr.Error())
+	return strings.Contains(msg, "revert") || strings.Contains(msg, "out of gas") || strings.Contains(msg, "invalid opcode")
+}
@@ -0,0 +1,85 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient answers eth_calls with their calldata, or an error if the calldata is "revert"
+type fakeClient struct {
+	batches int
+}
+
+func (c *fakeClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return errors.New("not implemented")
+}
+
+func (c *fakeClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	c.batches++
+	if len(b) > maxBatchSize {
+		return fmt.Errorf("batch too large: %d", len(b))
+	}
+	for i := range b {
+		calldata := b[i].Args[0].(*EthCallCalldataParam)
+		if calldata.Data == "revert" {
+			b[i].Error = errors.New("execution reverted")
+			continue
+		}
+		*b[i].Result.(*string) = calldata.Data
+	}
+	return nil
+}
+
+func TestBatchEthCall(t *testing.T) {
+	var requests []EthCallRequest
+	for i := 0; i < maxBatchSize+10; i++ {
+		data := fmt.Sprintf("0x%02x", i)
+		if i == 3 {
+			data = "revert"
+		}
+		requests = append(requests, EthCallRequest{
+			Calldata:    &EthCallCalldataParam{Data: data},
+			BlockNumber: "latest",
+		})
+	}
+
+	client := new(fakeClient)
+	results, err := BatchEthCall(client, requests)
+	require.NoError(t, err)
+	require.Len(t, results, len(requests))
+	assert.Equal(t, 2, client.batches)
+	for i, result := range results {
+		if i == 3 {
+			assert.Error(t, result.Err)
+			continue
+		}
+		assert.NoError(t, result.Err)
+		assert.Equal(t, fmt.Sprintf("0x%02x", i), result.Result)
+	}
+}
+
+func TestMergeStateOverrides(t *testing.T) {
+	var (
+		token = common.HexToAddress("0x01")
+		other = common.HexToAddress("0x02")
+		slot0 = common.HexToHash("0x00")
+		slot1 = common.HexToHash("0x01")
+	)
+	base := StateOverride{token: {StateDiff: map[common.Hash]string{slot0: "0x1", slot1: "0x2"}}}
+	top := StateOverride{
+		token: {StateDiff: map[common.Hash]string{slot1: "0x0"}},
+		other: {StateDiff: map[common.Hash]string{slot0: "0x3"}},
+	}
+	merged := MergeStateOverrides(base, top)
+	assert.Equal(t, map[common.Hash]string{slot0: "0x1", slot1: "0x0"}, merged[token].StateDiff)
+	assert.Equal(t, map[common.Hash]string{slot0: "0x3"}, merged[other].StateDiff)
+	// base is left as it was
+	assert.Equal(t, "0x2", base[token].StateDiff[slot1])
+}
@@ -0,0 +1,421 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Capability is a set of optional features of an endpoint.
+type Capability uint8
+
+const (
+	// CapabilityNativeTracer is set if the endpoint supports debug_trace* with go-ethereum's native tracers
+	// (callTracer, prestateTracer, ...)
+	CapabilityNativeTracer Capability = 1 << iota
+	// CapabilityJSTracer is set if the endpoint supports debug_trace* with JavaScript tracers
+	CapabilityJSTracer
+)
+
+// Has returns true if c includes all capabilities of other.
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+func (c Capability) String() string {
+	var names []string
+	if c.Has(CapabilityNativeTracer) {
+		names = append(names, "native-tracer")
+	}
+	if c.Has(CapabilityJSTracer) {
+		names = append(names, "js-tracer")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// nativeTracers are the tracers built in go-ethereum
+// https://github.com/ethereum/go-ethereum/tree/master/eth/tracers/native
+var nativeTracers = map[string]bool{
+	"callTracer":     true,
+	"flatCallTracer": true,
+	"prestateTracer": true,
+	"4byteTracer":    true,
+	"muxTracer":      true,
+	"noopTracer":     true,
+}
+
+// probeJSTracer is the smallest valid JavaScript tracer
+const probeJSTracer = "{result:function(){return null},fault:function(){}}"
+
+// requiredCapability returns the capability an endpoint needs to serve a request.
+func requiredCapability(method string, args []interface{}) Capability {
+	if !strings.HasPrefix(method, "debug_") {
+		return 0
+	}
+	for _, arg := range args {
+		tracer, ok := arg.(*DebugTraceCallTracerConfigParam)
+		if !ok || tracer == nil {
+			continue
+		}
+		if nativeTracers[tracer.Tracer] {
+			return CapabilityNativeTracer
+		}
+		return CapabilityJSTracer
+	}
+	// any debug namespace will do
+	return CapabilityNativeTracer
+}
+
+// PoolConfig configures a Pool.
+type PoolConfig struct {
+	// Timeout is the timeout of each attempt on an endpoint
+	Timeout time.Duration
+	// MaxFailures is the number of consecutive failures after which an endpoint is considered unhealthy
+	MaxFailures int
+	// Cooldown is how long an unhealthy endpoint is skipped, doubled each time it fails again, up to MaxCooldown
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+}
+
+// DefaultPoolConfig is a reasonable configuration for remote providers.
+var DefaultPoolConfig = PoolConfig{
+	Timeout:     30 * time.Second,
+	MaxFailures: 3,
+	Cooldown:    10 * time.Second,
+	MaxCooldown: 5 * time.Minute,
+}
+
+// EndpointHealth is a snapshot of the health of an endpoint.
+type EndpointHealth struct {
+	Name                string
+	Capabilities        Capability
+	Healthy             bool
+	Requests            int
+	Failures            int
+	ConsecutiveFailures int
+	LastError           error
+	// Latency is the moving average of the latency of successful requests
+	Latency time.Duration
+}
+
+type endpoint struct {
+	name   string
+	client Client
+
+	mu           sync.Mutex
+	capabilities Capability
+	// probed is set once the capabilities are known, probing while they are being probed
+	probed              bool
+	probing             bool
+	requests            int
+	failures            int
+	consecutiveFailures int
+	lastError           error
+	latency             time.Duration
+	unhealthyUntil      time.Time
+	cooldown            time.Duration
+}
+
+func (e *endpoint) healthy(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !now.Before(e.unhealthyUntil)
+}
+
+func (e *endpoint) has(capability Capability) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.capabilities.Has(capability)
+}
+
+// startProbe returns true, and marks the endpoint as being probed, if its capabilities are unknown because it was
+// not reachable when last probed, and its cooldown is over.
+func (e *endpoint) startProbe(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.probed || e.probing || now.Before(e.unhealthyUntil) {
+		return false
+	}
+	e.probing = true
+	return true
+}
+
+func (e *endpoint) recordSuccess(latency time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.consecutiveFailures = 0
+	e.cooldown = 0
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = (4*e.latency + latency) / 5
+	}
+}
+
+func (e *endpoint) recordFailure(err error, config PoolConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.failures++
+	e.consecutiveFailures++
+	e.lastError = err
+	if e.consecutiveFailures < config.MaxFailures {
+		return
+	}
+	if e.cooldown == 0 {
+		e.cooldown = config.Cooldown
+	} else {
+		e.cooldown *= 2
+	}
+	if e.cooldown > config.MaxCooldown {
+		e.cooldown = config.MaxCooldown
+	}
+	e.unhealthyUntil = time.Now().Add(e.cooldown)
+}
+
+func (e *endpoint) health(now time.Time) EndpointHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return EndpointHealth{
+		Name:                e.name,
+		Capabilities:        e.capabilities,
+		Healthy:             !now.Before(e.unhealthyUntil),
+		Requests:            e.requests,
+		Failures:            e.failures,
+		ConsecutiveFailures: e.consecutiveFailures,
+		LastError:           e.lastError,
+		Latency:             e.latency,
+	}
+}
+
+// ErrNoCapableEndpoint is returned when no endpoint of a pool supports a request.
+var ErrNoCapableEndpoint = errors.New("no endpoint supports the request")
+
+// Pool is a Client spreading requests over several endpoints. Trace requests are only routed to the endpoints
+// supporting their tracer, and a request failing on an endpoint (transport error, timeout, rate limit, ...)
+// is sent to the next one. Endpoints which could not be probed are probed again in the background once their
+// cooldown is over.
+type Pool struct {
+	endpoints []*endpoint
+	config    PoolConfig
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewPool probes the capabilities of each client (keyed by a name used in logs, e.g. the URL) and returns a pool
+// of them. Endpoints which can not be reached are kept, without capabilities until they are probed again,
+// and marked as unhealthy.
+func NewPool(ctx context.Context, clients map[string]Client, config PoolConfig) (*Pool, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no endpoint")
+	}
+	if config.MaxFailures < 1 {
+		config.MaxFailures = 1
+	}
+	if config.MaxCooldown < config.Cooldown {
+		config.MaxCooldown = config.Cooldown
+	}
+	p := &Pool{config: config}
+
+	var (
+		wg        sync.WaitGroup
+		endpoints = make([]*endpoint, 0, len(clients))
+	)
+	for name, client := range clients {
+		e := &endpoint{name: name, client: client, probing: true}
+		endpoints = append(endpoints, e)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.probe(ctx, e)
+		}()
+	}
+	wg.Wait()
+
+	reachable := 0
+	for _, e := range endpoints {
+		if e.healthy(time.Now()) {
+			reachable++
+		}
+	}
+	if reachable == 0 {
+		return nil, errors.New("no endpoint is reachable")
+	}
+	p.endpoints = endpoints
+	return p, nil
+}
+
+// DialPool dials each URL with DialRateLimited and returns a pool of them.
+func DialPool(ctx context.Context, urls []string, requestsPerSecond float64, burst int, config PoolConfig) (*Pool, error) {
+	clients := make(map[string]Client, len(urls))
+	for _, url := range urls {
+		client, err := DialRateLimited(url, requestsPerSecond, burst)
+		if err != nil {
+			return nil, fmt.Errorf("could not dial %s: %w", url, err)
+		}
+		clients[url] = client
+	}
+	return NewPool(ctx, clients, config)
+}
+
+// probe finds the capabilities of an endpoint by tracing an empty call with each kind of tracer.
+// The endpoint must be marked as being probed.
+func (p *Pool) probe(ctx context.Context, e *endpoint) {
+	call := func(result interface{}, method string, args ...interface{}) error {
+		ctx, cancel := p.attemptContext(ctx)
+		defer cancel()
+		return e.client.CallContext(ctx, result, method, args...)
+	}
+
+	var blockNumber string
+	if err := call(&blockNumber, "eth_blockNumber"); err != nil {
+		logger.Warnw("endpoint is not reachable", "endpoint", e.name, "error", err)
+		for i := 0; i < p.config.MaxFailures; i++ {
+			e.recordFailure(err, p.config)
+		}
+		e.mu.Lock()
+		e.probing = false
+		e.mu.Unlock()
+		return
+	}
+
+	calldata := &DebugTraceCallCalldataParam{
+		From: "0x0000000000000000000000000000000000000000",
+		To:   "0x0000000000000000000000000000000000000000",
+		Data: "0x",
+	}
+	var capabilities Capability
+	for _, probe := range []struct {
+		capability Capability
+		tracer     string
+	}{
+		{CapabilityNativeTracer, "callTracer"},
+		{CapabilityJSTracer, probeJSTracer},
+	} {
+		var result interface{}
+		err := call(&result, "debug_traceCall", calldata, blockNumber, &DebugTraceCallTracerConfigParam{Tracer: probe.tracer})
+		if err != nil {
+			logger.Debugw("endpoint does not support tracer", "endpoint", e.name, "capability", probe.capability, "error", err)
+			continue
+		}
+		capabilities |= probe.capability
+	}
+	e.mu.Lock()
+	e.capabilities, e.probed, e.probing = capabilities, true, false
+	e.mu.Unlock()
+	logger.Infow("probed endpoint", "endpoint", e.name, "capabilities", capabilities)
+}
+
+func (p *Pool) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.config.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.config.Timeout)
+}
+
+// candidates returns the endpoints with the capability in the order they should be tried: healthy endpoints
+// first, starting from the next one in round-robin order, then unhealthy endpoints as a last resort.
+// It starts probing the endpoints which are due to be probed again.
+func (p *Pool) candidates(capability Capability) []*endpoint {
+	p.mu.Lock()
+	start := p.next
+	p.next = (p.next + 1) % len(p.endpoints)
+	p.mu.Unlock()
+
+	var (
+		now                = time.Now()
+		healthy, unhealthy []*endpoint
+	)
+	for i := range p.endpoints {
+		e := p.endpoints[(start+i)%len(p.endpoints)]
+		if e.startProbe(now) {
+			go p.probe(context.Background(), e)
+		}
+		if !e.has(capability) {
+			continue
+		}
+		if e.healthy(now) {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// shouldFailover returns true if a request failing with err may succeed on another endpoint.
+func shouldFailover(err error) bool {
+	if IsRetryable(err) {
+		return true
+	}
+	// the endpoint does not support the method after all
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601
+}
+
+// do sends a request with send to each candidate endpoint until one does not fail with a transient error.
+func (p *Pool) do(ctx context.Context, capability Capability, send func(ctx context.Context, client Client) error) error {
+	candidates := p.candidates(capability)
+	if len(candidates) == 0 {
+		return fmt.Errorf("%w: requires %s", ErrNoCapableEndpoint, capability)
+	}
+
+	var err error
+	for _, e := range candidates {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		attemptCtx, cancel := p.attemptContext(ctx)
+		start := time.Now()
+		err = send(attemptCtx, e.client)
+		cancel()
+		if ctx.Err() != nil {
+			return err
+		}
+		if err == nil || !shouldFailover(err) {
+			// errors which are not transient are valid responses, e.g. a reverted eth_call
+			e.recordSuccess(time.Since(start))
+			return err
+		}
+		e.recordFailure(err, p.config)
+		logger.Debugw("request failed, trying next endpoint", "endpoint", e.name, "error", err)
+	}
+	return err
+}
+
+func (p *Pool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return p.do(ctx, requiredCapability(method, args), func(ctx context.Context, client Client) error {
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+// BatchCallContext sends the whole batch to one endpoint, able to serve all its requests.
+func (p *Pool) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	var capability Capability
+	for _, elem := range b {
+		capability |= requiredCapability(elem.Method, elem.Args)
+	}
+	return p.do(ctx, capability, func(ctx context.Context, client Client) error {
+		return client.BatchCallContext(ctx, b)
+	})
+}
+
+// Health returns the health of each endpoint.
+func (p *Pool) Health() []EndpointHealth {
+	now := time.Now()
+	health := make([]EndpointHealth, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		health = append(health, e.health(now))
+	}
+	return health
+}
@@ -0,0 +1,144 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// methodNotFoundError is returned by endpoints without the debug namespace
+type methodNotFoundError struct{}
+
+func (methodNotFoundError) Error() string {
+	return "the method debug_traceCall does not exist/is not available"
+}
+func (methodNotFoundError) ErrorCode() int { return -32601 }
+
+// fakeEndpoint supports the given tracers, and fails every request once down is set
+type fakeEndpoint struct {
+	nativeTracer bool
+	jsTracer     bool
+	down         bool
+	requests     int
+
+	mu sync.Mutex
+}
+
+func (e *fakeEndpoint) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	if e.down {
+		return context.DeadlineExceeded
+	}
+	if method != "debug_traceCall" {
+		return json.Unmarshal([]byte(`"0x1"`), result)
+	}
+	tracer := args[2].(*DebugTraceCallTracerConfigParam)
+	if nativeTracers[tracer.Tracer] && e.nativeTracer || !nativeTracers[tracer.Tracer] && e.jsTracer {
+		return nil
+	}
+	return methodNotFoundError{}
+}
+
+func (e *fakeEndpoint) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("not implemented")
+}
+
+func TestPool(t *testing.T) {
+	var (
+		full   = &fakeEndpoint{nativeTracer: true, jsTracer: true}
+		native = &fakeEndpoint{nativeTracer: true}
+		basic  = &fakeEndpoint{}
+	)
+	pool, err := NewPool(context.Background(), map[string]Client{
+		"full":   full,
+		"native": native,
+		"basic":  basic,
+	}, PoolConfig{MaxFailures: 1, Cooldown: time.Hour})
+	require.NoError(t, err)
+
+	capabilities := make(map[string]Capability)
+	for _, h := range pool.Health() {
+		capabilities[h.Name] = h.Capabilities
+	}
+	assert.Equal(t, map[string]Capability{
+		"full":   CapabilityNativeTracer | CapabilityJSTracer,
+		"native": CapabilityNativeTracer,
+		"basic":  0,
+	}, capabilities)
+
+	traceCall := func(tracer string) error {
+		var result interface{}
+		return DebugTraceCall(pool, &DebugTraceCallCalldataParam{}, "latest", &DebugTraceCallTracerConfigParam{Tracer: tracer}, &result)
+	}
+
+	// JS tracer calls are only sent to the endpoint supporting them
+	full.requests, native.requests, basic.requests = 0, 0, 0
+	for i := 0; i < 3; i++ {
+		require.NoError(t, traceCall("{}"))
+	}
+	assert.Equal(t, 3, full.requests)
+	assert.Zero(t, native.requests+basic.requests)
+
+	// native tracer calls fail over to the other capable endpoint
+	full.down = true
+	for i := 0; i < 3; i++ {
+		require.NoError(t, traceCall("callTracer"))
+	}
+	assert.Zero(t, basic.requests)
+	for _, h := range pool.Health() {
+		if h.Name == "full" {
+			assert.False(t, h.Healthy)
+			assert.ErrorIs(t, h.LastError, context.DeadlineExceeded)
+		}
+	}
+
+	// unhealthy endpoints are still tried when no other endpoint can serve the request
+	assert.ErrorIs(t, traceCall("{}"), context.DeadlineExceeded)
+
+	// other requests are served by any endpoint
+	_, err = BlockNumber(pool)
+	assert.NoError(t, err)
+}
+
+func TestPoolReprobe(t *testing.T) {
+	var (
+		native = &fakeEndpoint{nativeTracer: true, down: true}
+		basic  = &fakeEndpoint{}
+	)
+	pool, err := NewPool(context.Background(), map[string]Client{
+		"native": native,
+		"basic":  basic,
+	}, PoolConfig{MaxFailures: 1, Cooldown: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	traceCall := func() error {
+		var result interface{}
+		return DebugTraceCall(pool, &DebugTraceCallCalldataParam{}, "latest", &DebugTraceCallTracerConfigParam{Tracer: "callTracer"}, &result)
+	}
+	// the endpoint was down when probed, so it has no capability
+	assert.ErrorIs(t, traceCall(), ErrNoCapableEndpoint)
+
+	// once it is back and its cooldown is over, the next request probes it again
+	native.down = false
+	time.Sleep(20 * time.Millisecond)
+	_, err = BlockNumber(pool)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		for _, h := range pool.Health() {
+			if h.Name == "native" {
+				return h.Capabilities == CapabilityNativeTracer
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, traceCall())
+}
@@ -0,0 +1,73 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitedTransport waits for the endpoint's limiter before sending each HTTP request.
+type rateLimitedTransport struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// DialRateLimited connects to an HTTP JSON-RPC endpoint, sending at most requestsPerSecond requests per second
+// (with bursts of up to burst requests). Each client has its own limiter, so use one client per endpoint.
+func DialRateLimited(url string, requestsPerSecond float64, burst int) (*rpc.Client, error) {
+	if burst < 1 {
+		burst = 1
+	}
+	httpClient := &http.Client{
+		Transport: &rateLimitedTransport{
+			limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+			next:    http.DefaultTransport,
+		},
+	}
+	return rpc.DialHTTPWithClient(url, httpClient)
+}
+
+// IsRetryable returns true if err is a transient provider or transport error (rate limited, timeout, 5xx, ...)
+// so the request may succeed if sent again.
+func IsRetryable(err error) bool {
+	if err == nil || IsExecutionReverted(err) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32005 is the "limit exceeded" code of EIP-1474
+		if rpcErr.ErrorCode() == -32005 {
+			return true
+		}
+		msg := strings.ToLower(rpcErr.Error())
+		return strings.Contains(msg, "rate limit") ||
+			strings.Contains(msg, "too many requests") ||
+			strings.Contains(msg, "timeout") ||
+			strings.Contains(msg, "timed out")
+	}
+	return false
+}
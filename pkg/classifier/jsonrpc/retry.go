@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryConfig configures the retries of requests failing with transient errors.
+type RetryConfig struct {
+	// MaxRetries is the number of times a request is retried on transient RPC errors
+	MaxRetries int
+	// MinBackoff is the wait before the first retry, doubled on each following retry
+	MinBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryConfig is a conservative configuration for a single remote endpoint.
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries: 5,
+	MinBackoff: 500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+}
+
+// Backoff returns the wait before the given retry, with jitter.
+func (c RetryConfig) Backoff(retry int) time.Duration {
+	minBackoff, maxBackoff := c.MinBackoff, c.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultRetryConfig.MinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+	d := minBackoff << retry
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Retry calls do until it succeeds, fails with an error retryable rejects or MaxRetries retries are done, waiting
+// Backoff between calls. keysAndValues are logged with each retry.
+func (c RetryConfig) Retry(ctx context.Context, retryable func(error) bool, do func() error, keysAndValues ...interface{}) error {
+	for retry := 0; ; retry++ {
+		err := do()
+		if err == nil || retry >= c.MaxRetries || !retryable(err) {
+			return err
+		}
+		wait := c.Backoff(retry)
+		logger.Debugw("retrying", append(keysAndValues, "retry", retry+1, "wait", wait, "error", err)...)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
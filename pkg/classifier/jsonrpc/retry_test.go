@@ -0,0 +1,45 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	config := RetryConfig{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", errs: []error{nil}, wantCalls: 1},
+		{name: "transient error", errs: []error{context.DeadlineExceeded, nil}, wantCalls: 2},
+		{name: "too many retries", errs: []error{context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded}, wantCalls: 3, wantErr: context.DeadlineExceeded},
+		{name: "not retryable", errs: []error{methodNotFoundError{}}, wantCalls: 1, wantErr: methodNotFoundError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			err := config.Retry(context.Background(), IsRetryable, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	config := RetryConfig{MinBackoff: time.Second, MaxBackoff: 4 * time.Second}
+	for retry, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		got := config.Backoff(retry)
+		assert.GreaterOrEqual(t, got, want/2)
+		assert.LessOrEqual(t, got, want)
+	}
+	assert.LessOrEqual(t, RetryConfig{}.Backoff(0), DefaultRetryConfig.MinBackoff)
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/utils"
@@ -47,22 +46,24 @@ var (
 	)
 )
 
-type PrestateTracerResult struct {
-	Post map[common.Address]struct {
-		Balance *hexutil.Big                `json:"balance,omitempty"`
-		Code    []byte                      `json:"code,omitempty"`
-		Nonce   uint64                      `json:"nonce,omitempty"`
-		Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
-	} `json:"post"`
+// PrestateAccount similar to eth/tracers/native.account
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
 }
 
-func ExtractStateDiff(scenario *TransferScenario, transferTraceResult *PrestateTracerResult, blockNumberHex string, client *rpc.Client) (*big.Int, error) {
-	transferStateDiff := make(StateOverride)
-	/*
-		Step 1.2: extract the stateAfter
-	*/
+type PrestateTracerResult struct {
+	Pre  map[common.Address]PrestateAccount `json:"pre"`
+	Post map[common.Address]PrestateAccount `json:"post"`
+}
 
-	for addr, override := range transferTraceResult.Post {
+// PostStateOverride converts the post state of a prestateTracer diffMode result to a state override,
+// so following calls can be made on top of the traced call.
+func PostStateOverride(traceResult *PrestateTracerResult) StateOverride {
+	stateDiff := make(StateOverride)
+	for addr, override := range traceResult.Post {
 		var (
 			balance *hexutil.Big
 			nonce   = new(hexutil.Uint64)
@@ -72,16 +73,66 @@ func ExtractStateDiff(scenario *TransferScenario, transferTraceResult *PrestateT
 			balance = override.Balance
 		}
 		*nonce = hexutil.Uint64(override.Nonce)
+		// slots cleared by the call are only in the pre state
+		for slot := range traceResult.Pre[addr].Storage {
+			storage[slot] = utils.RemoveLeadingZerosFromHash(common.Hash{})
+		}
 		for slot, val := range override.Storage {
 			storage[slot] = utils.RemoveLeadingZerosFromHash(val)
 		}
-		transferStateDiff[addr] = OverrideAccount{
+		stateDiff[addr] = OverrideAccount{
 			Balance:   balance,
 			Code:      override.Code,
 			Nonce:     nonce,
 			StateDiff: storage,
 		}
 	}
+	return stateDiff
+}
+
+// MergeStateOverrides returns base with the accounts and slots of top, the post state of a call made on top of base,
+// written over it.
+func MergeStateOverrides(base, top StateOverride) StateOverride {
+	merged := make(StateOverride, len(base)+len(top))
+	for addr, account := range base {
+		stateDiff := make(map[common.Hash]string, len(account.StateDiff))
+		for slot, val := range account.StateDiff {
+			stateDiff[slot] = val
+		}
+		account.StateDiff = stateDiff
+		merged[addr] = account
+	}
+	for addr, account := range top {
+		mergedAccount, ok := merged[addr]
+		if !ok {
+			merged[addr] = account
+			continue
+		}
+		if account.Balance != nil {
+			mergedAccount.Balance = account.Balance
+		}
+		if account.Nonce != nil {
+			mergedAccount.Nonce = account.Nonce
+		}
+		if len(account.Code) > 0 {
+			mergedAccount.Code = account.Code
+		}
+		for slot, val := range account.StateDiff {
+			if mergedAccount.StateDiff == nil {
+				mergedAccount.StateDiff = make(map[common.Hash]string)
+			}
+			mergedAccount.StateDiff[slot] = val
+		}
+		merged[addr] = mergedAccount
+	}
+	return merged
+}
+
+func ExtractStateDiff(scenario *TransferScenario, transferTraceResult *PrestateTracerResult, blockNumberHex string, client Client) (*big.Int, error) {
+	/*
+		Step 1.2: extract the stateAfter
+	*/
+	transferStateDiff := PostStateOverride(transferTraceResult)
 
 	/*
 		Step 2: Make 2 balanceOf(to) calls: 1 without statediff overrides and 1 with statediff overrides
@@ -93,48 +144,35 @@ func ExtractStateDiff(scenario *TransferScenario, transferTraceResult *PrestateT
 		return nil, err
 	}
 
-	var (
-		balanceBeforeTransfer *big.Int
-		balanceAfterTransfer  *big.Int
-	)
-
-	balanceOfBeforeResult, err := EthCall(
-		client,
-		&EthCallCalldataParam{
-			From: scenario.MsgSender.String(),
-			To:   scenario.Token.String(),
-			Data: hexutil.Encode(balanceOfData),
-		},
-		blockNumberHex,
-		nil,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("could not eth_call balanceOf() before transfer: %w", err)
+	balanceOfCalldata := &EthCallCalldataParam{
+		From: scenario.MsgSender.String(),
+		To:   scenario.Token.String(),
+		Data: hexutil.Encode(balanceOfData),
 	}
-	decoded, err := hexutil.Decode(*balanceOfBeforeResult)
+	results, err := BatchEthCall(client, []EthCallRequest{
+		{Calldata: balanceOfCalldata, BlockNumber: blockNumberHex},
+		{Calldata: balanceOfCalldata, BlockNumber: blockNumberHex, Override: transferStateDiff},
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not eth_call balanceOf(): %w", err)
 	}
-	balanceBeforeTransfer = new(big.Int).SetBytes(decoded)
-
-	balanceOfAfterResult, err := EthCall(
-		client,
-		&EthCallCalldataParam{
-			From: scenario.MsgSender.String(),
-			To:   scenario.Token.String(),
-			Data: hexutil.Encode(balanceOfData),
-		},
-		blockNumberHex,
-		transferStateDiff,
-	)
+	if results[0].Err != nil {
+		return nil, fmt.Errorf("could not eth_call balanceOf() before transfer: %w", results[0].Err)
+	}
+	if results[1].Err != nil {
+		return nil, fmt.Errorf("could not eth_call balanceOf() after transfer: %w", results[1].Err)
+	}
+
+	decoded, err := hexutil.Decode(results[0].Result)
 	if err != nil {
-		return nil, fmt.Errorf("could not eth_call balanceOf() after transfer: %w", err)
+		return nil, err
 	}
-	decoded, err = hexutil.Decode(*balanceOfAfterResult)
+	balanceBeforeTransfer := new(big.Int).SetBytes(decoded)
+	decoded, err = hexutil.Decode(results[1].Result)
 	if err != nil {
 		return nil, err
 	}
-	balanceAfterTransfer = new(big.Int).SetBytes(decoded)
+	balanceAfterTransfer := new(big.Int).SetBytes(decoded)
 
 	if balanceAfterTransfer.Cmp(balanceBeforeTransfer) <= 0 {
 		return nil, fmt.Errorf("balance after transfer is <= balance before transfer")
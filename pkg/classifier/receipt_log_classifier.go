@@ -0,0 +1,299 @@
+package classifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// ReceiptLogClassifier classifies tokens from the receipts of historical transfers, for chains whose nodes only serve the
+// standard JSON-RPC methods: no debug_trace*, no state overrides.
+// Only txs calling transfer() or transferFrom() of the token directly can be used, the input of internal calls is not
+// known without tracing. If the nodes serve the callTracer, the transfer calls made by other contracts (routers,
+// aggregators, ...) are found in the traces of the txs and each is paired with the Transfer events it emitted.
+type ReceiptLogClassifier struct {
+	client     jsonrpc.Client
+	traceCalls bool
+}
+
+var _ Classifier = (*ReceiptLogClassifier)(nil)
+
+// NewReceiptLogClassifier rpcClient is either a *rpc.Client or a *jsonrpc.Pool to use several endpoints, traceCalls
+// is set if it serves debug_traceTransaction with the callTracer.
+func NewReceiptLogClassifier(rpcClient jsonrpc.Client, traceCalls bool) *ReceiptLogClassifier {
+	return &ReceiptLogClassifier{
+		client:     rpcClient,
+		traceCalls: traceCalls,
+	}
+}
+
+func (c *ReceiptLogClassifier) IsErc20(contractAddress common.Address, codes []byte) bool {
+	if codes == nil {
+		code, err := jsonrpc.GetCode(c.client, contractAddress, "latest")
+		if err != nil || len(code) == 0 {
+			return false
+		}
+		codes = code
+	}
+	return IsErc20(codes)
+}
+
+// TransferEvidence pairs the amount a transfer() or transferFrom() tx requested with the Transfer events the token
+// emitted in the tx.
+type TransferEvidence struct {
+	TxHash common.Hash
+	// From is the owner of the transferred tokens, To the recipient
+	From common.Address
+	To   common.Address
+	// Requested is the amount in the input of the tx
+	Requested *big.Int
+	// Received is the sum of the Transfer events from From to To
+	Received *big.Int
+	// Fees are the sums of the other Transfer events from From, by receiver. The zero address receives burnt fees.
+	Fees map[common.Address]*big.Int
+}
+
+// Fee returns the part of the requested amount the recipient did not receive, or the fees sent elsewhere if larger.
+func (e *TransferEvidence) Fee() *big.Int {
+	fee := new(big.Int).Sub(e.Requested, e.Received)
+	fees := new(big.Int)
+	for _, amount := range e.Fees {
+		fees.Add(fees, amount)
+	}
+	if fees.Cmp(fee) > 0 {
+		return fees
+	}
+	return fee
+}
+
+// FeeReceiver returns the receiver of the largest fee, ok is false if the transfer sent no fee event.
+func (e *TransferEvidence) FeeReceiver() (receiver common.Address, ok bool) {
+	var largest *big.Int
+	for address, amount := range e.Fees {
+		if largest == nil || amount.Cmp(largest) > 0 || amount.Cmp(largest) == 0 && address.Hex() < receiver.Hex() {
+			receiver, largest = address, amount
+		}
+	}
+	return receiver, largest != nil
+}
+
+// ErrNotTokenTransfer is returned for a tx not calling transfer() or transferFrom() of the token, or reverted.
+var ErrNotTokenTransfer = errors.New("tx is not a successful transfer of the token")
+
+// callTracerWithLogs is the callTracer config returning the logs emitted by each call.
+var callTracerWithLogs = &jsonrpc.DebugTraceCallTracerConfigParam{
+	Tracer:       "callTracer",
+	TracerConfig: json.RawMessage(`{"withLog":true}`),
+}
+
+// transferEvidence decodes the transfer() or transferFrom() input of a tx sent by sender to token, and sums the
+// Transfer events of token in its receipt.
+func transferEvidence(token, sender common.Address, tx *ethtypes.Transaction, receipt *ethtypes.Receipt) (*TransferEvidence, error) {
+	if tx.To() == nil || *tx.To() != token || receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		return nil, ErrNotTokenTransfer
+	}
+	return newTransferEvidence(tx.Hash(), token, sender, tx.Data(), receipt.Logs)
+}
+
+// callTransferEvidences returns the evidences of the successful transfer() and transferFrom() calls to token in the
+// callTracer trace of a tx, each paired with the Transfer events emitted within the call.
+func callTransferEvidences(txHash common.Hash, token common.Address, root *jsonrpc.CallFrame) ([]*TransferEvidence, error) {
+	// the effects of a call reverted by a caller catching the revert are reverted too
+	reverted := make(map[*jsonrpc.CallFrame]bool)
+	walkCallFrames(root, 0, func(call *jsonrpc.CallFrame, _ int) {
+		if call.Error != "" {
+			walkCallFrames(call, 0, func(call *jsonrpc.CallFrame, _ int) {
+				reverted[call] = true
+			})
+		}
+	})
+	var evidences []*TransferEvidence
+	for _, call := range FindTransferCalls(root, token) {
+		if reverted[call] {
+			continue
+		}
+		if len(call.Output) > 0 && new(big.Int).SetBytes(call.Output).Cmp(big.NewInt(1)) != 0 {
+			// transfer returned false
+			continue
+		}
+		var logs []*ethtypes.Log
+		walkCallFrames(call, 0, func(call *jsonrpc.CallFrame, _ int) {
+			for _, l := range call.Logs {
+				logs = append(logs, &ethtypes.Log{Address: l.Address, Topics: l.Topics, Data: l.Data, TxHash: txHash})
+			}
+		})
+		evidence, err := newTransferEvidence(txHash, token, call.From, call.Input, logs)
+		if err != nil {
+			return nil, err
+		}
+		evidences = append(evidences, evidence)
+	}
+	return evidences, nil
+}
+
+// newTransferEvidence decodes the input of a transfer() or transferFrom() call sent by sender to token, and sums the
+// Transfer events of token in logs.
+func newTransferEvidence(txHash common.Hash, token, sender common.Address, input []byte, logs []*ethtypes.Log) (*TransferEvidence, error) {
+	transfer, err := DecodeTransferCall(input)
+	if errors.Is(err, ErrNotTransferCall) {
+		return nil, ErrNotTokenTransfer
+	}
+	if err != nil {
+		return nil, err
+	}
+	evidence := &TransferEvidence{
+		TxHash:    txHash,
+		From:      sender,
+		To:        transfer.To,
+		Requested: transfer.Amount,
+		Received:  new(big.Int),
+		Fees:      make(map[common.Address]*big.Int),
+	}
+	if transfer.IsTransferFrom {
+		evidence.From = transfer.From
+	}
+	for _, vLog := range logs {
+		if vLog.Address != token || !IsTransferEvent(vLog) {
+			continue
+		}
+		event, err := DecodeTransferEvent(vLog)
+		if err != nil {
+			return nil, err
+		}
+		if event.From != evidence.From {
+			continue
+		}
+		if event.To == evidence.To {
+			evidence.Received.Add(evidence.Received, event.Amount)
+			continue
+		}
+		if evidence.Fees[event.To] == nil {
+			evidence.Fees[event.To] = new(big.Int)
+		}
+		evidence.Fees[event.To].Add(evidence.Fees[event.To], event.Amount)
+	}
+	return evidence, nil
+}
+
+// CollectEvidences fetches the txs and receipts of txHashes, or their traces if the nodes serve the callTracer, and
+// keeps the transfers of token.
+func (c *ReceiptLogClassifier) CollectEvidences(token common.Address, txHashes []common.Hash) ([]*TransferEvidence, error) {
+	var evidences []*TransferEvidence
+	for _, txHash := range txHashes {
+		if c.traceCalls {
+			frame := new(jsonrpc.CallFrame)
+			if err := jsonrpc.DebugTraceTransaction(c.client, txHash, callTracerWithLogs, frame); err != nil {
+				return nil, fmt.Errorf("could not trace tx %s: %w", txHash, err)
+			}
+			callEvidences, err := callTransferEvidences(txHash, token, frame)
+			if err != nil {
+				logger.Warnw("could not collect transfer evidence", "txHash", txHash, "error", err)
+				continue
+			}
+			evidences = append(evidences, callEvidences...)
+			continue
+		}
+
+		tx, sender, err := jsonrpc.TransactionByHash(c.client, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("could not get tx %s: %w", txHash, err)
+		}
+		if tx.To() == nil || *tx.To() != token {
+			continue
+		}
+		receipt, err := jsonrpc.TransactionReceipt(c.client, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("could not get receipt of tx %s: %w", txHash, err)
+		}
+		evidence, err := transferEvidence(token, sender, tx, receipt)
+		if errors.Is(err, ErrNotTokenTransfer) {
+			continue
+		}
+		if err != nil {
+			logger.Warnw("could not collect transfer evidence", "txHash", txHash, "error", err)
+			continue
+		}
+		evidences = append(evidences, evidence)
+	}
+	return evidences, nil
+}
+
+// IsFeeOnTransfer implement token classifier for ReceiptLogClassifier from the txs of the Transfer events.
+// The logs are not fetched, they must be given.
+func (c *ReceiptLogClassifier) IsFeeOnTransfer(ercContract common.Address, logs []ethtypes.Log) (FeeOnTransferResult, error) {
+	if len(logs) == 0 {
+		return FeeOnTransferResult{}, errors.New("there is no logs for processing")
+	}
+	var (
+		seen     = make(map[common.Hash]struct{}, len(logs))
+		txHashes []common.Hash
+	)
+	for _, vLog := range logs {
+		if vLog.Address != ercContract {
+			continue
+		}
+		if _, ok := seen[vLog.TxHash]; ok {
+			continue
+		}
+		seen[vLog.TxHash] = struct{}{}
+		txHashes = append(txHashes, vLog.TxHash)
+	}
+	evidences, err := c.CollectEvidences(ercContract, txHashes)
+	if err != nil {
+		return FeeOnTransferResult{}, err
+	}
+	return decideFeeOnTransferFromEvidences(evidences)
+}
+
+// decideFeeOnTransferFromEvidences classifies a token as fee on transfer if a recipient received less than requested
+// or a fee was sent elsewhere. The fee receiver is the one receiving the most fees, the fee formular is regressed
+// from the requested amounts.
+func decideFeeOnTransferFromEvidences(evidences []*TransferEvidence) (FeeOnTransferResult, error) {
+	if len(evidences) == 0 {
+		return FeeOnTransferResult{}, ErrCouldNotDecide
+	}
+	var (
+		numLess   int
+		fees      = make([]float64, 0, len(evidences))
+		requested = make([]float64, 0, len(evidences))
+		receivers = make(map[common.Address]int)
+		mostFees  common.Address
+	)
+	for _, e := range evidences {
+		fee := e.Fee()
+		if fee.Sign() <= 0 {
+			continue
+		}
+		numLess++
+		if receiver, ok := e.FeeReceiver(); ok {
+			receivers[receiver]++
+			if receivers[receiver] > receivers[mostFees] || receivers[receiver] == receivers[mostFees] && receiver.Hex() < mostFees.Hex() {
+				mostFees = receiver
+			}
+		}
+		f, _ := fee.Float64()
+		r, _ := e.Requested.Float64()
+		fees = append(fees, f)
+		requested = append(requested, r)
+	}
+	logger.Infow("finished pairing transfers", "len(txs)", len(evidences), "txs with fee", numLess, "most fees to", mostFees)
+	if numLess == 0 {
+		return FeeOnTransferResult{}, nil
+	}
+
+	result := FeeOnTransferResult{IsFeeOnTransfer: true, FeeReceiver: mostFees}
+	r, err := regress(fees, requested)
+	if err != nil {
+		logger.Infow("cannot regress from data", "error", err)
+		return result, nil
+	}
+	result.Coefficients = r.GetCoeffs()
+	result.Formular = r.Formula
+	return result, nil
+}
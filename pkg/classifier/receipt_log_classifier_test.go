@@ -0,0 +1,259 @@
+package classifier
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+func transferLog(token, from, to common.Address, amount int64) *ethtypes.Log {
+	return &ethtypes.Log{
+		Address: token,
+		Topics: []common.Hash{
+			transferEventABI.ID,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data: common.BigToHash(big.NewInt(amount)).Bytes(),
+	}
+}
+
+func TestTransferEvidence(t *testing.T) {
+	var (
+		token     = common.HexToAddress("0x5732046a883704404f284ce41ffadd5b007fd668")
+		sender    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		owner     = common.HexToAddress("0x3333333333333333333333333333333333333333")
+		receiver  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		feeWallet = common.HexToAddress("0x4444444444444444444444444444444444444444")
+	)
+	transferInput, err := abis.ERC20.Pack("transfer", receiver, big.NewInt(1000))
+	require.NoError(t, err)
+	transferFromInput, err := abis.ERC20.Pack("transferFrom", owner, receiver, big.NewInt(1000))
+	require.NoError(t, err)
+	approveInput, err := abis.ERC20.Pack("approve", receiver, big.NewInt(1000))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		to           common.Address
+		input        []byte
+		status       uint64
+		logs         []*ethtypes.Log
+		wantErr      error
+		wantFrom     common.Address
+		wantFee      int64
+		wantReceiver *common.Address
+	}{
+		{
+			name:     "exact transfer",
+			to:       token,
+			input:    transferInput,
+			status:   ethtypes.ReceiptStatusSuccessful,
+			logs:     []*ethtypes.Log{transferLog(token, sender, receiver, 1000)},
+			wantFrom: sender,
+		},
+		{
+			name:     "reflection emits less",
+			to:       token,
+			input:    transferInput,
+			status:   ethtypes.ReceiptStatusSuccessful,
+			logs:     []*ethtypes.Log{transferLog(token, sender, receiver, 980)},
+			wantFrom: sender,
+			wantFee:  20,
+		},
+		{
+			name:   "fee sent to a wallet",
+			to:     token,
+			input:  transferFromInput,
+			status: ethtypes.ReceiptStatusSuccessful,
+			logs: []*ethtypes.Log{
+				transferLog(token, owner, feeWallet, 30),
+				transferLog(token, owner, receiver, 970),
+				// events of other tokens are ignored
+				transferLog(common.HexToAddress("0x1"), owner, feeWallet, 1000),
+			},
+			wantFrom:     owner,
+			wantFee:      30,
+			wantReceiver: &feeWallet,
+		},
+		{
+			name:    "reverted",
+			to:      token,
+			input:   transferInput,
+			status:  ethtypes.ReceiptStatusFailed,
+			wantErr: ErrNotTokenTransfer,
+		},
+		{
+			name:    "not a transfer",
+			to:      token,
+			input:   approveInput,
+			status:  ethtypes.ReceiptStatusSuccessful,
+			wantErr: ErrNotTokenTransfer,
+		},
+		{
+			name:    "another contract",
+			to:      common.HexToAddress("0x1"),
+			input:   transferInput,
+			status:  ethtypes.ReceiptStatusSuccessful,
+			wantErr: ErrNotTokenTransfer,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := ethtypes.NewTx(&ethtypes.LegacyTx{To: &tt.to, Data: tt.input})
+			receipt := &ethtypes.Receipt{Status: tt.status, Logs: tt.logs}
+
+			got, err := transferEvidence(token, sender, tx, receipt)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, got.From)
+			assert.Equal(t, receiver, got.To)
+			assert.Equal(t, big.NewInt(1000), got.Requested)
+			assert.Zero(t, got.Fee().Cmp(big.NewInt(tt.wantFee)))
+			feeReceiver, ok := got.FeeReceiver()
+			assert.Equal(t, tt.wantReceiver != nil, ok)
+			if tt.wantReceiver != nil {
+				assert.Equal(t, *tt.wantReceiver, feeReceiver)
+			}
+		})
+	}
+}
+
+func callLog(token, from, to common.Address, amount int64) jsonrpc.CallLog {
+	l := transferLog(token, from, to, amount)
+	return jsonrpc.CallLog{Address: l.Address, Topics: l.Topics, Data: l.Data}
+}
+
+func TestCallTransferEvidences(t *testing.T) {
+	var (
+		token          = common.HexToAddress("0x5732046a883704404f284ce41ffadd5b007fd668")
+		implementation = common.HexToAddress("0x6666666666666666666666666666666666666666")
+		router         = common.HexToAddress("0x5555555555555555555555555555555555555555")
+		owner          = common.HexToAddress("0x3333333333333333333333333333333333333333")
+		receiver       = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		feeWallet      = common.HexToAddress("0x4444444444444444444444444444444444444444")
+		txHash         = common.HexToHash("0x01")
+		success        = common.BigToHash(big.NewInt(1)).Bytes()
+	)
+	transferInput, err := abis.ERC20.Pack("transfer", receiver, big.NewInt(1000))
+	require.NoError(t, err)
+	transferFromInput, err := abis.ERC20.Pack("transferFrom", owner, receiver, big.NewInt(1000))
+	require.NoError(t, err)
+
+	root := &jsonrpc.CallFrame{
+		Type: "CALL",
+		From: owner,
+		To:   &router,
+		Calls: []jsonrpc.CallFrame{
+			{
+				// the fee is taken by the implementation of a proxy token
+				Type:   "CALL",
+				From:   router,
+				To:     &token,
+				Input:  transferFromInput,
+				Output: success,
+				Calls: []jsonrpc.CallFrame{{
+					Type:   "DELEGATECALL",
+					From:   token,
+					To:     &implementation,
+					Input:  transferFromInput,
+					Output: success,
+					Logs: []jsonrpc.CallLog{
+						callLog(token, owner, feeWallet, 30),
+						callLog(token, owner, receiver, 970),
+					},
+				}},
+			},
+			{
+				Type:   "CALL",
+				From:   router,
+				To:     &token,
+				Input:  transferInput,
+				Output: success,
+				Logs:   []jsonrpc.CallLog{callLog(token, router, receiver, 1000)},
+			},
+			{
+				// a transfer returning false
+				Type:   "CALL",
+				From:   router,
+				To:     &token,
+				Input:  transferInput,
+				Output: common.Hash{}.Bytes(),
+			},
+			{
+				// a transfer reverted by its caller
+				Type:  "CALL",
+				From:  router,
+				To:    &router,
+				Error: "execution reverted",
+				Calls: []jsonrpc.CallFrame{{
+					Type:   "CALL",
+					From:   router,
+					To:     &token,
+					Input:  transferInput,
+					Output: success,
+				}},
+			},
+		},
+	}
+
+	got, err := callTransferEvidences(txHash, token, root)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	assert.Equal(t, owner, got[0].From)
+	assert.Equal(t, big.NewInt(970), got[0].Received)
+	assert.Zero(t, got[0].Fee().Cmp(big.NewInt(30)))
+	feeReceiver, ok := got[0].FeeReceiver()
+	assert.True(t, ok)
+	assert.Equal(t, feeWallet, feeReceiver)
+
+	// the events of the first call are not counted in the second
+	assert.Equal(t, router, got[1].From)
+	assert.Equal(t, big.NewInt(1000), got[1].Received)
+	assert.Zero(t, got[1].Fee().Sign())
+	assert.Equal(t, txHash, got[1].TxHash)
+}
+
+func TestDecideFeeOnTransferFromEvidences(t *testing.T) {
+	feeWallet := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	evidence := func(requested, received, fee int64) *TransferEvidence {
+		e := &TransferEvidence{
+			Requested: big.NewInt(requested),
+			Received:  big.NewInt(received),
+			Fees:      make(map[common.Address]*big.Int),
+		}
+		if fee > 0 {
+			e.Fees[feeWallet] = big.NewInt(fee)
+		}
+		return e
+	}
+
+	_, err := decideFeeOnTransferFromEvidences(nil)
+	assert.ErrorIs(t, err, ErrCouldNotDecide)
+
+	got, err := decideFeeOnTransferFromEvidences([]*TransferEvidence{evidence(1000, 1000, 0), evidence(500, 500, 0)})
+	require.NoError(t, err)
+	assert.False(t, got.IsFeeOnTransfer)
+
+	got, err = decideFeeOnTransferFromEvidences([]*TransferEvidence{
+		evidence(1000, 970, 30),
+		evidence(2000, 1940, 60),
+		evidence(3000, 2910, 90),
+		evidence(500, 500, 0),
+	})
+	require.NoError(t, err)
+	assert.True(t, got.IsFeeOnTransfer)
+	assert.Equal(t, feeWallet, got.FeeReceiver)
+	assert.NotEmpty(t, got.Formular)
+}
@@ -0,0 +1,387 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/vm"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+var (
+	deadAddress = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	// eip1967ImplementationSlot is bytes32(uint256(keccak256('eip1967.proxy.implementation')) - 1)
+	eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+	// unboundedFeeTestValue is set by fee setters to check if they have an upper bound.
+	// It is 100x the maximum fee when the fee is a percentage and 100% when it is in basis points.
+	unboundedFeeTestValue = big.NewInt(10000)
+
+	capabilityMethodSignatures = map[CapabilityKind][]string{
+		CapabilityBlacklist: {
+			"blacklist(address)",
+			"addToBlacklist(address)",
+			"addBlacklist(address)",
+			"blacklistAddress(address,bool)",
+			"setBlacklist(address,bool)",
+			"updateBlacklist(address,bool)",
+			"setIsBlacklisted(address,bool)",
+			"addBot(address)",
+			"addBots(address[])",
+			"setBot(address,bool)",
+			"setBots(address[],bool)",
+			"blockBots(address[])",
+		},
+		CapabilityPause: {
+			"pause()",
+			"setPaused(bool)",
+		},
+		CapabilityMint: {
+			"mint(address,uint256)",
+			"mintTo(address,uint256)",
+			"mint(uint256)",
+		},
+		CapabilityUnboundedFee: {
+			"setFee(uint256)",
+			"setFee(uint256,uint256)",
+			"setFees(uint256,uint256)",
+			"setTaxFee(uint256)",
+			"setTaxFeePercent(uint256)",
+			"setBuyFee(uint256)",
+			"setSellFee(uint256)",
+			"setBuyTax(uint256)",
+			"setSellTax(uint256)",
+			"setTaxes(uint256,uint256)",
+			"updateFees(uint256,uint256)",
+			"updateBuyFees(uint256,uint256,uint256)",
+			"updateSellFees(uint256,uint256,uint256)",
+		},
+	}
+)
+
+// codeSelectors returns the 4-byte values pushed by PUSH4 instructions, which include the selectors of the
+// contract's methods in the function dispatcher.
+func codeSelectors(code []byte) map[string]bool {
+	selectors := make(map[string]bool)
+	it := asm.NewInstructionIterator(code)
+	for it.Next() {
+		if it.Op() == vm.PUSH4 && len(it.Arg()) == 4 {
+			selectors[hexutil.Encode(it.Arg())] = true
+		}
+	}
+	return selectors
+}
+
+// methodInputTypes returns the input types of a method signature, e.g. [address uint256] for transfer(address,uint256).
+// Tuple inputs are not supported.
+func methodInputTypes(signature string) []string {
+	start, end := strings.Index(signature, "("), strings.LastIndex(signature, ")")
+	if start < 0 || end <= start+1 {
+		return nil
+	}
+	return strings.Split(signature[start+1:end], ",")
+}
+
+// packCall returns the calldata of a method signature with the given args.
+func packCall(signature string, args ...interface{}) ([]byte, error) {
+	var arguments abi.Arguments
+	for _, t := range methodInputTypes(signature) {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, abi.Argument{Type: typ})
+	}
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(hexutil.MustDecode(getMethodHash(signature)), packed...), nil
+}
+
+// capabilityCallArgs fills the inputs of a capability method: addresses are target and amounts are value.
+func capabilityCallArgs(signature string, target common.Address, value *big.Int) ([]interface{}, error) {
+	var args []interface{}
+	for _, t := range methodInputTypes(signature) {
+		switch t {
+		case "address":
+			args = append(args, target)
+		case "address[]":
+			args = append(args, []common.Address{target})
+		case "bool":
+			args = append(args, true)
+		case "uint256":
+			args = append(args, value)
+		default:
+			return nil, fmt.Errorf("unsupported input type %s", t)
+		}
+	}
+	return args, nil
+}
+
+// callSucceeds eth_calls data and returns false if the call reverted.
+func (c *StorageTraceClassifier) callSucceeds(
+	from, to common.Address, data []byte, blockNumberHex string, override jsonrpc.StateOverride,
+) (bool, []byte, error) {
+	result, err := jsonrpc.EthCall(
+		c.client,
+		&jsonrpc.EthCallCalldataParam{
+			From: from.String(),
+			To:   to.String(),
+			Gas:  c.gas.gasLimitHex(),
+			Data: hexutil.Encode(data),
+		},
+		blockNumberHex,
+		override,
+	)
+	if err != nil {
+		if jsonrpc.IsExecutionReverted(err) {
+			return false, nil, nil
+		}
+		return false, nil, fmt.Errorf("could not eth_call: %w", err)
+	}
+	output, err := hexutil.Decode(*result)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, output, nil
+}
+
+// transferSucceeds returns true if the scenario's transfer succeeds on top of override.
+func (c *StorageTraceClassifier) transferSucceeds(
+	scenario *jsonrpc.TransferScenario, blockNumberHex string, override jsonrpc.StateOverride,
+) (bool, error) {
+	transferData, err := packTransferData(scenario)
+	if err != nil {
+		return false, err
+	}
+	success, output, err := c.callSucceeds(scenario.MsgSender, scenario.Token, transferData, blockNumberHex, override)
+	if err != nil {
+		return false, err
+	}
+	return success && isTransferSuccess(output), nil
+}
+
+// simulateStateDiff traces a call on top of override with the builtin prestateTracer in diffMode and returns its post
+// state as a state override.
+func (c *StorageTraceClassifier) simulateStateDiff(
+	from, to common.Address, data []byte, blockNumberHex string, override jsonrpc.StateOverride,
+) (jsonrpc.StateOverride, error) {
+	traceResult := new(jsonrpc.PrestateTracerResult)
+	err := jsonrpc.DebugTraceCall(
+		c.client,
+		&jsonrpc.DebugTraceCallCalldataParam{
+			From: from.String(),
+			To:   to.String(),
+			Gas:  c.gas.gasLimitHex(),
+			Data: hexutil.Encode(data),
+		},
+		blockNumberHex,
+		&jsonrpc.DebugTraceCallTracerConfigParam{
+			Tracer:         "prestateTracer",
+			TracerConfig:   jsonrpc.TransferTracerConfigEncoded,
+			StateOverrides: override,
+		},
+		traceResult,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not debug_traceCall: %w", err)
+	}
+	return jsonrpc.PostStateOverride(traceResult), nil
+}
+
+// ProxyImplementation returns the implementation of an EIP-1967 proxy, the zero address if token is not a proxy.
+func ProxyImplementation(client jsonrpc.Client, token common.Address, blockNumberHex string) (common.Address, error) {
+	implementationSlot, err := jsonrpc.GetStorageAt(client, token, eip1967ImplementationSlot, blockNumberHex)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("could not get storage: %w", err)
+	}
+	return common.BytesToAddress(implementationSlot.Bytes()), nil
+}
+
+// tokenSelectors returns the selectors found in the token's bytecode, and in its implementation's bytecode
+// if the token is an EIP-1967 proxy.
+func (c *StorageTraceClassifier) tokenSelectors(token common.Address, blockNumberHex string) (map[string]bool, common.Address, error) {
+	code, err := jsonrpc.GetCode(c.client, token, blockNumberHex)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("could not get code: %w", err)
+	}
+	if len(code) == 0 {
+		return nil, common.Address{}, errors.New("token has no code")
+	}
+	selectors := codeSelectors(code)
+
+	implementation, err := ProxyImplementation(c.client, token, blockNumberHex)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	if implementation == (common.Address{}) {
+		return selectors, implementation, nil
+	}
+	implementationCode, err := jsonrpc.GetCode(c.client, implementation, blockNumberHex)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("could not get implementation code: %w", err)
+	}
+	for selector := range codeSelectors(implementationCode) {
+		selectors[selector] = true
+	}
+	return selectors, implementation, nil
+}
+
+// tokenOwner returns the result of owner(), false if the token has no owner() method.
+func (c *StorageTraceClassifier) tokenOwner(token common.Address, blockNumberHex string) (common.Address, bool, error) {
+	data, err := packCall("owner()")
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	success, output, err := c.callSucceeds(common.Address{}, token, data, blockNumberHex, nil)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	if !success || len(output) != common.HashLength {
+		return common.Address{}, false, nil
+	}
+	return common.BytesToAddress(output), true, nil
+}
+
+// confirmCapability simulates the owner calling the capability's method.
+func (c *StorageTraceClassifier) confirmCapability(
+	capability *OwnerCapability, token, owner common.Address, scenario *jsonrpc.TransferScenario, blockNumberHex string,
+) error {
+	var (
+		target = owner
+		value  = big.NewInt(1)
+	)
+	switch capability.Kind {
+	case CapabilityBlacklist, CapabilityPause:
+		if scenario == nil {
+			capability.Detail = "no transfer scenario to confirm with"
+			return nil
+		}
+		target = scenario.MsgSender
+		if scenario.IsTransferFrom {
+			target = scenario.From
+		}
+		// the transfer has to succeed before the owner's call for its revert after to be caused by it
+		success, err := c.transferSucceeds(scenario, blockNumberHex, nil)
+		if err != nil {
+			return err
+		}
+		if !success {
+			capability.Detail = fmt.Sprintf("transfer from %s already reverts before the owner calls %s", target, capability.Method)
+			return nil
+		}
+	case CapabilityUnboundedFee:
+		value = unboundedFeeTestValue
+	}
+
+	args, err := capabilityCallArgs(capability.Method, target, value)
+	if err != nil {
+		return err
+	}
+	data, err := packCall(capability.Method, args...)
+	if err != nil {
+		return err
+	}
+	success, _, err := c.callSucceeds(owner, token, data, blockNumberHex, nil)
+	if err != nil {
+		return err
+	}
+	if !success {
+		capability.Detail = fmt.Sprintf("%s reverted when called by the owner", capability.Method)
+		return nil
+	}
+
+	switch capability.Kind {
+	case CapabilityMint:
+		capability.Confirmed = true
+		capability.Detail = "owner can mint"
+	case CapabilityUnboundedFee:
+		capability.Confirmed = true
+		capability.Detail = fmt.Sprintf("owner can set fee to %s", value)
+	case CapabilityBlacklist, CapabilityPause:
+		override, err := c.simulateStateDiff(owner, token, data, blockNumberHex, nil)
+		if err != nil {
+			return err
+		}
+		success, err := c.transferSucceeds(scenario, blockNumberHex, override)
+		if err != nil {
+			return err
+		}
+		if !success {
+			capability.Confirmed = true
+			capability.Detail = fmt.Sprintf("transfer from %s reverts after the owner calls %s", target, capability.Method)
+		} else {
+			capability.Detail = fmt.Sprintf("transfer from %s still succeeds after the owner calls %s", target, capability.Method)
+		}
+	}
+	return nil
+}
+
+// AssessRisk lists the owner-controlled capabilities of a token found in its bytecode (or its EIP-1967 implementation's)
+// and confirms each of them by simulating the owner using it.
+// scenario is a transfer known to succeed, it is used to confirm that blacklisting its sender or pausing the token makes
+// it revert. It is optional, without it these capabilities are reported unconfirmed.
+func (c *StorageTraceClassifier) AssessRisk(token common.Address, scenario *jsonrpc.TransferScenario) (*RiskReport, error) {
+	blockScenario := scenario
+	if blockScenario == nil {
+		blockScenario = &jsonrpc.TransferScenario{Token: token}
+	}
+	_, blockNumberHex, err := c.resolveBlockNumber(blockScenario)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors, implementation, err := c.tokenSelectors(token, blockNumberHex)
+	if err != nil {
+		return nil, err
+	}
+
+	report := new(RiskReport)
+	if implementation != (common.Address{}) {
+		report.Capabilities = append(report.Capabilities, OwnerCapability{
+			Kind:      CapabilityUpgradeable,
+			Confirmed: true,
+			Detail:    fmt.Sprintf("EIP-1967 proxy to %s", implementation),
+		})
+	}
+
+	owner, hasOwner, err := c.tokenOwner(token, blockNumberHex)
+	if err != nil {
+		return nil, err
+	}
+	report.Owner = owner
+	report.OwnershipRenounced = hasOwner && (owner == common.Address{} || owner == deadAddress)
+
+	for _, kind := range []CapabilityKind{CapabilityBlacklist, CapabilityPause, CapabilityMint, CapabilityUnboundedFee} {
+		for _, signature := range capabilityMethodSignatures[kind] {
+			if !selectors[getMethodHash(signature)] {
+				continue
+			}
+			capability := OwnerCapability{
+				Kind:   kind,
+				Method: signature,
+			}
+			switch {
+			case !hasOwner:
+				capability.Detail = "no owner() to simulate from"
+			case report.OwnershipRenounced:
+				capability.Detail = "ownership renounced"
+			default:
+				if err := c.confirmCapability(&capability, token, owner, scenario, blockNumberHex); err != nil {
+					return nil, fmt.Errorf("could not confirm %s: %w", signature, err)
+				}
+			}
+			report.Capabilities = append(report.Capabilities, capability)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,127 @@
+package classifier
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+func Test_packCall(t *testing.T) {
+	to := common.HexToAddress("0x49003cc3b1d8835c3b4aa5a581a6be0b0843e91d")
+	want, err := abis.ERC20.Pack("transfer", to, big.NewInt(1000))
+	require.NoError(t, err)
+
+	args, err := capabilityCallArgs("transfer(address,uint256)", to, big.NewInt(1000))
+	require.NoError(t, err)
+	got, err := packCall("transfer(address,uint256)", args...)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = packCall("pause()")
+	require.NoError(t, err)
+	assert.Equal(t, getMethodHash("pause()"), hexutil.Encode(got))
+}
+
+func Test_codeSelectors(t *testing.T) {
+	// PUSH4 0xa9059cbb EQ PUSH1 0x20 PUSH4 0x8456cb59
+	code := hexutil.MustDecode("0x63a9059cbb14602063" + "8456cb59")
+	selectors := codeSelectors(code)
+	assert.True(t, selectors[getMethodHash("transfer(address,uint256)")])
+	assert.True(t, selectors[getMethodHash("pause()")])
+	assert.Len(t, selectors, 2)
+}
+
+func TestAssessRisk(t *testing.T) {
+	pausedSlot := common.HexToHash("0x0a")
+	scenario := &jsonrpc.TransferScenario{
+		MsgSender:   recordedSender,
+		Token:       recordedToken,
+		To:          recordedReceiver,
+		Amount:      big.NewInt(1000),
+		BlockNumber: "0x1036640",
+	}
+	diffs := map[string]*jsonrpc.PrestateTracerResult{
+		"pause()": {
+			Pre:  map[common.Address]jsonrpc.PrestateAccount{recordedToken: {Storage: map[common.Hash]common.Hash{pausedSlot: {}}}},
+			Post: map[common.Address]jsonrpc.PrestateAccount{recordedToken: {Storage: map[common.Hash]common.Hash{pausedSlot: common.HexToHash("0x01")}}},
+		},
+	}
+	pausable := func(_ []byte, override jsonrpc.StateOverride) ([]byte, error) {
+		if paused, ok := override[recordedToken].StateDiff[pausedSlot]; ok && common.HexToHash(paused) != (common.Hash{}) {
+			return nil, revertError{}
+		}
+		return returnsTrue(nil, nil)
+	}
+	reverts := func([]byte, jsonrpc.StateOverride) ([]byte, error) {
+		return nil, revertError{}
+	}
+
+	tests := []struct {
+		name     string
+		scenario *jsonrpc.TransferScenario
+		transfer func([]byte, jsonrpc.StateOverride) ([]byte, error)
+		want     []OwnerCapability
+	}{
+		{
+			name:     "pause blocks transfers",
+			scenario: scenario,
+			transfer: pausable,
+			want: []OwnerCapability{
+				{Kind: CapabilityPause, Method: "pause()", Confirmed: true, Detail: fmt.Sprintf("transfer from %s reverts after the owner calls pause()", recordedSender)},
+				{Kind: CapabilityMint, Method: "mint(address,uint256)", Confirmed: true, Detail: "owner can mint"},
+			},
+		},
+		{
+			name:     "pause doesn't block transfers",
+			scenario: scenario,
+			transfer: returnsTrue,
+			want: []OwnerCapability{
+				{Kind: CapabilityPause, Method: "pause()", Detail: fmt.Sprintf("transfer from %s still succeeds after the owner calls pause()", recordedSender)},
+				{Kind: CapabilityMint, Method: "mint(address,uint256)", Confirmed: true, Detail: "owner can mint"},
+			},
+		},
+		{
+			name:     "transfer reverts before pausing",
+			scenario: scenario,
+			transfer: reverts,
+			want: []OwnerCapability{
+				{Kind: CapabilityPause, Method: "pause()", Detail: fmt.Sprintf("transfer from %s already reverts before the owner calls pause()", recordedSender)},
+				{Kind: CapabilityMint, Method: "mint(address,uint256)", Confirmed: true, Detail: "owner can mint"},
+			},
+		},
+		{
+			name:     "no scenario",
+			transfer: pausable,
+			want: []OwnerCapability{
+				{Kind: CapabilityPause, Method: "pause()", Detail: "no transfer scenario to confirm with"},
+				{Kind: CapabilityMint, Method: "mint(address,uint256)", Confirmed: true, Detail: "owner can mint"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClassifier(&ownedTokenClient{
+				diffs: diffs,
+				methods: map[string]func([]byte, jsonrpc.StateOverride) ([]byte, error){
+					"transfer(address,uint256)": tt.transfer,
+					"owner()":                   returnsAddress(recordedOwner),
+					"pause()":                   returnsTrue,
+					"mint(address,uint256)":     returnsTrue,
+				},
+			}, nil)
+			report, err := c.AssessRisk(recordedToken, tt.scenario)
+			require.NoError(t, err)
+			assert.Equal(t, recordedOwner, report.Owner)
+			assert.False(t, report.OwnershipRenounced)
+			assert.Equal(t, tt.want, report.Capabilities)
+		})
+	}
+}
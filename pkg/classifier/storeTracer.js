@@ -1,24 +1,31 @@
 var tracer = {
-  sloads: [],
+  ops: [],
   step: function (log, db) {
     let op = log.op.toNumber()
     if (op == 0x54 /*SLOAD*/ || op == 0x55 /*SSTORE*/ ) {
       let addr = log.contract.getAddress()
       let slot = toWord(log.stack.peek(0).toString(16))
-      this.sloads.push({
+      let oldValue = toHex(db.getState(addr, slot))
+      let newValue = oldValue
+      if (op == 0x55) {
+        newValue = toHex(toWord(log.stack.peek(1).toString(16)))
+      }
+      this.ops.push({
         op: op,
         addr: toHex(addr),
         slot: toHex(slot),
-        value: toHex(db.getState(addr, slot))
+        oldValue: oldValue,
+        newValue: newValue,
+        depth: log.getDepth()
       })
     }
 
   },
   result: function (ctx) {
     return {
-      sloads: this.sloads,
+      ops: this.ops,
       output: toHex(ctx.output)
     }
   },
   fault: function () { }
-}
\ No newline at end of file
+}
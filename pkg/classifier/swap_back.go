@@ -0,0 +1,126 @@
+package classifier
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// addLiquidityMethods are the hex encoded method ids of the router calls adding liquidity, as opposed to swapping.
+var addLiquidityMethods = map[string]bool{
+	getMethodHash("addLiquidityETH(address,uint256,uint256,uint256,address,uint256)"):              true,
+	getMethodHash("addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)"): true,
+}
+
+// SwapBack is a swap-back or auto-liquidity call made by a token inside one of its transfers.
+type SwapBack struct {
+	TxHash common.Hash
+	Call   ExternalCall
+	// AddLiquidity is true if the call adds liquidity to a pool rather than swapping
+	AddLiquidity bool
+	// AmountIn is the amount of the token the token contract sold or added to the pool,
+	// nil if it could not be decoded from the call tree
+	AmountIn *big.Int
+}
+
+// SwapBackReport stores the swap-backs found in the historical transfers of a token.
+type SwapBackReport struct {
+	Token                    common.Address
+	NumTransfers             int
+	NumTransfersWithSwapBack int
+	SwapBacks                []SwapBack
+	// Threshold is the estimated token balance of the token contract that triggers a swap-back.
+	// Tax tokens swap once their accumulated fees reach the threshold and most of them sell exactly the threshold amount,
+	// so it is estimated as the smallest amount sold in a swap. nil if no swap amount was decoded.
+	Threshold *big.Int
+	// LiquidityThreshold is the smallest amount added to a pool by an auto-liquidity call, kept apart from Threshold
+	// since auto-liquidity tokens only add a share of the swapped amount. nil if no addLiquidity amount was decoded.
+	LiquidityThreshold *big.Int
+}
+
+// minPositive returns amount if it is positive and smaller than min, min otherwise.
+func minPositive(min, amount *big.Int) *big.Int {
+	if amount == nil || amount.Sign() <= 0 || min != nil && min.Cmp(amount) <= 0 {
+		return min
+	}
+	return amount
+}
+
+// HasSwapBack returns true if any transfer of the token changed pool reserves.
+func (r *SwapBackReport) HasSwapBack() bool {
+	return r.NumTransfersWithSwapBack > 0
+}
+
+// swapBackAmountIn sums the token amounts pulled from the token contract itself inside a swap-back call.
+// Both UniswapV2 style routers and UniswapV3 callbacks pull the sold tokens with transferFrom(token, pool, amount).
+func swapBackAmountIn(swapBack *jsonrpc.CallFrame, token common.Address) *big.Int {
+	var (
+		transferFrom = abis.ERC20.Methods["transferFrom"]
+		amountIn     *big.Int
+	)
+	walkCallFrames(swapBack, 0, func(call *jsonrpc.CallFrame, _ int) {
+		if call.To == nil || *call.To != token || !bytes.HasPrefix(call.Input, transferFrom.ID) {
+			return
+		}
+		params, err := transferFrom.Inputs.Unpack(call.Input[4:])
+		if err != nil {
+			logger.Debugw("could not unpack transferFrom() method params", "error", err)
+			return
+		}
+		from, ok := params[0].(common.Address)
+		if !ok || from != token {
+			return
+		}
+		amount, ok := params[2].(*big.Int)
+		if !ok {
+			return
+		}
+		if amountIn == nil {
+			amountIn = new(big.Int)
+		}
+		amountIn.Add(amountIn, amount)
+	})
+	return amountIn
+}
+
+// DetectSwapBacks looks for router or pair swap/addLiquidity calls made by the token inside its transfers,
+// given the callTracer results of historical txs.
+func DetectSwapBacks(token common.Address, callFrames map[common.Hash]*jsonrpc.CallFrame) *SwapBackReport {
+	report := &SwapBackReport{
+		Token: token,
+	}
+	for txHash, root := range callFrames {
+		for _, transferCall := range FindTransferCalls(root, token) {
+			report.NumTransfers++
+
+			var found bool
+			for _, call := range ExternalCallsDuringTransfer(transferCall, token) {
+				if call.Kind != ExternalCallSwapBack {
+					continue
+				}
+				found = true
+
+				swapBack := SwapBack{
+					TxHash:       txHash,
+					Call:         call,
+					AddLiquidity: addLiquidityMethods[call.Selector],
+					AmountIn:     swapBackAmountIn(call.frame, token),
+				}
+				report.SwapBacks = append(report.SwapBacks, swapBack)
+				if swapBack.AddLiquidity {
+					report.LiquidityThreshold = minPositive(report.LiquidityThreshold, swapBack.AmountIn)
+				} else {
+					report.Threshold = minPositive(report.Threshold, swapBack.AmountIn)
+				}
+			}
+			if found {
+				report.NumTransfersWithSwapBack++
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,96 @@
+package classifier
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+func TestDetectSwapBacks(t *testing.T) {
+	token := common.HexToAddress("0x9b0e1c344141fb361b842d397df07174e1cdb988")
+	ether := func(n int64, exp int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(n), new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil))
+	}
+	tests := []struct {
+		name                   string
+		traces                 []string
+		wantTransfers          int
+		wantWithSwapBack       int
+		wantAddLiquidity       []bool
+		wantThreshold          *big.Int
+		wantLiquidityThreshold *big.Int
+	}{
+		{
+			name:             "swap-back",
+			traces:           []string{"swap_back_trace.json"},
+			wantTransfers:    1,
+			wantWithSwapBack: 1,
+			wantAddLiquidity: []bool{false},
+			wantThreshold:    ether(250, 18),
+		},
+		{
+			// the addLiquidity amount is smaller than the swapped half but is not the threshold
+			name:                   "swap and liquify",
+			traces:                 []string{"swap_and_liquify_trace.json"},
+			wantTransfers:          1,
+			wantWithSwapBack:       1,
+			wantAddLiquidity:       []bool{false, true},
+			wantThreshold:          ether(100, 18),
+			wantLiquidityThreshold: ether(987, 17),
+		},
+		{
+			name:             "uniswap v3 swap-back",
+			traces:           []string{"v3_swap_back_trace.json"},
+			wantTransfers:    1,
+			wantWithSwapBack: 1,
+			wantAddLiquidity: []bool{false},
+			wantThreshold:    ether(120, 18),
+		},
+		{
+			name:          "no swap-back",
+			traces:        []string{"no_swap_back_trace.json"},
+			wantTransfers: 1,
+		},
+		{
+			name:                   "many txs",
+			traces:                 []string{"swap_back_trace.json", "swap_and_liquify_trace.json", "v3_swap_back_trace.json", "no_swap_back_trace.json"},
+			wantTransfers:          4,
+			wantWithSwapBack:       3,
+			wantThreshold:          ether(100, 18),
+			wantLiquidityThreshold: ether(987, 17),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callFrames := make(map[common.Hash]*jsonrpc.CallFrame)
+			for i, name := range tt.traces {
+				encoded, err := os.ReadFile("testdata/" + name)
+				require.NoError(t, err)
+				var frame jsonrpc.CallFrame
+				require.NoError(t, json.Unmarshal(encoded, &frame))
+				callFrames[common.BigToHash(big.NewInt(int64(i+1)))] = &frame
+			}
+
+			report := DetectSwapBacks(token, callFrames)
+			assert.Equal(t, tt.wantWithSwapBack > 0, report.HasSwapBack())
+			assert.Equal(t, tt.wantTransfers, report.NumTransfers)
+			assert.Equal(t, tt.wantWithSwapBack, report.NumTransfersWithSwapBack)
+			assert.Equal(t, tt.wantThreshold, report.Threshold)
+			assert.Equal(t, tt.wantLiquidityThreshold, report.LiquidityThreshold)
+			if tt.wantAddLiquidity != nil {
+				addLiquidity := make([]bool, 0, len(report.SwapBacks))
+				for _, swapBack := range report.SwapBacks {
+					addLiquidity = append(addLiquidity, swapBack.AddLiquidity)
+				}
+				assert.Equal(t, tt.wantAddLiquidity, addLiquidity)
+			}
+		})
+	}
+}
@@ -2,32 +2,55 @@ package classifier
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/vm"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
 	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
 )
 
 type StorageTraceClassifier struct {
-	probe     *Probe
-	client    *rpc.Client
-	ethClient *ethclient.Client
+	probe  *Probe
+	client jsonrpc.Client
+	gas    GasConfig
 }
 
-func NewClassifier(rpcClient *rpc.Client, erc20balanceSlotProbe *Probe) *StorageTraceClassifier {
+var _ Classifier = (*StorageTraceClassifier)(nil)
+
+// NewClassifier rpcClient is either a *rpc.Client or a *jsonrpc.Pool to use several endpoints.
+// If erc20balanceSlotProbe is nil, balance slots are probed with rpcClient.
+func NewClassifier(rpcClient jsonrpc.Client, erc20balanceSlotProbe *Probe) *StorageTraceClassifier {
+	return NewClassifierWithGas(rpcClient, erc20balanceSlotProbe, DefaultGasConfig)
+}
+
+// NewClassifierWithGas simulates calls with the gas handling of a chain other than Ethereum mainnet.
+func NewClassifierWithGas(rpcClient jsonrpc.Client, erc20balanceSlotProbe *Probe, gas GasConfig) *StorageTraceClassifier {
+	if erc20balanceSlotProbe == nil {
+		erc20balanceSlotProbe = NewProbeWithGas(rpcClient, gas)
+	}
 	return &StorageTraceClassifier{
-		probe:     erc20balanceSlotProbe,
-		client:    rpcClient,
-		ethClient: ethclient.NewClient(rpcClient),
+		probe:  erc20balanceSlotProbe,
+		client: rpcClient,
+		gas:    gas,
+	}
+}
+
+func (c *StorageTraceClassifier) IsErc20(contractAddress common.Address, codes []byte) bool {
+	if codes == nil {
+		code, err := jsonrpc.GetCode(c.client, contractAddress, "latest")
+		if err != nil || len(code) == 0 {
+			return false
+		}
+		codes = code
 	}
+	return IsErc20(codes)
 }
 
+// ReadSlotStorage probes the balance slots of the token contract, and of the senders and receivers of txs.
+// Addresses whose slot could not be probed are left out.
 func (c *StorageTraceClassifier) ReadSlotStorage(txs []*types.TxFromTransferEvent, contractAddr common.Address) (balanceSlot map[common.Address]common.Hash) {
 	var (
 		balanceSlotMap = make(map[common.Address]common.Hash)
@@ -43,6 +66,7 @@ func (c *StorageTraceClassifier) ReadSlotStorage(txs []*types.TxFromTransferEven
 		slot, err := c.probe.ProbeBalanceSlot(contractAddr, address)
 		if err != nil {
 			logger.Warnw("failed to probe balance slot", "address", address, "error", err)
+			delete(balanceSlotMap, address)
 			continue
 		}
 		balanceSlotMap[address] = slot
@@ -50,20 +74,19 @@ func (c *StorageTraceClassifier) ReadSlotStorage(txs []*types.TxFromTransferEven
 	return balanceSlotMap
 }
 
+// TraceCallAndGetBalance traces each tx with the prestateTracer in diffMode and reads the token balances of its sender,
+// its receiver and the token contract before and after the tx from the diff of their balance slots.
+// The txs are not re-simulated, the node replays them on the state they were mined on.
+// The contract's balances are left nil if its balance slot could not be probed.
 func (c *StorageTraceClassifier) TraceCallAndGetBalance(contractAddress common.Address, txs []*types.TxFromTransferEvent, balanceSlotMap map[common.Address]common.Hash) (map[common.Hash]*types.StateChanges, error) {
 	var (
 		results = make(map[common.Hash]*types.StateChanges, len(txs))
-		tracer  = jsonrpc.DebugTraceCallTracerConfigParam{
-			// we are using the builtin prestateTracer in go-ethereum
-			// https://github.com/ethereum/go-ethereum/blob/master/eth/tracers/native/prestate.go
-			Tracer:         "prestateTracer",
-			TracerConfig:   jsonrpc.TransferTracerConfigEncoded,
-			StateOverrides: nil,
-		}
 	)
-	contract, avail := balanceSlotMap[contractAddress]
-	if !avail {
-		return nil, errors.New("no contract storage slot available")
+	var contract *common.Hash
+	if slot, avail := balanceSlotMap[contractAddress]; avail {
+		contract = &slot
+	} else {
+		logger.Warnw("no contract storage slot available, the fee receiver is not checked", "contract", contractAddress)
 	}
 	for _, tx := range txs {
 		from, avail := balanceSlotMap[tx.From]
@@ -75,15 +98,19 @@ func (c *StorageTraceClassifier) TraceCallAndGetBalance(contractAddress common.A
 			continue
 		}
 
-		var opsResult tracingResult
+		diff := new(jsonrpc.PrestateTracerResult)
 		err := jsonrpc.DebugTraceTransaction(
 			c.client,
 			tx.TxHash,
-			&tracer,
-			opsResult,
+			&jsonrpc.DebugTraceCallTracerConfigParam{
+				Tracer:       "prestateTracer",
+				TracerConfig: jsonrpc.TransferTracerConfigEncoded,
+			},
+			diff,
 		)
 		if err != nil {
-			logger.Warnw("failed to to call json rpc ")
+			logger.Warnw("could not debug_traceTransaction", "txHash", tx.TxHash, "error", err)
+			continue
 		}
 		sd := &types.StateChanges{
 			Contract: &types.BalanceDiff{
@@ -102,8 +129,8 @@ func (c *StorageTraceClassifier) TraceCallAndGetBalance(contractAddress common.A
 				After:   nil,
 			},
 		}
-		if eErr := extractBalance(opsResult, sd, from, to, contract); eErr != nil {
-			logger.Warnw("cannot extract balance", "error", eErr)
+		if eErr := extractBalance(diff, contractAddress, sd, from, to, contract); eErr != nil {
+			logger.Warnw("cannot extract balance", "txHash", tx.TxHash, "error", eErr)
 			continue
 		}
 
@@ -112,61 +139,174 @@ func (c *StorageTraceClassifier) TraceCallAndGetBalance(contractAddress common.A
 	return results, nil
 }
 
-func extractBalance(opsResult tracingResult, sd *types.StateChanges, from, to, contract common.Hash) error {
-	for _, op := range opsResult.ops {
-		decoded, err := hexutil.Decode(op.Value)
-		if err != nil {
-			return err
-		}
-		if op.Op == vm.SLOAD {
-			switch op.Address {
-			case from.String():
-				if sd.From.Before != nil {
-					logger.Warnw("sd.From.Before is already set", "current value", sd.From.Before.String())
-				}
-				sd.From.Before = big.NewInt(0).SetBytes(decoded)
-			case to.String():
-				if sd.To.Before != nil {
-					logger.Warnw("sd.To.Before is already set", "current value", sd.To.Before.String())
-				}
-				sd.To.Before = big.NewInt(0).SetBytes(decoded)
-			case contract.String():
-				if sd.Contract.Before != nil {
-					logger.Warnw("sd.Contract.Before is already set", "current value", sd.Contract.Before.String())
-				}
-				sd.Contract.Before = big.NewInt(0).SetBytes(decoded)
+// slotBalance is a balance read from the diff of its slot, required if the slot must be in the diff.
+type slotBalance struct {
+	diff     *types.BalanceDiff
+	slot     common.Hash
+	required bool
+}
+
+// extractBalance sets the balances before and after a transfer from the diff of the balance slots of the token.
+// Unchanged slots are not in the diff: the contract's balance is then left nil, the sender's and receiver's must change.
+// The contract's balance is also left nil if contract is nil.
+// Zero slots are left out of the diff: a slot set by the tx is only in the post state, a slot cleared only in the pre.
+func extractBalance(diff *jsonrpc.PrestateTracerResult, token common.Address, sd *types.StateChanges, from, to common.Hash, contract *common.Hash) error {
+	var (
+		pre  = diff.Pre[token].Storage
+		post = diff.Post[token].Storage
+	)
+	balances := []slotBalance{
+		{sd.From, from, true},
+		{sd.To, to, true},
+	}
+	if contract != nil {
+		balances = append(balances, slotBalance{sd.Contract, *contract, false})
+	}
+	for _, balance := range balances {
+		before, inPre := pre[balance.slot]
+		after, inPost := post[balance.slot]
+		if !inPre && !inPost {
+			if balance.required {
+				return fmt.Errorf("balance slot %s of %s is not changed", balance.slot, balance.diff.Address)
 			}
+			continue
 		}
-		if op.Op == vm.SLOAD {
-			switch op.Address {
-			case from.String():
-				if sd.From.After != nil {
-					logger.Warnw("sd.From.After is already set", "current value", sd.From.After.String())
-				}
-				sd.From.After = big.NewInt(0).SetBytes(decoded)
-			case to.String():
-				if sd.To.After != nil {
-					logger.Warnw("sd.To.After is already set", "current value", sd.To.After.String())
-				}
-				sd.To.After = big.NewInt(0).SetBytes(decoded)
-			case contract.String():
-				if sd.Contract.After != nil {
-					logger.Warnw("sd.Contract.After is already set", "current value", sd.Contract.After.String())
-				}
-				sd.Contract.After = big.NewInt(0).SetBytes(decoded)
-			}
+		balance.diff.Before = before.Big()
+		balance.diff.After = after.Big()
+	}
+	return nil
+}
+
+// balanceChange returns After - Before, zero if the balance did not change.
+func balanceChange(diff *types.BalanceDiff) *big.Int {
+	if diff.Before == nil || diff.After == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Sub(diff.After, diff.Before)
+}
+
+// transferFee returns the amount sent minus the amount received in a transfer.
+func transferFee(sd *types.StateChanges) (sent, fee *big.Int) {
+	sent = new(big.Int).Neg(balanceChange(sd.From))
+	return sent, new(big.Int).Sub(sent, balanceChange(sd.To))
+}
+
+// mainTransfers keeps the transfer with the largest amount of each tx, the others may be the transfers of the fee.
+// Mints and burns are left out, their sender or receiver has no balance.
+func mainTransfers(txs []*types.TxFromTransferEvent) []*types.TxFromTransferEvent {
+	var (
+		byHash = make(map[common.Hash]*types.TxFromTransferEvent, len(txs))
+		hashes []common.Hash
+	)
+	for _, tx := range txs {
+		if tx.From == (common.Address{}) || tx.To == (common.Address{}) || tx.From == tx.To || tx.Amount == nil {
+			continue
+		}
+		main, ok := byHash[tx.TxHash]
+		if !ok {
+			hashes = append(hashes, tx.TxHash)
+		}
+		if !ok || main.Amount.Cmp(tx.Amount) < 0 {
+			byHash[tx.TxHash] = tx
 		}
 	}
+	transfers := make([]*types.TxFromTransferEvent, 0, len(hashes))
+	for _, hash := range hashes {
+		transfers = append(transfers, byHash[hash])
+	}
+	return transfers
+}
 
-	return nil
+// transfersFromLogs decodes the Transfer events of a token.
+func transfersFromLogs(token common.Address, logs []ethtypes.Log) []*types.TxFromTransferEvent {
+	txs := make([]*types.TxFromTransferEvent, 0, len(logs))
+	for i := range logs {
+		if logs[i].Address != token || !IsTransferEvent(&logs[i]) {
+			continue
+		}
+		tx, err := DecodeTransferEvent(&logs[i])
+		if err != nil {
+			logger.Errorw("could not decode event log", "error", err, "tx", logs[i].TxHash)
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs
 }
 
-func (c *StorageTraceClassifier) IsFeeOnTransfer(ercContract common.Address) (bool, error) {
+// IsFeeOnTransfer implement token classifier for StorageTraceClassifier from the balance changes of the Transfer
+// events' txs. The logs are not fetched, they must be given.
+func (c *StorageTraceClassifier) IsFeeOnTransfer(ercContract common.Address, logs []ethtypes.Log) (FeeOnTransferResult, error) {
+	if len(logs) == 0 {
+		return FeeOnTransferResult{}, errors.New("there is no logs for processing")
+	}
+	return c.IsFeeOnTransferFromTxs(ercContract, transfersFromLogs(ercContract, logs))
+}
 
-	// filter for transfer tx
+// IsFeeOnTransferFromTxs probes the balance slots of the senders and receivers of historical transfers, reads their
+// balances before and after each tx and classifies the token as fee on transfer if the receivers got less than what
+// the senders sent.
+func (c *StorageTraceClassifier) IsFeeOnTransferFromTxs(ercContract common.Address, txs []*types.TxFromTransferEvent) (FeeOnTransferResult, error) {
+	txs = mainTransfers(txs)
+	if len(txs) == 0 {
+		return FeeOnTransferResult{}, errors.New("there is no transfer for processing")
+	}
+	balanceSlotMap := c.ReadSlotStorage(txs, ercContract)
+	stateChanges, err := c.TraceCallAndGetBalance(ercContract, txs, balanceSlotMap)
+	if err != nil {
+		return FeeOnTransferResult{}, fmt.Errorf("could not get balances: %w", err)
+	}
+	changes := make([]*types.StateChanges, 0, len(stateChanges))
+	for _, tx := range txs {
+		if sd, ok := stateChanges[tx.TxHash]; ok {
+			changes = append(changes, sd)
+		}
+	}
+	return decideFeeOnTransfer(changes)
+}
 
-	// find slotstorage from & ti
+// decideFeeOnTransfer classifies a token from the balance changes of its transfers: it is fee on transfer if a receiver
+// got less than what its sender sent. The fee formular is regressed from the amounts sent, and the token contract is
+// the fee receiver if its balance grows with the fees.
+func decideFeeOnTransfer(changes []*types.StateChanges) (FeeOnTransferResult, error) {
+	if len(changes) == 0 {
+		return FeeOnTransferResult{}, ErrCouldNotDecide
+	}
+	var (
+		numLess          int
+		fees             = make([]float64, 0, len(changes))
+		sents            = make([]float64, 0, len(changes))
+		contractReceived = true
+	)
+	for _, sd := range changes {
+		sent, fee := transferFee(sd)
+		if fee.Sign() <= 0 {
+			continue
+		}
+		numLess++
+		if balanceChange(sd.Contract).Cmp(fee) != 0 {
+			contractReceived = false
+		}
+		f, _ := fee.Float64()
+		s, _ := sent.Float64()
+		fees = append(fees, f)
+		sents = append(sents, s)
+	}
+	logger.Infow("finished computing fees", "len(txs)", len(changes), "txs with fee", numLess)
+	if numLess == 0 {
+		return FeeOnTransferResult{}, nil
+	}
 
-	// trace call get balance before after
-	return false, nil
+	result := FeeOnTransferResult{IsFeeOnTransfer: true}
+	if contractReceived {
+		result.FeeReceiver = changes[0].Contract.Address
+	}
+	r, err := regress(fees, sents)
+	if err != nil {
+		logger.Infow("cannot regress from data", "error", err)
+		return result, nil
+	}
+	result.Coefficients = r.GetCoeffs()
+	result.Formular = r.Formula
+	return result, nil
 }
@@ -0,0 +1,290 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/tracing"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
+)
+
+// the recorded traces are of BLZ (0x5732046a883704404f284ce41ffadd5b007fd668), whose balances mapping is at slot 4
+var (
+	recordedToken        = common.HexToAddress("0x5732046a883704404f284ce41ffadd5b007fd668")
+	recordedSender       = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	recordedReceiver     = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	recordedSenderSlot   = common.HexToHash("0xb7343549a9536f391671c3050c66deb1af0f3ede9688eb847b30447e55d6285a")
+	recordedReceiverSlot = common.HexToHash("0xce53ef3e8d9aa7e18fc44d8378145f2cb4b18a8dcd1dde867638f7071daae985")
+)
+
+func readRecordedTrace(t *testing.T, name string) *tracing.StorageTrace {
+	encoded, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	trace := new(tracing.StorageTrace)
+	require.NoError(t, json.Unmarshal(encoded, trace))
+	return trace
+}
+
+// recordedTraceClient only supports the JS tracer, it returns a recorded trace. eth_calls return the value of
+// balanceSlot, overridden or not.
+type recordedTraceClient struct {
+	trace       json.RawMessage
+	balanceSlot common.Hash
+	balance     common.Hash
+}
+
+func (c *recordedTraceClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if method != "debug_traceCall" {
+		return errors.New("not supported")
+	}
+	tracer, ok := args[2].(*jsonrpc.DebugTraceCallTracerConfigParam)
+	if !ok || tracer.Tracer != string(storageTracerMinified) {
+		return errors.New("not supported")
+	}
+	return json.Unmarshal(c.trace, result)
+}
+
+func (c *recordedTraceClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for i := range b {
+		value := c.balance
+		if override, ok := b[i].Args[2].(jsonrpc.StateOverride)[recordedToken].StateDiff[c.balanceSlot]; ok {
+			value = common.HexToHash(override)
+		}
+		*b[i].Result.(*string) = value.Hex()
+	}
+	return nil
+}
+
+func TestProbeBalanceSlot(t *testing.T) {
+	encoded, err := os.ReadFile("testdata/balance_of_trace.json")
+	require.NoError(t, err)
+	probe := NewProbe(&recordedTraceClient{
+		trace:       encoded,
+		balanceSlot: recordedSenderSlot,
+		balance:     common.BigToHash(big.NewInt(1000000)),
+	})
+
+	slot, err := probe.ProbeBalanceSlot(recordedToken, recordedSender)
+	require.NoError(t, err)
+	assert.Equal(t, recordedSenderSlot, slot)
+}
+
+func readRecordedDiff(t *testing.T, name string) *jsonrpc.PrestateTracerResult {
+	encoded, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	diff := new(jsonrpc.PrestateTracerResult)
+	require.NoError(t, json.Unmarshal(encoded, diff))
+	return diff
+}
+
+// recordedDiffClient only supports debug_traceTransaction with the prestateTracer in diffMode, it returns the recorded
+// diff of a tx.
+type recordedDiffClient struct {
+	diffs map[common.Hash]string
+}
+
+func (c *recordedDiffClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if method != "debug_traceTransaction" {
+		return errors.New("not supported")
+	}
+	tracer, ok := args[1].(*jsonrpc.DebugTraceCallTracerConfigParam)
+	if !ok || tracer.Tracer != "prestateTracer" || string(tracer.TracerConfig) != string(jsonrpc.TransferTracerConfigEncoded) {
+		return errors.New("not supported")
+	}
+	name, ok := c.diffs[args[0].(common.Hash)]
+	if !ok {
+		return errors.New("transaction not found")
+	}
+	encoded, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, result)
+}
+
+func (c *recordedDiffClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("not supported")
+}
+
+// recordedContractSlot is the balance slot of the token contract itself
+var recordedContractSlot = common.HexToHash("0x4ccad95ac445c74e6d365efcdc772e5c2b41f52e67662fbf2068a8cc5a931c1f")
+
+func TestExtractBalance(t *testing.T) {
+	newStateChanges := func() *types.StateChanges {
+		return &types.StateChanges{
+			Contract: &types.BalanceDiff{Address: recordedToken},
+			From:     &types.BalanceDiff{Address: recordedSender},
+			To:       &types.BalanceDiff{Address: recordedReceiver},
+		}
+	}
+
+	diff := readRecordedDiff(t, "transfer_prestate_diff.json")
+	sd := newStateChanges()
+	require.NoError(t, extractBalance(diff, recordedToken, sd, recordedSenderSlot, recordedReceiverSlot, &recordedContractSlot))
+	assert.Equal(t, big.NewInt(1000000), sd.From.Before)
+	assert.Equal(t, big.NewInt(999000), sd.From.After)
+	assert.Equal(t, big.NewInt(500), sd.To.Before)
+	assert.Equal(t, big.NewInt(1500), sd.To.After)
+	assert.Nil(t, sd.Contract.Before)
+	assert.Nil(t, sd.Contract.After)
+
+	// the sender's balance is cleared, its slot is only in the pre state
+	diff = readRecordedDiff(t, "fee_transfer_prestate_diff.json")
+	sd = newStateChanges()
+	require.NoError(t, extractBalance(diff, recordedToken, sd, recordedSenderSlot, recordedReceiverSlot, &recordedContractSlot))
+	assert.Equal(t, big.NewInt(1000), sd.From.Before)
+	assert.Zero(t, sd.From.After.Sign())
+	assert.Zero(t, sd.Contract.Before.Sign())
+	assert.Equal(t, big.NewInt(20), sd.Contract.After)
+
+	// the receiver and the contract had no balance, their slots are only in the post state
+	diff = readRecordedDiff(t, "first_transfer_prestate_diff.json")
+	sd = newStateChanges()
+	require.NoError(t, extractBalance(diff, recordedToken, sd, recordedSenderSlot, recordedReceiverSlot, &recordedContractSlot))
+	assert.Equal(t, big.NewInt(1000), sd.From.Before)
+	assert.Equal(t, big.NewInt(100), sd.From.After)
+	assert.Zero(t, sd.To.Before.Sign())
+	assert.Equal(t, big.NewInt(880), sd.To.After)
+	assert.Zero(t, sd.Contract.Before.Sign())
+	assert.Equal(t, big.NewInt(20), sd.Contract.After)
+	sent, fee := transferFee(sd)
+	assert.Equal(t, big.NewInt(900), sent)
+	assert.Equal(t, big.NewInt(20), fee)
+
+	// balances of another token are not read
+	assert.Error(t, extractBalance(diff, common.HexToAddress("0x1"), newStateChanges(), recordedSenderSlot, recordedReceiverSlot, &recordedContractSlot))
+}
+
+func TestTraceCallAndGetBalance(t *testing.T) {
+	var (
+		transferTx = common.HexToHash("0x01")
+		feeTx      = common.HexToHash("0x02")
+		missingTx  = common.HexToHash("0x03")
+	)
+	c := NewClassifier(&recordedDiffClient{diffs: map[common.Hash]string{
+		transferTx: "transfer_prestate_diff.json",
+		feeTx:      "fee_transfer_prestate_diff.json",
+	}}, nil)
+	txs := []*types.TxFromTransferEvent{
+		{From: recordedSender, To: recordedReceiver, TxHash: transferTx, Amount: big.NewInt(1000)},
+		{From: recordedSender, To: recordedReceiver, TxHash: feeTx, Amount: big.NewInt(980)},
+		{From: recordedSender, To: recordedReceiver, TxHash: missingTx, Amount: big.NewInt(1000)},
+	}
+	balanceSlotMap := map[common.Address]common.Hash{
+		recordedToken:    recordedContractSlot,
+		recordedSender:   recordedSenderSlot,
+		recordedReceiver: recordedReceiverSlot,
+	}
+
+	stateChanges, err := c.TraceCallAndGetBalance(recordedToken, txs, balanceSlotMap)
+	require.NoError(t, err)
+	require.Len(t, stateChanges, 2)
+
+	sent, fee := transferFee(stateChanges[transferTx])
+	assert.Equal(t, big.NewInt(1000), sent)
+	assert.Zero(t, fee.Sign())
+	sent, fee = transferFee(stateChanges[feeTx])
+	assert.Equal(t, big.NewInt(1000), sent)
+	assert.Equal(t, big.NewInt(20), fee)
+
+	result, err := decideFeeOnTransfer([]*types.StateChanges{stateChanges[transferTx], stateChanges[feeTx]})
+	require.NoError(t, err)
+	assert.True(t, result.IsFeeOnTransfer)
+	assert.Equal(t, recordedToken, result.FeeReceiver)
+
+	// without the contract's slot, the balances are still read but the fee receiver is unknown
+	delete(balanceSlotMap, recordedToken)
+	stateChanges, err = c.TraceCallAndGetBalance(recordedToken, txs, balanceSlotMap)
+	require.NoError(t, err)
+	require.Len(t, stateChanges, 2)
+	assert.Nil(t, stateChanges[feeTx].Contract.After)
+	result, err = decideFeeOnTransfer([]*types.StateChanges{stateChanges[transferTx], stateChanges[feeTx]})
+	require.NoError(t, err)
+	assert.True(t, result.IsFeeOnTransfer)
+	assert.Equal(t, common.Address{}, result.FeeReceiver)
+}
+
+func TestDecideFeeOnTransfer(t *testing.T) {
+	transfer := func(sent, received, contractReceived int64) *types.StateChanges {
+		return &types.StateChanges{
+			Contract: &types.BalanceDiff{Address: recordedToken, Before: big.NewInt(0), After: big.NewInt(contractReceived)},
+			From:     &types.BalanceDiff{Address: recordedSender, Before: big.NewInt(sent), After: big.NewInt(0)},
+			To:       &types.BalanceDiff{Address: recordedReceiver, Before: big.NewInt(0), After: big.NewInt(received)},
+		}
+	}
+	tests := []struct {
+		name         string
+		changes      []*types.StateChanges
+		want         bool
+		wantReceiver common.Address
+		wantFormular bool
+		wantCouldNot bool
+	}{
+		{name: "no transfer", wantCouldNot: true},
+		{name: "no fee", changes: []*types.StateChanges{transfer(1000, 1000, 0), transfer(500, 500, 0)}},
+		{
+			name:         "fee to the contract",
+			changes:      []*types.StateChanges{transfer(1000, 980, 20), transfer(500, 490, 10), transfer(2000, 1960, 40)},
+			want:         true,
+			wantReceiver: recordedToken,
+			wantFormular: true,
+		},
+		{
+			name:         "fee burnt",
+			changes:      []*types.StateChanges{transfer(1000, 990, 0), transfer(2000, 1980, 0), transfer(3000, 2970, 0)},
+			want:         true,
+			wantFormular: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decideFeeOnTransfer(tt.changes)
+			if tt.wantCouldNot {
+				assert.ErrorIs(t, err, ErrCouldNotDecide)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.IsFeeOnTransfer)
+			assert.Equal(t, tt.wantReceiver, got.FeeReceiver)
+			assert.Equal(t, tt.wantFormular, got.Formular != "")
+		})
+	}
+}
+
+func TestMainTransfers(t *testing.T) {
+	var (
+		tx1 = common.HexToHash("0x01")
+		tx2 = common.HexToHash("0x02")
+	)
+	txs := []*types.TxFromTransferEvent{
+		{From: recordedSender, To: recordedToken, TxHash: tx1, Amount: big.NewInt(20)},
+		{From: recordedSender, To: recordedReceiver, TxHash: tx1, Amount: big.NewInt(980)},
+		{From: common.Address{}, To: recordedReceiver, TxHash: tx2, Amount: big.NewInt(1000)},
+	}
+	assert.Equal(t, []*types.TxFromTransferEvent{txs[1]}, mainTransfers(txs))
+}
+
+func TestStorageTraceSlots(t *testing.T) {
+	trace := readRecordedTrace(t, "transfer_trace.json")
+	slots := trace.Slots(recordedToken)
+	require.Len(t, slots, 3)
+
+	// slot 8 holds the transfers enabled flag, it is only read
+	assert.Equal(t, &tracing.SlotAccess{
+		Before: common.HexToHash("0x0000000000000000000000010000000000000000000000000000000000000000"),
+		After:  common.HexToHash("0x0000000000000000000000010000000000000000000000000000000000000000"),
+		Loaded: true,
+	}, slots[common.HexToHash("0x8")])
+	assert.True(t, slots[recordedSenderSlot].Loaded)
+	assert.True(t, slots[recordedSenderSlot].Stored)
+}
@@ -3,9 +3,13 @@ package classifier
 import (
 	"bytes"
 	_ "embed"
+	"errors"
+	"fmt"
 
-	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/tdewolff/minify/v2/js"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/tracing"
 )
 
 //go:embed storeTracer.js
@@ -13,18 +17,6 @@ var storageTracer []byte
 
 var storageTracerMinified []byte
 
-type StorageTracingResult struct {
-	Op      vm.OpCode `json:"op"`
-	Address string    `json:"addr"`
-	Slot    string    `json:"slot"`
-	Value   string    `json:"value"`
-}
-
-type tracingResult struct {
-	ops    []StorageTracingResult `json:"ops"`
-	Output string                 `json:"output"`
-}
-
 func init() {
 	// we need to minify the tracer script because we can not put multipleline string in JSON value
 	minified := new(bytes.Buffer)
@@ -34,3 +26,60 @@ func init() {
 	}
 	storageTracerMinified = bytes.TrimPrefix(minified.Bytes(), []byte("var tracer="))
 }
+
+// storageTraceMethod traces the SLOAD and SSTORE instructions of a call
+type storageTraceMethod struct {
+	name  string
+	trace func(client jsonrpc.Client, calldata *jsonrpc.DebugTraceCallCalldataParam, blockNumber string, overrides jsonrpc.StateOverride) (*tracing.StorageTrace, error)
+}
+
+// storageTraceMethods are tried in order: the native tracer in-process only needs the prestateTracer from the node,
+// the struct logger is slow but available on any node with the debug namespace, and many nodes disable the JS tracer.
+var storageTraceMethods = []storageTraceMethod{
+	{"native", tracing.TraceStorageInProcess},
+	{"structLogger", tracing.TraceStorageWithStructLogger},
+	{"js", traceStorageWithJSTracer},
+}
+
+func traceStorageWithJSTracer(
+	client jsonrpc.Client,
+	calldata *jsonrpc.DebugTraceCallCalldataParam,
+	blockNumber string,
+	overrides jsonrpc.StateOverride,
+) (*tracing.StorageTrace, error) {
+	result := new(tracing.StorageTrace)
+	err := jsonrpc.DebugTraceCall(
+		client,
+		calldata,
+		blockNumber,
+		&jsonrpc.DebugTraceCallTracerConfigParam{
+			Tracer:         string(storageTracerMinified),
+			StateOverrides: overrides,
+		},
+		result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// traceStorageOps traces a call with the storage tracer to get its SLOAD and SSTORE instructions.
+func traceStorageOps(
+	client jsonrpc.Client,
+	calldata *jsonrpc.DebugTraceCallCalldataParam,
+	blockNumber string,
+	overrides jsonrpc.StateOverride,
+) (*tracing.StorageTrace, error) {
+	var errs []error
+	for _, method := range storageTraceMethods {
+		trace, err := method.trace(client, calldata, blockNumber, overrides)
+		if err != nil {
+			logger.Debugw("could not trace storage", "method", method.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", method.name, err))
+			continue
+		}
+		return trace, nil
+	}
+	return nil, errors.Join(errs...)
+}
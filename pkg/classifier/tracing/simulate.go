@@ -0,0 +1,277 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// Header is the part of a block header the EVM needs.
+type Header struct {
+	Number     *hexutil.Big   `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	Time       hexutil.Uint64 `json:"timestamp"`
+	Coinbase   common.Address `json:"miner"`
+	GasLimit   hexutil.Uint64 `json:"gasLimit"`
+	Difficulty *hexutil.Big   `json:"difficulty"`
+	MixDigest  common.Hash    `json:"mixHash"`
+	BaseFee    *hexutil.Big   `json:"baseFeePerGas"`
+	// ExcessBlobGas is nil before Cancun
+	ExcessBlobGas *hexutil.Uint64 `json:"excessBlobGas"`
+}
+
+// message is the call to simulate.
+type message struct {
+	from     common.Address
+	to       common.Address
+	input    []byte
+	gas      uint64
+	gasPrice *big.Int
+	value    *big.Int
+}
+
+// callMessage converts a debug_traceCall calldata to a message, gas defaults to the block gas limit.
+func callMessage(calldata *jsonrpc.DebugTraceCallCalldataParam, header *Header) (*message, error) {
+	var (
+		msg = &message{
+			from:     common.HexToAddress(calldata.From),
+			to:       common.HexToAddress(calldata.To),
+			gas:      uint64(header.GasLimit),
+			gasPrice: new(big.Int),
+			value:    new(big.Int),
+		}
+		err error
+	)
+	if calldata.Data != "" {
+		if msg.input, err = hexutil.Decode(calldata.Data); err != nil {
+			return nil, fmt.Errorf("could not decode calldata: %w", err)
+		}
+	}
+	if calldata.Gas != "" {
+		if msg.gas, err = hexutil.DecodeUint64(calldata.Gas); err != nil {
+			return nil, fmt.Errorf("could not decode gas: %w", err)
+		}
+	}
+	if calldata.GasPrice != "" {
+		if msg.gasPrice, err = hexutil.DecodeBig(calldata.GasPrice); err != nil {
+			return nil, fmt.Errorf("could not decode gas price: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+// SimulationResult is the result of a call simulated in-process.
+type SimulationResult struct {
+	Output  []byte
+	GasUsed uint64
+	// Err is the execution error of the call (e.g. vm.ErrExecutionReverted), the call is traced anyway
+	Err error
+}
+
+// chainConfig returns a config with every fork up to Cancun enabled, the simulation only runs on recent blocks.
+func chainConfig(chainID *big.Int) *params.ChainConfig {
+	config := *params.AllEthashProtocolChanges
+	config.ChainID = chainID
+	forkTime := uint64(0)
+	config.ShanghaiTime = &forkTime
+	config.CancunTime = &forkTime
+	return &config
+}
+
+func getHeader(client jsonrpc.Client, blockNumber string) (*Header, error) {
+	header := new(Header)
+	if err := client.CallContext(context.Background(), header, "eth_getBlockByNumber", blockNumber, false); err != nil {
+		return nil, err
+	}
+	if header.Number == nil {
+		return nil, fmt.Errorf("block %s not found", blockNumber)
+	}
+	return header, nil
+}
+
+func getChainID(client jsonrpc.Client) (*big.Int, error) {
+	var chainID hexutil.Big
+	if err := client.CallContext(context.Background(), &chainID, "eth_chainId"); err != nil {
+		return nil, fmt.Errorf("could not get chain id: %w", err)
+	}
+	return chainID.ToInt(), nil
+}
+
+// Simulate runs a call in-process with tracer attached, on top of the state of blockNumber and the overrides.
+// The state is fetched with the prestateTracer, which returns every account and slot the call accesses,
+// that is the whole state the execution needs since it is deterministic.
+// Only the native prestateTracer is required from the node, tracer itself runs locally.
+func Simulate(
+	client jsonrpc.Client,
+	calldata *jsonrpc.DebugTraceCallCalldataParam,
+	blockNumber string,
+	overrides jsonrpc.StateOverride,
+	tracer vm.EVMLogger,
+) (*SimulationResult, error) {
+	// resolve the block first so the state and the header are from the same block
+	header, err := getHeader(client, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block %s: %w", blockNumber, err)
+	}
+	chainID, err := getChainID(client)
+	if err != nil {
+		return nil, err
+	}
+	prestate := make(map[common.Address]jsonrpc.PrestateAccount)
+	err = jsonrpc.DebugTraceCall(
+		client,
+		calldata,
+		hexutil.EncodeBig(header.Number.ToInt()),
+		&jsonrpc.DebugTraceCallTracerConfigParam{
+			// https://github.com/ethereum/go-ethereum/blob/master/eth/tracers/native/prestate.go
+			Tracer:         "prestateTracer",
+			StateOverrides: overrides,
+		},
+		&prestate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get prestate: %w", err)
+	}
+
+	msg, err := callMessage(calldata, header)
+	if err != nil {
+		return nil, err
+	}
+	return simulate(chainID, header, blockHashes(client), prestate, msg, tracer)
+}
+
+// blockHashes returns the hashes of previous blocks for BLOCKHASH.
+func blockHashes(client jsonrpc.Client) vm.GetHashFunc {
+	return func(n uint64) common.Hash {
+		h, err := getHeader(client, hexutil.EncodeUint64(n))
+		if err != nil {
+			return common.Hash{}
+		}
+		return h.Hash
+	}
+}
+
+// simulate runs a call on an in-memory state built from the prestate.
+func simulate(
+	chainID *big.Int,
+	header *Header,
+	getHash vm.GetHashFunc,
+	prestate map[common.Address]jsonrpc.PrestateAccount,
+	msg *message,
+	tracer vm.EVMLogger,
+) (*SimulationResult, error) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return nil, err
+	}
+	for address, account := range prestate {
+		statedb.CreateAccount(address)
+		if account.Balance != nil {
+			statedb.SetBalance(address, uint256.MustFromBig(account.Balance.ToInt()))
+		}
+		statedb.SetNonce(address, account.Nonce)
+		statedb.SetCode(address, account.Code)
+		for slot, value := range account.Storage {
+			statedb.SetState(address, slot, value)
+		}
+	}
+
+	config := chainConfig(chainID)
+
+	blockContext := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     getHash,
+		Coinbase:    header.Coinbase,
+		GasLimit:    uint64(header.GasLimit),
+		BlockNumber: header.Number.ToInt(),
+		Time:        uint64(header.Time),
+		Difficulty:  new(big.Int),
+	}
+	if header.Difficulty != nil {
+		blockContext.Difficulty = header.Difficulty.ToInt()
+	}
+	if header.BaseFee != nil {
+		blockContext.BaseFee = header.BaseFee.ToInt()
+	}
+	if header.ExcessBlobGas != nil {
+		blockContext.BlobBaseFee = eip4844.CalcBlobFee(uint64(*header.ExcessBlobGas))
+	} else {
+		blockContext.BlobBaseFee = new(big.Int)
+	}
+	// proof-of-stake blocks have no difficulty, the mix digest is PREVRANDAO
+	if blockContext.Difficulty.Sign() == 0 {
+		random := header.MixDigest
+		blockContext.Random = &random
+	}
+
+	evm := vm.NewEVM(
+		blockContext,
+		vm.TxContext{Origin: msg.from, GasPrice: msg.gasPrice},
+		statedb,
+		config,
+		vm.Config{Tracer: tracer, NoBaseFee: true},
+	)
+	rules := config.Rules(blockContext.BlockNumber, blockContext.Random != nil, blockContext.Time)
+	statedb.Prepare(rules, msg.from, blockContext.Coinbase, &msg.to, vm.ActivePrecompiles(rules), nil)
+
+	if tracer != nil {
+		tracer.CaptureTxStart(msg.gas)
+	}
+	output, leftOverGas, err := evm.Call(vm.AccountRef(msg.from), msg.to, msg.input, msg.gas, uint256.MustFromBig(msg.value))
+	if tracer != nil {
+		tracer.CaptureTxEnd(leftOverGas)
+	}
+	return &SimulationResult{
+		Output:  output,
+		GasUsed: msg.gas - leftOverGas,
+		Err:     err,
+	}, nil
+}
+
+// TraceStorageInProcess traces a call with the native storage tracer, in-process (see Simulate).
+func TraceStorageInProcess(
+	client jsonrpc.Client,
+	calldata *jsonrpc.DebugTraceCallCalldataParam,
+	blockNumber string,
+	overrides jsonrpc.StateOverride,
+) (*StorageTrace, error) {
+	tracer := NewStorageTracer()
+	result, err := Simulate(client, calldata, blockNumber, overrides, tracer)
+	if err != nil {
+		return nil, err
+	}
+	// a revert is the call's own behavior, any other error (e.g. an opcode unknown to this EVM) means the simulation
+	// diverged from the node and its trace can't be trusted
+	if result.Err != nil && !errors.Is(result.Err, vm.ErrExecutionReverted) {
+		return nil, fmt.Errorf("could not simulate call: %w", result.Err)
+	}
+	return storageTraceResult(tracer)
+}
+
+func storageTraceResult(tracer tracers.Tracer) (*StorageTrace, error) {
+	encoded, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	trace := new(StorageTrace)
+	if err := json.Unmarshal(encoded, trace); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
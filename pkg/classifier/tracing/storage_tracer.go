@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// StorageTracerName is the name the storage tracer is registered with in tracers.DefaultDirectory,
+// so nodes built with this package can serve it with debug_traceCall.
+const StorageTracerName = "storageTracer"
+
+func init() {
+	tracers.DefaultDirectory.Register(StorageTracerName, newStorageTracer, false)
+}
+
+// storageTracer is a go implementation of storeTracer.js, it records every SLOAD and SSTORE instruction.
+type storageTracer struct {
+	env       *vm.EVM
+	trace     StorageTrace
+	interrupt atomic.Bool
+	reason    error
+}
+
+// NewStorageTracer returns a storage tracer, its result is a StorageTrace.
+func NewStorageTracer() tracers.Tracer {
+	return &storageTracer{}
+}
+
+func newStorageTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return NewStorageTracer(), nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *storageTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *storageTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.trace.Output = common.CopyBytes(output)
+}
+
+// CaptureState records SLOAD and SSTORE instructions with the value of their slot before they are executed.
+func (t *storageTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || t.interrupt.Load() {
+		return
+	}
+	if op != vm.SLOAD && op != vm.SSTORE {
+		return
+	}
+	stack := scope.Stack.Data()
+	if len(stack) < 1 || op == vm.SSTORE && len(stack) < 2 {
+		return
+	}
+	var (
+		address = scope.Contract.Address()
+		slot    = common.Hash(scope.Stack.Back(0).Bytes32())
+		value   = t.env.StateDB.GetState(address, slot)
+	)
+	access := StorageAccess{
+		Op:       op,
+		Address:  address,
+		Slot:     slot,
+		OldValue: value,
+		NewValue: value,
+		Depth:    depth,
+	}
+	if op == vm.SSTORE {
+		access.NewValue = scope.Stack.Back(1).Bytes32()
+	}
+	t.trace.Ops = append(t.trace.Ops, access)
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *storageTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *storageTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't execute any code.
+func (t *storageTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+func (*storageTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (*storageTracer) CaptureTxEnd(restGas uint64) {}
+
+// GetResult returns the StorageTrace encoded in JSON.
+func (t *storageTracer) GetResult() (json.RawMessage, error) {
+	if t.trace.Ops == nil {
+		t.trace.Ops = []StorageAccess{}
+	}
+	result, err := json.Marshal(t.trace)
+	if err != nil {
+		return nil, err
+	}
+	return result, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *storageTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
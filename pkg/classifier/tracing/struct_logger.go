@@ -0,0 +1,185 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// storageContext is the storage a frame reads and writes: the callee's for CALL and STATICCALL,
+// the caller's for DELEGATECALL and CALLCODE.
+type storageContext struct {
+	address common.Address
+	// creating is set while the contract is being created, its address is only known when the creation returns
+	creating bool
+	// accesses are the indexes of the accesses to patch with the address of the created contract
+	accesses []int
+	// values are the last values read or written in the trace
+	values map[common.Hash]common.Hash
+}
+
+type structLogFrame struct {
+	storage *storageContext
+}
+
+func stackBack(log *jsonrpc.StructLog, n int) (common.Hash, error) {
+	if len(log.Stack) <= n {
+		return common.Hash{}, fmt.Errorf("%s at pc %d has no stack, the node may disable it", log.Op, log.Pc)
+	}
+	return common.HexToHash(log.Stack[len(log.Stack)-1-n]), nil
+}
+
+func storageValue(log *jsonrpc.StructLog, slot common.Hash) (common.Hash, bool) {
+	for s, value := range log.Storage {
+		if common.HexToHash(s) == slot {
+			return common.HexToHash(value), true
+		}
+	}
+	return common.Hash{}, false
+}
+
+// StorageTraceFromStructLogs rebuilds the storage trace of a call to root from the logs of the default struct logger.
+// The struct logger does not say which contract a step runs in, so it is tracked through the call opcodes' stack
+// arguments. SLOADs read their value from the storage field, SSTOREs do not have their old value in the logs,
+// so it is the last value read or written in the trace, or initialValue if the slot was not accessed yet.
+func StorageTraceFromStructLogs(
+	root common.Address,
+	result *jsonrpc.StructLoggerResult,
+	initialValue func(address common.Address, slot common.Hash) (common.Hash, error),
+) (*StorageTrace, error) {
+	var (
+		trace    = &StorageTrace{Ops: []StorageAccess{}}
+		contexts = make(map[common.Address]*storageContext)
+		frames   []*structLogFrame
+		// pending is the frame entered if the next step is one level deeper
+		pending *structLogFrame
+	)
+	storageOf := func(address common.Address) *storageContext {
+		if contexts[address] == nil {
+			contexts[address] = &storageContext{address: address, values: make(map[common.Hash]common.Hash)}
+		}
+		return contexts[address]
+	}
+	frames = append(frames, &structLogFrame{storage: storageOf(root)})
+	record := func(storage *storageContext, access StorageAccess) {
+		if storage.creating {
+			storage.accesses = append(storage.accesses, len(trace.Ops))
+		}
+		trace.Ops = append(trace.Ops, access)
+	}
+
+	for i := range result.StructLogs {
+		log := &result.StructLogs[i]
+		if pending != nil && log.Depth == len(frames)+1 {
+			frames = append(frames, pending)
+		}
+		pending = nil
+		for log.Depth < len(frames) {
+			exited := frames[len(frames)-1]
+			frames = frames[:len(frames)-1]
+			if exited.storage.creating {
+				// CREATE and CREATE2 push the address of the created contract
+				created, err := stackBack(log, 0)
+				if err != nil {
+					return nil, err
+				}
+				exited.storage.address = common.BytesToAddress(created.Bytes())
+				exited.storage.creating = false
+				for _, j := range exited.storage.accesses {
+					trace.Ops[j].Address = exited.storage.address
+				}
+				if contexts[exited.storage.address] == nil {
+					contexts[exited.storage.address] = exited.storage
+				}
+			}
+		}
+		current := frames[len(frames)-1].storage
+
+		switch op := vm.StringToOp(log.Op); op {
+		case vm.CALL, vm.STATICCALL:
+			to, err := stackBack(log, 1)
+			if err != nil {
+				return nil, err
+			}
+			pending = &structLogFrame{storage: storageOf(common.BytesToAddress(to.Bytes()))}
+		case vm.DELEGATECALL, vm.CALLCODE:
+			pending = &structLogFrame{storage: current}
+		case vm.CREATE, vm.CREATE2:
+			pending = &structLogFrame{storage: &storageContext{creating: true, values: make(map[common.Hash]common.Hash)}}
+		case vm.SLOAD:
+			slot, err := stackBack(log, 0)
+			if err != nil {
+				return nil, err
+			}
+			value, ok := storageValue(log, slot)
+			if !ok && i+1 < len(result.StructLogs) {
+				// older nodes set the storage of an SLOAD on the following step
+				value, ok = storageValue(&result.StructLogs[i+1], slot)
+			}
+			if !ok {
+				return nil, fmt.Errorf("SLOAD at pc %d has no storage, the node may disable it", log.Pc)
+			}
+			current.values[slot] = value
+			record(current, StorageAccess{Op: op, Address: current.address, Slot: slot, OldValue: value, NewValue: value, Depth: log.Depth})
+		case vm.SSTORE:
+			slot, err := stackBack(log, 0)
+			if err != nil {
+				return nil, err
+			}
+			newValue, err := stackBack(log, 1)
+			if err != nil {
+				return nil, err
+			}
+			value, ok := current.values[slot]
+			if !ok && !current.creating {
+				if value, err = initialValue(current.address, slot); err != nil {
+					return nil, err
+				}
+			}
+			current.values[slot] = newValue
+			record(current, StorageAccess{Op: op, Address: current.address, Slot: slot, OldValue: value, NewValue: newValue, Depth: log.Depth})
+		}
+	}
+
+	output, err := hex.DecodeString(strings.TrimPrefix(result.ReturnValue, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode return value: %w", err)
+	}
+	trace.Output = output
+	return trace, nil
+}
+
+// TraceStorageWithStructLogger traces a call with the default struct logger and rebuilds its storage trace,
+// for nodes supporting neither the JavaScript tracer nor the prestateTracer. This is slow, the node returns the stack
+// and storage of every step.
+func TraceStorageWithStructLogger(
+	client jsonrpc.Client,
+	calldata *jsonrpc.DebugTraceCallCalldataParam,
+	blockNumber string,
+	overrides jsonrpc.StateOverride,
+) (*StorageTrace, error) {
+	result, err := jsonrpc.DebugTraceCallStructLogger(client, calldata, blockNumber, &jsonrpc.StructLoggerConfigParam{
+		StateOverrides: overrides,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the value of a slot at the start of the call is its overridden value, or its value at blockNumber
+	initialValue := func(address common.Address, slot common.Hash) (common.Hash, error) {
+		if override, ok := overrides[address]; ok {
+			if value, ok := override.StateDiff[slot]; ok {
+				return common.HexToHash(value), nil
+			}
+			if override.State != nil {
+				return common.HexToHash(override.State[slot]), nil
+			}
+		}
+		return jsonrpc.GetStorageAt(client, address, slot, blockNumber)
+	}
+	return StorageTraceFromStructLogs(common.HexToAddress(calldata.To), result, initialValue)
+}
@@ -1 +1,73 @@
+// Package tracing captures the SLOAD and SSTORE instructions of a call without the JavaScript tracer:
+// with a native go-ethereum tracer run in-process (see TraceStorageInProcess), or rebuilt from the default
+// struct logger on nodes without custom tracers (see TraceStorageWithStructLogger).
 package tracing
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// StorageAccess is an SLOAD or SSTORE instruction.
+type StorageAccess struct {
+	Op      vm.OpCode      `json:"op"`
+	Address common.Address `json:"addr"`
+	Slot    common.Hash    `json:"slot"`
+	// OldValue is the value of the slot before the instruction
+	OldValue common.Hash `json:"oldValue"`
+	// NewValue is the value of the slot after the instruction, OldValue for an SLOAD
+	NewValue common.Hash `json:"newValue"`
+	// Depth is the call depth of the instruction, 1 in the called contract
+	Depth int `json:"depth"`
+}
+
+// StorageTrace is the result of the storage tracers (the native tracer, storeTracer.js or the struct logger's rebuild).
+type StorageTrace struct {
+	Ops    []StorageAccess `json:"ops"`
+	Output hexutil.Bytes   `json:"output"`
+}
+
+// SlotAccess sums up the accesses to a slot in a trace.
+type SlotAccess struct {
+	// Before is the value of the slot before its first access
+	Before common.Hash
+	// After is the value of the slot after its last access. Writes in reverted calls are not undone.
+	After  common.Hash
+	Loaded bool
+	Stored bool
+}
+
+// Contracts returns the accesses to the storage of each contract, by slot.
+func (t *StorageTrace) Contracts() map[common.Address]map[common.Hash]*SlotAccess {
+	contracts := make(map[common.Address]map[common.Hash]*SlotAccess)
+	for _, op := range t.Ops {
+		slots := contracts[op.Address]
+		if slots == nil {
+			slots = make(map[common.Hash]*SlotAccess)
+			contracts[op.Address] = slots
+		}
+		access := slots[op.Slot]
+		if access == nil {
+			access = &SlotAccess{Before: op.OldValue}
+			slots[op.Slot] = access
+		}
+		access.After = op.NewValue
+		switch op.Op {
+		case vm.SLOAD:
+			access.Loaded = true
+		case vm.SSTORE:
+			access.Stored = true
+		}
+	}
+	return contracts
+}
+
+// Slots returns the accesses to the storage of a contract, by slot.
+func (t *StorageTrace) Slots(address common.Address) map[common.Hash]*SlotAccess {
+	slots := t.Contracts()[address]
+	if slots == nil {
+		return make(map[common.Hash]*SlotAccess)
+	}
+	return slots
+}
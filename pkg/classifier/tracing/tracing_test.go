@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+var (
+	caller   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	contract = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	callee   = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	// contract copies slot 1 into slot 2, then calls callee and returns its output
+	contractCode = hexutil.MustDecode("0x" +
+		"600154" + "600255" + // SSTORE(2, SLOAD(1))
+		"6020600060006000600073" + callee.Hex()[2:] + "5af150" + // CALL(gas, callee, 0, 0, 0, 0, 32)
+		"60206000f3") // RETURN(0, 32)
+	// callee returns slot 0
+	calleeCode = hexutil.MustDecode("0x600054" + "60005260206000f3")
+
+	prestate = map[common.Address]jsonrpc.PrestateAccount{
+		caller: {Balance: (*hexutil.Big)(big.NewInt(1e18))},
+		contract: {
+			Code:    contractCode,
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x1"): common.HexToHash("0x5")},
+		},
+		callee: {
+			Code:    calleeCode,
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x0"): common.HexToHash("0x7")},
+		},
+	}
+
+	expectedTrace = &StorageTrace{
+		Ops: []StorageAccess{
+			{Op: vm.SLOAD, Address: contract, Slot: common.HexToHash("0x1"), OldValue: common.HexToHash("0x5"), NewValue: common.HexToHash("0x5"), Depth: 1},
+			{Op: vm.SSTORE, Address: contract, Slot: common.HexToHash("0x2"), OldValue: common.Hash{}, NewValue: common.HexToHash("0x5"), Depth: 1},
+			{Op: vm.SLOAD, Address: callee, Slot: common.HexToHash("0x0"), OldValue: common.HexToHash("0x7"), NewValue: common.HexToHash("0x7"), Depth: 2},
+		},
+		Output: common.HexToHash("0x7").Bytes(),
+	}
+)
+
+func simulateTestCall(t *testing.T, tracer vm.EVMLogger) *SimulationResult {
+	result, err := simulate(
+		big.NewInt(1),
+		&Header{
+			Number:     (*hexutil.Big)(big.NewInt(1)),
+			GasLimit:   30_000_000,
+			Difficulty: (*hexutil.Big)(new(big.Int)),
+		},
+		func(uint64) common.Hash { return common.Hash{} },
+		prestate,
+		&message{
+			from:     caller,
+			to:       contract,
+			gas:      500000,
+			gasPrice: new(big.Int),
+			value:    new(big.Int),
+		},
+		tracer,
+	)
+	require.NoError(t, err)
+	require.NoError(t, result.Err)
+	return result
+}
+
+func TestStorageTracer(t *testing.T) {
+	tracer := NewStorageTracer()
+	simulateTestCall(t, tracer)
+
+	trace, err := storageTraceResult(tracer)
+	require.NoError(t, err)
+	assert.Equal(t, expectedTrace, trace)
+}
+
+func TestStorageTraceFromStructLogs(t *testing.T) {
+	structLogger := logger.NewStructLogger(nil)
+	simulateTestCall(t, structLogger)
+	encoded, err := structLogger.GetResult()
+	require.NoError(t, err)
+	result := new(jsonrpc.StructLoggerResult)
+	require.NoError(t, json.Unmarshal(encoded, result))
+
+	trace, err := StorageTraceFromStructLogs(contract, result, func(address common.Address, slot common.Hash) (common.Hash, error) {
+		return prestate[address].Storage[slot], nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expectedTrace, trace)
+}
+
+// prestateClient serves a block, the chain id and a prestate to Simulate.
+type prestateClient struct {
+	prestate map[common.Address]jsonrpc.PrestateAccount
+}
+
+func (c *prestateClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	switch method {
+	case "eth_getBlockByNumber":
+		*result.(*Header) = Header{
+			Number:     (*hexutil.Big)(big.NewInt(1)),
+			GasLimit:   30_000_000,
+			Difficulty: (*hexutil.Big)(new(big.Int)),
+		}
+	case "eth_chainId":
+		*result.(*hexutil.Big) = hexutil.Big(*big.NewInt(1))
+	case "debug_traceCall":
+		*result.(*map[common.Address]jsonrpc.PrestateAccount) = c.prestate
+	default:
+		return errors.New("not supported")
+	}
+	return nil
+}
+
+func (c *prestateClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("not supported")
+}
+
+func TestTraceStorageInProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    []byte
+		wantErr bool
+	}{
+		{name: "success", code: contractCode},
+		// SSTORE(0, 1) REVERT(0, 0)
+		{name: "revert", code: hexutil.MustDecode("0x6001600055" + "60006000fd")},
+		// TSTORE(0, 1) MCOPY(32, 0, 32)
+		{name: "cancun opcodes", code: hexutil.MustDecode("0x600160005d" + "6020600060205e")},
+		{name: "invalid opcode", code: hexutil.MustDecode("0xfe"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &prestateClient{prestate: map[common.Address]jsonrpc.PrestateAccount{
+				caller:   prestate[caller],
+				contract: {Code: tt.code, Storage: prestate[contract].Storage},
+				callee:   prestate[callee],
+			}}
+			_, err := TraceStorageInProcess(client, &jsonrpc.DebugTraceCallCalldataParam{
+				From: caller.Hex(),
+				To:   contract.Hex(),
+			}, "latest", nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
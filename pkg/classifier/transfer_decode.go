@@ -0,0 +1,81 @@
+package classifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
+)
+
+var (
+	transferMethodABI     = abis.ERC20.Methods["transfer"]
+	transferFromMethodABI = abis.ERC20.Methods["transferFrom"]
+	transferEventABI      = abis.ERC20.Events["Transfer"]
+)
+
+// ErrNotTransferCall is returned when an input is neither a transfer() nor a transferFrom() call.
+var ErrNotTransferCall = errors.New("not a transfer() or transferFrom() call")
+
+// TransferCall is a decoded transfer() or transferFrom() call.
+type TransferCall struct {
+	IsTransferFrom bool
+	// From is the owner of the tokens of a transferFrom(), zero for a transfer() which moves the caller's tokens
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+}
+
+// DecodeTransferCall decodes the input of a transfer() or transferFrom() call.
+func DecodeTransferCall(input []byte) (*TransferCall, error) {
+	switch {
+	case bytes.HasPrefix(input, transferMethodABI.ID):
+		params, err := transferMethodABI.Inputs.Unpack(input[4:])
+		if err != nil {
+			return nil, fmt.Errorf("could not unpack transfer() method params: %w", err)
+		}
+		return &TransferCall{
+			To:     params[0].(common.Address),
+			Amount: params[1].(*big.Int),
+		}, nil
+	case bytes.HasPrefix(input, transferFromMethodABI.ID):
+		params, err := transferFromMethodABI.Inputs.Unpack(input[4:])
+		if err != nil {
+			return nil, fmt.Errorf("could not unpack transferFrom() method params: %w", err)
+		}
+		return &TransferCall{
+			IsTransferFrom: true,
+			From:           params[0].(common.Address),
+			To:             params[1].(common.Address),
+			Amount:         params[2].(*big.Int),
+		}, nil
+	}
+	return nil, ErrNotTransferCall
+}
+
+// IsTransferEvent returns if a log is an ERC20 Transfer event, with indexed sender and receiver.
+func IsTransferEvent(vLog *ethtypes.Log) bool {
+	return len(vLog.Topics) == 3 && vLog.Topics[0] == transferEventABI.ID
+}
+
+// DecodeTransferEvent decodes an ERC20 Transfer event.
+func DecodeTransferEvent(vLog *ethtypes.Log) (*types.TxFromTransferEvent, error) {
+	if !IsTransferEvent(vLog) {
+		return nil, errors.New("not a Transfer event")
+	}
+	event, err := abis.ERC20.Unpack("Transfer", vLog.Data)
+	if err != nil {
+		return nil, fmt.Errorf("could not unpack event log: %w", err)
+	}
+	return &types.TxFromTransferEvent{
+		From:   common.BytesToAddress(vLog.Topics[1].Bytes()),
+		To:     common.BytesToAddress(vLog.Topics[2].Bytes()),
+		TxHash: vLog.TxHash,
+		Amount: event[0].(*big.Int),
+	}, nil
+}
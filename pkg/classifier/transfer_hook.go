@@ -0,0 +1,244 @@
+package classifier
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// ExternalCallKind is the category of an external call made by a token contract during a transfer.
+type ExternalCallKind string
+
+const (
+	// ExternalCallHook is a transfer hook: ERC-777 tokensToSend/tokensReceived, ERC-1363 callbacks or an ERC-1820 registry lookup.
+	ExternalCallHook ExternalCallKind = "hook"
+	// ExternalCallSwapBack is a DEX router or pair call, usually a tax token swapping its accumulated fees.
+	ExternalCallSwapBack ExternalCallKind = "swap-back"
+	// ExternalCallOracle is a price or reserve read, e.g. Chainlink latestRoundData or pair getReserves.
+	ExternalCallOracle ExternalCallKind = "oracle"
+	// ExternalCallUnknown is any other external call.
+	ExternalCallUnknown ExternalCallKind = "unknown"
+)
+
+// erc1820Registry is the address of the ERC-1820 registry, the same on every chain.
+var erc1820Registry = common.HexToAddress("0x1820a4B7618BdE71Dce8cdc73aAB6C95905faD24")
+
+var (
+	hookMethodSignatures = []string{
+		// ERC-777
+		"tokensToSend(address,address,address,uint256,bytes,bytes)",
+		"tokensReceived(address,address,address,uint256,bytes,bytes)",
+		// ERC-1363
+		"onTransferReceived(address,address,uint256,bytes)",
+		"onApprovalReceived(address,uint256,bytes)",
+		// ERC-1820
+		"getInterfaceImplementer(address,bytes32)",
+	}
+	swapBackMethodSignatures = []string{
+		// UniswapV2 router
+		"swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+		"swapExactTokensForTokensSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+		"swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+		"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+		"swapTokensForExactETH(uint256,uint256,address[],address,uint256)",
+		"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)",
+		"addLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+		"addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)",
+		// UniswapV2 pair
+		"swap(uint256,uint256,address,bytes)",
+		"mint(address)",
+		"sync()",
+		"skim(address)",
+		// UniswapV3 SwapRouter and SwapRouter02
+		"exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))",
+		"exactInputSingle((address,address,uint24,address,uint256,uint256,uint160))",
+		"exactInput((bytes,address,uint256,uint256,uint256))",
+		"exactInput((bytes,address,uint256,uint256))",
+		// UniswapV3 pool
+		"swap(address,bool,int256,uint160,bytes)",
+	}
+	oracleMethodSignatures = []string{
+		// Chainlink aggregator
+		"latestRoundData()",
+		"latestAnswer()",
+		// UniswapV2 pair and router quotes
+		"getReserves()",
+		"getAmountsOut(uint256,address[])",
+		"getAmountsIn(uint256,address[])",
+		// UniswapV3 pool
+		"slot0()",
+		"observe(uint32[])",
+		// common TWAP oracles
+		"consult(address,uint256)",
+	}
+
+	externalCallKinds = make(map[string]ExternalCallKind)
+)
+
+func init() {
+	for _, b := range []struct {
+		kind       ExternalCallKind
+		signatures []string
+	}{
+		{ExternalCallHook, hookMethodSignatures},
+		{ExternalCallSwapBack, swapBackMethodSignatures},
+		{ExternalCallOracle, oracleMethodSignatures},
+	} {
+		for _, sig := range b.signatures {
+			externalCallKinds[getMethodHash(sig)] = b.kind
+		}
+	}
+}
+
+// ExternalCall is a call made by a token contract to another contract during a transfer.
+type ExternalCall struct {
+	Kind ExternalCallKind
+	// Type is the call type reported by the callTracer: CALL, STATICCALL, ...
+	Type string
+	From common.Address
+	To   common.Address
+	// Selector is the hex encoded 4-byte method id, empty if the call has no calldata (e.g. a plain native transfer)
+	Selector string
+	Input    hexutil.Bytes
+	Value    *hexutil.Big
+	// Depth is the depth of the call relative to the transfer call
+	Depth int
+
+	frame *jsonrpc.CallFrame
+}
+
+// TransferHookResult stores the external calls made during a transfer.
+type TransferHookResult struct {
+	ExternalCalls []ExternalCall
+}
+
+// Has returns true if any external call is of the given kind.
+func (r *TransferHookResult) Has(kind ExternalCallKind) bool {
+	for _, call := range r.ExternalCalls {
+		if call.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyExternalCall returns the category of a call based on its target and method id.
+func ClassifyExternalCall(call *jsonrpc.CallFrame) ExternalCallKind {
+	if call.To != nil && *call.To == erc1820Registry {
+		return ExternalCallHook
+	}
+	if len(call.Input) < 4 {
+		return ExternalCallUnknown
+	}
+	if kind, ok := externalCallKinds[hexutil.Encode(call.Input[:4])]; ok {
+		return kind
+	}
+	return ExternalCallUnknown
+}
+
+// walkCallFrames calls callback on call and all of its sub calls, depth first.
+func walkCallFrames(call *jsonrpc.CallFrame, depth int, callback func(call *jsonrpc.CallFrame, depth int)) {
+	callback(call, depth)
+	for i := range call.Calls {
+		walkCallFrames(&call.Calls[i], depth+1, callback)
+	}
+}
+
+// FindTransferCalls returns the successful transfer() and transferFrom() calls to token in the call tree.
+// Transfers nested in another transfer of the same token (e.g. the token selling its fees in a swap-back) are part
+// of the outer transfer and are not returned.
+func FindTransferCalls(root *jsonrpc.CallFrame, token common.Address) []*jsonrpc.CallFrame {
+	var (
+		transferID     = abis.ERC20.Methods["transfer"].ID
+		transferFromID = abis.ERC20.Methods["transferFrom"].ID
+		calls          []*jsonrpc.CallFrame
+		find           func(call *jsonrpc.CallFrame)
+	)
+	find = func(call *jsonrpc.CallFrame) {
+		if call.To != nil && *call.To == token && call.Error == "" &&
+			call.Type != "DELEGATECALL" && call.Type != "STATICCALL" &&
+			(bytes.HasPrefix(call.Input, transferID) || bytes.HasPrefix(call.Input, transferFromID)) {
+			calls = append(calls, call)
+			return
+		}
+		for i := range call.Calls {
+			find(&call.Calls[i])
+		}
+	}
+	find(root)
+	return calls
+}
+
+// ExternalCallsDuringTransfer returns the calls made by the token to other contracts within a transfer call.
+// Delegate calls (e.g. from a proxy to its implementation) run in the token's context so they are not reported,
+// but the calls they make are.
+func ExternalCallsDuringTransfer(transferCall *jsonrpc.CallFrame, token common.Address) []ExternalCall {
+	var calls []ExternalCall
+	walkCallFrames(transferCall, 0, func(call *jsonrpc.CallFrame, depth int) {
+		if depth == 0 || call.From != token || call.To == nil || *call.To == token {
+			return
+		}
+		if call.Type == "DELEGATECALL" || call.Type == "CALLCODE" {
+			return
+		}
+		var selector string
+		if len(call.Input) >= 4 {
+			selector = hexutil.Encode(call.Input[:4])
+		}
+		calls = append(calls, ExternalCall{
+			Kind:     ClassifyExternalCall(call),
+			Type:     call.Type,
+			From:     call.From,
+			To:       *call.To,
+			Selector: selector,
+			Input:    call.Input,
+			Value:    call.Value,
+			Depth:    depth,
+			frame:    call,
+		})
+	})
+	return calls
+}
+
+// DetectTransferHooks simulates the scenario's transfer with the builtin callTracer
+// and reports the external calls made by the token during the transfer.
+func (c *StorageTraceClassifier) DetectTransferHooks(scenario *jsonrpc.TransferScenario) (*TransferHookResult, error) {
+	_, blockNumberHex, err := c.resolveBlockNumber(scenario)
+	if err != nil {
+		return nil, err
+	}
+	transferData, err := packTransferData(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	callFrame := new(jsonrpc.CallFrame)
+	err = c.gas.traceTransfer(scenario, transferData, func(calldata *jsonrpc.DebugTraceCallCalldataParam) error {
+		return jsonrpc.DebugTraceCall(
+			c.client,
+			calldata,
+			blockNumberHex,
+			&jsonrpc.DebugTraceCallTracerConfigParam{
+				// https://github.com/ethereum/go-ethereum/blob/master/eth/tracers/native/call.go
+				Tracer:         "callTracer",
+				StateOverrides: msgSenderBalanceOverride(scenario),
+			},
+			callFrame,
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not debug_traceCall a transfer tx: %w", err)
+	}
+	if callFrame.Error != "" {
+		return nil, fmt.Errorf("transfer reverted: %s", callFrame.Error)
+	}
+
+	return &TransferHookResult{
+		ExternalCalls: ExternalCallsDuringTransfer(callFrame, scenario.Token),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package classifier
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+func addressPtr(s string) *common.Address {
+	addr := common.HexToAddress(s)
+	return &addr
+}
+
+func selectorInput(signature string) hexutil.Bytes {
+	return hexutil.MustDecode(getMethodHash(signature))
+}
+
+func TestExternalCallsDuringTransfer(t *testing.T) {
+	var (
+		sender         = common.HexToAddress("0x2FD45E9c69D50cD08a03792253daC3CA37a81cBf")
+		token          = common.HexToAddress("0x9b0e1c344141fb361b842d397df07174e1cdb988")
+		implementation = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		router         = common.HexToAddress("0x7a250d5630b4cf539739df2c5dacb4c659f2488d")
+		pair           = common.HexToAddress("0xfffa78c979c2f787b16eac7c7e9c77b11feb77fb")
+		receiver       = common.HexToAddress("0x49003cc3b1d8835c3b4aa5a581a6be0b0843e91d")
+	)
+	transferData, err := abis.ERC20.Pack("transfer", receiver, big.NewInt(1000))
+	require.NoError(t, err)
+
+	root := &jsonrpc.CallFrame{
+		Type:  "CALL",
+		From:  sender,
+		To:    &token,
+		Input: transferData,
+		Calls: []jsonrpc.CallFrame{
+			{
+				// proxy to implementation, runs in the token's context
+				Type:  "DELEGATECALL",
+				From:  token,
+				To:    &implementation,
+				Input: transferData,
+				Calls: []jsonrpc.CallFrame{
+					{Type: "CALL", From: token, To: &erc1820Registry, Input: selectorInput("getInterfaceImplementer(address,bytes32)")},
+					{Type: "STATICCALL", From: token, To: &pair, Input: selectorInput("getReserves()")},
+					{
+						Type:  "CALL",
+						From:  token,
+						To:    &router,
+						Input: selectorInput("swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)"),
+						Calls: []jsonrpc.CallFrame{
+							// made by the router, not by the token
+							{Type: "CALL", From: router, To: &pair, Input: selectorInput("swap(uint256,uint256,address,bytes)")},
+						},
+					},
+					{Type: "CALL", From: token, To: &receiver, Input: selectorInput("tokensReceived(address,address,address,uint256,bytes,bytes)")},
+					{Type: "CALL", From: token, To: addressPtr("0x00000000000000000000000000000000000000bb")},
+				},
+			},
+		},
+	}
+
+	transfers := FindTransferCalls(root, token)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, root, transfers[0])
+
+	calls := ExternalCallsDuringTransfer(transfers[0], token)
+	kinds := make([]ExternalCallKind, 0, len(calls))
+	for _, call := range calls {
+		kinds = append(kinds, call.Kind)
+		assert.Equal(t, 2, call.Depth)
+	}
+	assert.Equal(t, []ExternalCallKind{
+		ExternalCallHook,
+		ExternalCallOracle,
+		ExternalCallSwapBack,
+		ExternalCallHook,
+		ExternalCallUnknown,
+	}, kinds)
+
+	result := &TransferHookResult{ExternalCalls: calls}
+	assert.True(t, result.Has(ExternalCallSwapBack))
+}
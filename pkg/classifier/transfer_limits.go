@@ -0,0 +1,249 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/utils"
+)
+
+// ErrTransferNotSuccess is returned when a simulated transfer does not return true.
+var ErrTransferNotSuccess = errors.New("transfer not success")
+
+// TransferLimits stores the largest amounts a token lets its holders move.
+type TransferLimits struct {
+	// MaxTxAmount is the largest amount that can be transferred in one transfer (e.g. maxTxAmount),
+	// nil if the whole total supply can be transferred, or if it is above MaxWalletBalance and no receiver exempt
+	// from the max-wallet limit was found to transfer more.
+	MaxTxAmount *big.Int
+	// MaxWalletBalance is the largest balance a wallet can reach by receiving a transfer (e.g. maxWallet),
+	// nil if a wallet can receive the whole total supply.
+	MaxWalletBalance *big.Int
+}
+
+// isTransferSuccess returns true if the output of transfer() or transferFrom() is true.
+// Some tokens (e.g. USDT) do not return anything.
+func isTransferSuccess(output []byte) bool {
+	return len(output) == 0 || new(big.Int).SetBytes(output).Cmp(big.NewInt(1)) == 0
+}
+
+// ethCallUint256 calls a method returning a single uint256.
+func (c *StorageTraceClassifier) ethCallUint256(
+	contract common.Address, data []byte, blockNumberHex string, override jsonrpc.StateOverride,
+) (*big.Int, error) {
+	result, err := jsonrpc.EthCall(
+		c.client,
+		&jsonrpc.EthCallCalldataParam{
+			From: common.Address{}.String(),
+			To:   contract.String(),
+			Data: hexutil.Encode(data),
+		},
+		blockNumberHex,
+		override,
+	)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := hexutil.Decode(*result)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// searchLargest binary searches the largest value in [lo, hi) satisfying ok,
+// given that ok(lo) is true, ok(hi) is false and ok is monotonic.
+func searchLargest(lo, hi *big.Int, ok func(*big.Int) (bool, error)) (*big.Int, error) {
+	lo, hi = new(big.Int).Set(lo), new(big.Int).Set(hi)
+	one := big.NewInt(1)
+	for new(big.Int).Sub(hi, lo).Cmp(one) > 0 {
+		mid := new(big.Int).Add(lo, hi)
+		mid.Rsh(mid, 1)
+		success, err := ok(mid)
+		if err != nil {
+			return nil, err
+		}
+		if success {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// ProbeTransferLimits finds the max-transaction and max-wallet limits of the scenario's token by binary searching
+// over simulated transfers from the scenario's holder to the scenario's receiver.
+// The balances of both are set with state overrides, so the balance slots of the token must be probable.
+// Limits only apply to non-exempt wallets, hence the holder and the receiver should be regular wallets.
+// Amounts above the max-wallet limit are sent to the token contract, usually exempt as it collects the fees.
+func (c *StorageTraceClassifier) ProbeTransferLimits(scenario *jsonrpc.TransferScenario) (*TransferLimits, error) {
+	if c.probe == nil {
+		return nil, errors.New("a balance slot probe is required to probe transfer limits")
+	}
+	_, blockNumberHex, err := c.resolveBlockNumber(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	holder := scenario.MsgSender
+	if scenario.IsTransferFrom {
+		holder = scenario.From
+	}
+
+	totalSupplyData, err := abis.ERC20.Pack("totalSupply")
+	if err != nil {
+		return nil, err
+	}
+	totalSupply, err := c.ethCallUint256(scenario.Token, totalSupplyData, blockNumberHex, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not eth_call totalSupply(): %w", err)
+	}
+	if totalSupply.Sign() <= 0 {
+		return nil, errors.New("total supply is zero")
+	}
+
+	holderSlot, err := c.probe.ProbeBalanceSlot(scenario.Token, holder)
+	if err != nil {
+		return nil, fmt.Errorf("could not probe balance slot of holder %s: %w", holder, err)
+	}
+	receiverSlot, err := c.probe.ProbeBalanceSlot(scenario.Token, scenario.To)
+	if err != nil {
+		return nil, fmt.Errorf("could not probe balance slot of receiver %s: %w", scenario.To, err)
+	}
+
+	// transferTo transfers amount from the holder owning the whole total supply to a receiver owning receiverBalance
+	transferTo := func(receiver common.Address, receiverSlot common.Hash) transferProbe {
+		return func(amount, receiverBalance *big.Int) (bool, error) {
+			transferData, err := abis.ERC20.Pack("transfer", receiver, amount)
+			if err != nil {
+				return false, err
+			}
+			success, output, err := c.callSucceeds(
+				holder,
+				scenario.Token,
+				transferData,
+				blockNumberHex,
+				jsonrpc.StateOverride{
+					scenario.Token: {
+						StateDiff: map[common.Hash]string{
+							holderSlot:   utils.RemoveLeadingZerosFromHash(common.BigToHash(totalSupply)),
+							receiverSlot: utils.RemoveLeadingZerosFromHash(common.BigToHash(receiverBalance)),
+						},
+					},
+				},
+			)
+			if err != nil {
+				return false, err
+			}
+			return success && isTransferSuccess(output), nil
+		}
+	}
+
+	var toExempt transferProbe
+	if contractSlot, err := c.probe.ProbeBalanceSlot(scenario.Token, scenario.Token); err != nil {
+		logger.Debugw("could not probe balance slot of the token contract", "token", scenario.Token, "error", err)
+	} else {
+		toExempt = transferTo(scenario.Token, contractSlot)
+	}
+
+	limits, err := searchTransferLimits(totalSupply, transferTo(scenario.To, receiverSlot), toExempt)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infow("probed transfer limits", "token", scenario.Token, "maxTxAmount", limits.MaxTxAmount, "maxWalletBalance", limits.MaxWalletBalance)
+	return limits, nil
+}
+
+// transferProbe returns true if the holder owning the whole total supply can transfer amount to a receiver owning
+// receiverBalance.
+type transferProbe func(amount, receiverBalance *big.Int) (bool, error)
+
+// searchTransferLimits finds the limits from transfers to a regular wallet, toWallet, and to a receiver that may be
+// exempt from the max-wallet limit, toExempt, nil if there is none.
+// A transfer to a wallet fails above either limit, so the max-wallet limit is searched first and the max-transaction
+// limit is only searched to a wallet below it. Above it, amounts are sent to the exempt receiver if it is exempt,
+// otherwise MaxTxAmount is left nil.
+func searchTransferLimits(totalSupply *big.Int, toWallet, toExempt transferProbe) (*TransferLimits, error) {
+	var (
+		zero   = big.NewInt(0)
+		one    = big.NewInt(1)
+		limits = new(TransferLimits)
+	)
+	success, err := toWallet(one, zero)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		return nil, fmt.Errorf("could not transfer the smallest amount: %w", ErrTransferNotSuccess)
+	}
+
+	/*
+		Step 1: the largest receivable balance, sending the smallest amount to a receiver with a growing balance.
+	*/
+	maxReceiverBalance := new(big.Int).Sub(totalSupply, one)
+	success, err = toWallet(one, maxReceiverBalance)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		balance, err := searchLargest(zero, maxReceiverBalance, func(balance *big.Int) (bool, error) {
+			return toWallet(one, balance)
+		})
+		if err != nil {
+			return nil, err
+		}
+		limits.MaxWalletBalance = balance.Add(balance, one)
+	}
+
+	/*
+		Step 2: the largest transferable amount, sending to a receiver with an empty balance up to the max wallet
+		balance.
+	*/
+	maxAmount := totalSupply
+	if limits.MaxWalletBalance != nil {
+		maxAmount = limits.MaxWalletBalance
+	}
+	success, err = toWallet(maxAmount, zero)
+	if err != nil {
+		return nil, err
+	}
+	if !success {
+		limits.MaxTxAmount, err = searchLargest(one, maxAmount, func(amount *big.Int) (bool, error) {
+			return toWallet(amount, zero)
+		})
+		return limits, err
+	}
+	if limits.MaxWalletBalance == nil {
+		return limits, nil
+	}
+
+	/*
+		Step 3: the largest transferable amount above the max wallet balance, sending to the exempt receiver.
+	*/
+	exempt := false
+	if toExempt != nil {
+		if exempt, err = toExempt(one, maxReceiverBalance); err != nil {
+			return nil, err
+		}
+	}
+	if !exempt {
+		logger.Infow("no receiver exempt from the max wallet balance, the max transaction amount is not known above it",
+			"maxWalletBalance", limits.MaxWalletBalance)
+		return limits, nil
+	}
+	success, err = toExempt(totalSupply, zero)
+	if err != nil || success {
+		return limits, err
+	}
+	limits.MaxTxAmount, err = searchLargest(maxAmount, totalSupply, func(amount *big.Int) (bool, error) {
+		return toExempt(amount, zero)
+	})
+	return limits, err
+}
@@ -0,0 +1,96 @@
+package classifier
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_searchLargest(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int64
+		hi    int64
+	}{
+		{name: "limit in the middle", limit: 12345, hi: 1000000},
+		{name: "limit is the lower bound", limit: 1, hi: 1000000},
+		{name: "limit is right below the upper bound", limit: 999999, hi: 1000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			got, err := searchLargest(big.NewInt(1), big.NewInt(tt.hi), func(amount *big.Int) (bool, error) {
+				calls++
+				return amount.Cmp(big.NewInt(tt.limit)) <= 0, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, big.NewInt(tt.limit), got)
+			assert.LessOrEqual(t, calls, 21)
+		})
+	}
+}
+
+// limitedToken simulates a token with a max-transaction and a max-wallet limit, 0 if it has none. transfer(true) sends
+// to a receiver exempt from the max-wallet limit.
+type limitedToken struct {
+	maxTx, maxWallet int64
+}
+
+func (l limitedToken) transfer(exempt bool) transferProbe {
+	return func(amount, receiverBalance *big.Int) (bool, error) {
+		if l.maxTx > 0 && amount.Cmp(big.NewInt(l.maxTx)) > 0 {
+			return false, nil
+		}
+		balance := new(big.Int).Add(receiverBalance, amount)
+		if !exempt && l.maxWallet > 0 && balance.Cmp(big.NewInt(l.maxWallet)) > 0 {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func Test_searchTransferLimits(t *testing.T) {
+	totalSupply := big.NewInt(1000000)
+	bigInt := func(v int64) *big.Int {
+		if v == 0 {
+			return nil
+		}
+		return big.NewInt(v)
+	}
+	tests := []struct {
+		name          string
+		token         limitedToken
+		noExempt      bool
+		wantMaxTx     int64
+		wantMaxWallet int64
+	}{
+		{name: "no limits", token: limitedToken{}},
+		{name: "max tx only", token: limitedToken{maxTx: 5000}, wantMaxTx: 5000},
+		{name: "max wallet only", token: limitedToken{maxWallet: 20000}, wantMaxWallet: 20000},
+		{name: "max tx below max wallet", token: limitedToken{maxTx: 5000, maxWallet: 20000}, wantMaxTx: 5000, wantMaxWallet: 20000},
+		{name: "max tx above max wallet", token: limitedToken{maxTx: 30000, maxWallet: 20000}, wantMaxTx: 30000, wantMaxWallet: 20000},
+		{name: "max tx equal to max wallet", token: limitedToken{maxTx: 20000, maxWallet: 20000}, wantMaxTx: 20000, wantMaxWallet: 20000},
+		{
+			name:          "max tx above max wallet without exempt receiver",
+			token:         limitedToken{maxTx: 30000, maxWallet: 20000},
+			noExempt:      true,
+			wantMaxWallet: 20000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toExempt := tt.token.transfer(true)
+			if tt.noExempt {
+				toExempt = tt.token.transfer(false)
+			}
+			got, err := searchTransferLimits(totalSupply, tt.token.transfer(false), toExempt)
+			require.NoError(t, err)
+			assert.Equal(t, &TransferLimits{MaxTxAmount: bigInt(tt.wantMaxTx), MaxWalletBalance: bigInt(tt.wantMaxWallet)}, got)
+		})
+	}
+
+	_, err := searchTransferLimits(totalSupply, func(*big.Int, *big.Int) (bool, error) { return false, nil }, nil)
+	assert.ErrorIs(t, err, ErrTransferNotSuccess)
+}
@@ -1 +1,290 @@
+// Package fetcher fetches the chain data the classifiers need: transactions, receipts, callTracer frames, prestateTracer
+// diffs, code and logs. Requests are retried on transient errors and fetched concurrently in batches. Responses are not
+// cached by the Fetcher, its client caches them if it is a pkg/cache client.
 package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// Kinds of fetched data, named in progress reports and errors.
+const (
+	KindTransaction  = "transaction"
+	KindReceipt      = "receipt"
+	KindCallFrame    = "callFrame"
+	KindPrestateDiff = "prestateDiff"
+	KindCode         = "code"
+)
+
+// Config configures a Fetcher.
+type Config struct {
+	// Workers is the number of requests of a batch sent concurrently
+	Workers int
+	jsonrpc.RetryConfig
+	// Logs configures the log fetches
+	Logs LogFetcherConfig
+	// OnProgress is called each time an item of a batch is fetched. If nil, progress is logged periodically.
+	OnProgress func(kind string, done, total int)
+}
+
+// DefaultConfig retries requests with jsonrpc.DefaultRetryConfig.
+var DefaultConfig = Config{
+	Workers:     8,
+	RetryConfig: jsonrpc.DefaultRetryConfig,
+	Logs:        DefaultLogFetcherConfig,
+}
+
+// Fetcher fetches chain data through a JSON-RPC client.
+type Fetcher struct {
+	client jsonrpc.Client
+	config Config
+}
+
+// NewFetcher rpcClient is either a *rpc.Client or a *jsonrpc.Pool to use several endpoints, wrapped by cache.Dial to
+// cache the responses that can not change.
+func NewFetcher(rpcClient jsonrpc.Client, config Config) *Fetcher {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	return &Fetcher{
+		client: rpcClient,
+		config: config,
+	}
+}
+
+// call sends a request, retrying on transient RPC errors. A null result is returned as ethereum.NotFound.
+func (f *Fetcher) call(ctx context.Context, method string, args ...interface{}) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := f.config.Retry(ctx, jsonrpc.IsRetryable, func() error {
+		return f.client.CallContext(ctx, &result, method, args...)
+	}, "method", method)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return nil, ethereum.NotFound
+	}
+	return result, nil
+}
+
+// get sends the request of the key of kind and decodes its response into result.
+func (f *Fetcher) get(ctx context.Context, kind, key string, result interface{}, method string, args ...interface{}) error {
+	encoded, err := f.call(ctx, method, args...)
+	if err != nil {
+		return fmt.Errorf("could not %s %s: %w", method, key, err)
+	}
+	if err := json.Unmarshal(encoded, result); err != nil {
+		return fmt.Errorf("could not decode %s %s: %w", kind, key, err)
+	}
+	return nil
+}
+
+// Transaction returns a transaction.
+func (f *Fetcher) Transaction(ctx context.Context, txHash common.Hash) (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	if err := f.get(ctx, KindTransaction, txHash.Hex(), tx, "eth_getTransactionByHash", txHash); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Receipt returns the receipt of a mined transaction.
+func (f *Fetcher) Receipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	receipt := new(types.Receipt)
+	if err := f.get(ctx, KindReceipt, txHash.Hex(), receipt, "eth_getTransactionReceipt", txHash); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// CallFrame returns the callTracer trace of a mined transaction.
+func (f *Fetcher) CallFrame(ctx context.Context, txHash common.Hash) (*jsonrpc.CallFrame, error) {
+	frame := new(jsonrpc.CallFrame)
+	tracer := &jsonrpc.DebugTraceCallTracerConfigParam{
+		Tracer: "callTracer",
+	}
+	if err := f.get(ctx, KindCallFrame, txHash.Hex(), frame, "debug_traceTransaction", txHash, tracer); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// PrestateDiff returns the prestateTracer diffMode trace of a mined transaction.
+func (f *Fetcher) PrestateDiff(ctx context.Context, txHash common.Hash) (*jsonrpc.PrestateTracerResult, error) {
+	diff := new(jsonrpc.PrestateTracerResult)
+	tracer := &jsonrpc.DebugTraceCallTracerConfigParam{
+		Tracer:       "prestateTracer",
+		TracerConfig: jsonrpc.TransferTracerConfigEncoded,
+	}
+	if err := f.get(ctx, KindPrestateDiff, txHash.Hex(), diff, "debug_traceTransaction", txHash, tracer); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// CodeAt returns the code of an address at a block, empty if it has no code.
+func (f *Fetcher) CodeAt(ctx context.Context, address common.Address, blockNumber uint64) ([]byte, error) {
+	var (
+		code hexutil.Bytes
+		key  = fmt.Sprintf("%s@%d", address.Hex(), blockNumber)
+	)
+	if err := f.get(ctx, KindCode, key, &code, "eth_getCode", address, hexutil.EncodeUint64(blockNumber)); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// fetchAll calls fetch for each hash with config.Workers workers. It stops at the first error.
+func fetchAll[T any](ctx context.Context, f *Fetcher, kind string, hashes []common.Hash, fetch func(context.Context, common.Hash) (T, error)) (map[common.Hash]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = make(map[common.Hash]T, len(hashes))
+		jobs    = make(chan common.Hash)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		first   error
+		done    int
+		total   = countUnique(hashes)
+		last    = time.Now()
+	)
+	for i := 0; i < f.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range jobs {
+				result, err := fetch(ctx, hash)
+				mu.Lock()
+				if err != nil {
+					if first == nil {
+						first = err
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				results[hash] = result
+				done++
+				if f.config.OnProgress != nil {
+					f.config.OnProgress(kind, done, total)
+				} else if time.Since(last) > 10*time.Second || done == total {
+					logger.Infow("fetch progress", "kind", kind, "done", done, "total", total)
+					last = time.Now()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	seen := make(map[common.Hash]struct{}, len(hashes))
+	for _, hash := range hashes {
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		select {
+		case jobs <- hash:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if first != nil {
+		return nil, first
+	}
+	return results, ctx.Err()
+}
+
+func countUnique(hashes []common.Hash) int {
+	unique := make(map[common.Hash]struct{}, len(hashes))
+	for _, hash := range hashes {
+		unique[hash] = struct{}{}
+	}
+	return len(unique)
+}
+
+// Transactions returns mined transactions, fetched concurrently.
+func (f *Fetcher) Transactions(ctx context.Context, txHashes []common.Hash) (map[common.Hash]*types.Transaction, error) {
+	return fetchAll(ctx, f, KindTransaction, txHashes, f.Transaction)
+}
+
+// Receipts returns the receipts of mined transactions, fetched concurrently.
+func (f *Fetcher) Receipts(ctx context.Context, txHashes []common.Hash) (map[common.Hash]*types.Receipt, error) {
+	return fetchAll(ctx, f, KindReceipt, txHashes, f.Receipt)
+}
+
+// CallFrames returns the callTracer traces of mined transactions, fetched concurrently.
+func (f *Fetcher) CallFrames(ctx context.Context, txHashes []common.Hash) (map[common.Hash]*jsonrpc.CallFrame, error) {
+	return fetchAll(ctx, f, KindCallFrame, txHashes, f.CallFrame)
+}
+
+// PrestateDiffs returns the prestateTracer diffMode traces of mined transactions, fetched concurrently.
+func (f *Fetcher) PrestateDiffs(ctx context.Context, txHashes []common.Hash) (map[common.Hash]*jsonrpc.PrestateTracerResult, error) {
+	return fetchAll(ctx, f, KindPrestateDiff, txHashes, f.PrestateDiff)
+}
+
+// Logs streams the logs of address matching topics, most recent first, see LogFetcher.Stream.
+func (f *Fetcher) Logs(ctx context.Context, address common.Address, topics [][]common.Hash) (<-chan LogBatch, <-chan error) {
+	return NewLogFetcher(f.logClient(), f.config.Logs, nil).Stream(ctx, address, topics)
+}
+
+// RecentLogs returns the most recent logs of address matching topics, until limit logs or its creation block, see
+// LogFetcher.Recent.
+func (f *Fetcher) RecentLogs(ctx context.Context, address common.Address, topics [][]common.Hash, limit int) ([]types.Log, error) {
+	return NewLogFetcher(f.logClient(), f.config.Logs, nil).Recent(ctx, address, topics, limit)
+}
+
+func (f *Fetcher) logClient() LogClient {
+	return rpcLogClient{f}
+}
+
+// rpcLogClient implements LogClient with the Fetcher: requests are retried.
+type rpcLogClient struct {
+	f *Fetcher
+}
+
+func (c rpcLogClient) BlockNumber(ctx context.Context) (uint64, error) {
+	encoded, err := c.f.call(ctx, "eth_blockNumber")
+	if err != nil {
+		return 0, err
+	}
+	var result hexutil.Uint64
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+func (c rpcLogClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.f.CodeAt(ctx, account, blockNumber.Uint64())
+}
+
+func (c rpcLogClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	arg := map[string]interface{}{
+		"address":   q.Addresses,
+		"topics":    q.Topics,
+		"fromBlock": hexutil.EncodeBig(q.FromBlock),
+		"toBlock":   hexutil.EncodeBig(q.ToBlock),
+	}
+	encoded, err := c.f.call(ctx, "eth_getLogs", arg)
+	if err != nil {
+		return nil, err
+	}
+	var logs []types.Log
+	if err := json.Unmarshal(encoded, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
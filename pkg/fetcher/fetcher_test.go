@@ -0,0 +1,153 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// fakeClient serves signed txs, mined at block 100, and their receipts. The first request of each tx
+// fails with a transient error.
+type fakeClient struct {
+	mu       sync.Mutex
+	txs      map[common.Hash]*types.Transaction
+	requests map[string]int
+	failed   map[common.Hash]bool
+}
+
+func newFakeClient(t *testing.T, n int) *fakeClient {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	c := &fakeClient{
+		txs:      make(map[common.Hash]*types.Transaction),
+		requests: make(map[string]int),
+		failed:   make(map[common.Hash]bool),
+	}
+	for i := 0; i < n; i++ {
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &token, Gas: 21000, GasPrice: big.NewInt(1)}), signer, key)
+		require.NoError(t, err)
+		c.txs[tx.Hash()] = tx
+	}
+	return c
+}
+
+func (c *fakeClient) hashes() []common.Hash {
+	var hashes []common.Hash
+	for hash := range c.txs {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+func (c *fakeClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests[method]++
+	var response interface{}
+	switch method {
+	case "eth_getTransactionByHash", "eth_getTransactionReceipt":
+		hash := args[0].(common.Hash)
+		if !c.failed[hash] {
+			c.failed[hash] = true
+			return rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}
+		}
+		tx, ok := c.txs[hash]
+		if !ok {
+			response = nil
+		} else if method == "eth_getTransactionByHash" {
+			encoded, err := json.Marshal(tx)
+			if err != nil {
+				return err
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(encoded, &fields); err != nil {
+				return err
+			}
+			fields["blockNumber"] = hexutil.Uint64(100)
+			response = fields
+		} else {
+			response = &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: hash, Logs: []*types.Log{}}
+		}
+	case "eth_blockNumber":
+		response = hexutil.Uint64(100)
+	case "eth_getCode":
+		response = hexutil.Bytes{0x60}
+	case "eth_getLogs":
+		response = []types.Log{{Address: token, BlockNumber: 100, Topics: []common.Hash{}}}
+	default:
+		return errors.New("not supported")
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, result)
+}
+
+func (c *fakeClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return errors.New("not supported")
+}
+
+var testConfig = Config{
+	Workers: 4,
+	RetryConfig: jsonrpc.RetryConfig{
+		MaxRetries: 1,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	},
+	Logs: testLogFetcherConfig,
+}
+
+func TestFetcherTransactions(t *testing.T) {
+	var (
+		client  = newFakeClient(t, 10)
+		hashes  = client.hashes()
+		fetcher = NewFetcher(client, testConfig)
+	)
+
+	txs, err := fetcher.Transactions(context.Background(), append(hashes, hashes[0]))
+	require.NoError(t, err)
+	require.Len(t, txs, 10)
+	for _, hash := range hashes {
+		assert.Equal(t, hash, txs[hash].Hash())
+	}
+	// each tx is retried once
+	assert.Equal(t, 20, client.requests["eth_getTransactionByHash"])
+
+	receipts, err := fetcher.Receipts(context.Background(), hashes)
+	require.NoError(t, err)
+	assert.Equal(t, hashes[0], receipts[hashes[0]].TxHash)
+
+	_, err = fetcher.Transaction(context.Background(), common.HexToHash("0x1"))
+	assert.Error(t, err)
+	_, err = fetcher.Transaction(context.Background(), common.HexToHash("0x1"))
+	assert.ErrorIs(t, err, ethereum.NotFound)
+}
+
+func TestFetcherLogs(t *testing.T) {
+	fetcher := NewFetcher(newFakeClient(t, 0), testConfig)
+	batches, errc := fetcher.Logs(context.Background(), token, nil)
+	var logs []types.Log
+	for batch := range batches {
+		logs = append(logs, batch.Logs...)
+	}
+	require.NoError(t, <-errc)
+	require.NotEmpty(t, logs)
+	assert.Equal(t, token, logs[0].Address)
+}
@@ -0,0 +1,435 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// LogClient is the part of *ethclient.Client the log fetcher needs.
+type LogClient interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// LogFetcherConfig configures the block windows of eth_getLogs requests and their retries.
+type LogFetcherConfig struct {
+	// InitialWindow is the number of blocks of the first request
+	InitialWindow uint64
+	// MaxWindow is the number of blocks a window can grow to after requests returning few logs
+	MaxWindow uint64
+	// GrowBelow is the number of logs under which the window doubles
+	GrowBelow int
+	jsonrpc.RetryConfig
+}
+
+var DefaultLogFetcherConfig = LogFetcherConfig{
+	InitialWindow: 10000,
+	MaxWindow:     100000,
+	GrowBelow:     1000,
+	RetryConfig: jsonrpc.RetryConfig{
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+	},
+}
+
+// ErrNoCode is returned when the address has no code at the head block.
+var ErrNoCode = errors.New("address has no code")
+
+// LogBatch is the logs of a window of blocks, in the order they were emitted.
+type LogBatch struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Logs      []types.Log
+}
+
+// Checkpoint is the progress of the log fetch of an address: the logs of blocks [Low, High] were all streamed.
+type Checkpoint struct {
+	Address  common.Address `json:"address"`
+	Creation uint64         `json:"creation"`
+	Low      uint64         `json:"low"`
+	High     uint64         `json:"high"`
+}
+
+// done returns true if the logs down to the creation block were streamed.
+func (c *Checkpoint) done() bool {
+	return c.Low <= c.Creation
+}
+
+// CheckpointStore persists checkpoints so an interrupted fetch resumes where it stopped.
+type CheckpointStore interface {
+	// Load returns nil if there is no checkpoint for address
+	Load(address common.Address) (*Checkpoint, error)
+	Save(checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory, to resume fetches within a process.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[common.Address]Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[common.Address]Checkpoint)}
+}
+
+func (s *MemoryCheckpointStore) Load(address common.Address) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, ok := s.checkpoints[address]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (s *MemoryCheckpointStore) Save(checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[checkpoint.Address] = *checkpoint
+	return nil
+}
+
+// FileCheckpointStore keeps each checkpoint in a JSON file of dir named after the address.
+type FileCheckpointStore struct {
+	dir string
+}
+
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create checkpoint dir: %w", err)
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+func (s *FileCheckpointStore) path(address common.Address) string {
+	return filepath.Join(s.dir, strings.ToLower(address.Hex())+".json")
+}
+
+func (s *FileCheckpointStore) Load(address common.Address) (*Checkpoint, error) {
+	encoded, err := os.ReadFile(s.path(address))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(Checkpoint)
+	if err := json.Unmarshal(encoded, checkpoint); err != nil {
+		return nil, fmt.Errorf("could not decode checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (s *FileCheckpointStore) Save(checkpoint *Checkpoint) error {
+	encoded, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	// write then rename so an interrupted save does not corrupt the checkpoint
+	tmp := s.path(checkpoint.Address) + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(checkpoint.Address))
+}
+
+// LogFetcher fetches the logs of an address from its creation block with eth_getLogs. The block window of each request
+// shrinks when the provider rejects it for returning too many results and grows back when requests return few logs.
+type LogFetcher struct {
+	client      LogClient
+	config      LogFetcherConfig
+	checkpoints CheckpointStore
+}
+
+// NewLogFetcher checkpoints may be nil, the fetch then always starts over.
+func NewLogFetcher(client LogClient, config LogFetcherConfig, checkpoints CheckpointStore) *LogFetcher {
+	return &LogFetcher{
+		client:      client,
+		config:      config,
+		checkpoints: checkpoints,
+	}
+}
+
+// Stream fetches the logs of address matching topics and sends them by window. Without a checkpoint, windows go
+// backwards from the head block to the creation block of address, so the most recent logs come first. With a
+// checkpoint, the blocks mined since it are fetched first, then the fetch goes on backwards from where it stopped.
+// Logs of a window are checkpointed once the window is received, a resumed fetch does not send them again.
+// The batches channel is closed at the end of the fetch, the error channel then receives the error that stopped it,
+// or nil. Cancel ctx to stop early.
+func (f *LogFetcher) Stream(ctx context.Context, address common.Address, topics [][]common.Hash) (<-chan LogBatch, <-chan error) {
+	var (
+		batches = make(chan LogBatch)
+		errc    = make(chan error, 1)
+	)
+	go func() {
+		defer close(batches)
+		errc <- f.stream(ctx, address, topics, batches)
+	}()
+	return batches, errc
+}
+
+func (f *LogFetcher) stream(ctx context.Context, address common.Address, topics [][]common.Hash, batches chan<- LogBatch) error {
+	head, err := f.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get block number: %w", err)
+	}
+	checkpoint, err := f.loadCheckpoint(address)
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil {
+		creation, err := f.CreationBlock(ctx, address, head)
+		if err != nil {
+			return err
+		}
+		// nothing is fetched yet: the empty range above head
+		checkpoint = &Checkpoint{Address: address, Creation: creation, Low: head + 1, High: head}
+	}
+
+	send := func(batch LogBatch) error {
+		select {
+		case batches <- batch:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	window := f.config.InitialWindow
+
+	// blocks mined since the checkpoint, forwards
+	for checkpoint.High < head {
+		from, to := checkpoint.High+1, min(checkpoint.High+window, head)
+		batch, next, err := f.fetchWindow(ctx, address, topics, from, to, window, true)
+		if err != nil {
+			return err
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		window = next
+		checkpoint.High = batch.ToBlock
+		if err := f.saveCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+	// blocks before the checkpoint, backwards
+	for !checkpoint.done() {
+		to := checkpoint.Low - 1
+		from := checkpoint.Creation
+		if to-from+1 > window {
+			from = to - window + 1
+		}
+		batch, next, err := f.fetchWindow(ctx, address, topics, from, to, window, false)
+		if err != nil {
+			return err
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		window = next
+		checkpoint.Low = batch.FromBlock
+		if err := f.saveCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *LogFetcher) loadCheckpoint(address common.Address) (*Checkpoint, error) {
+	if f.checkpoints == nil {
+		return nil, nil
+	}
+	checkpoint, err := f.checkpoints.Load(address)
+	if err != nil {
+		return nil, fmt.Errorf("could not load checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (f *LogFetcher) saveCheckpoint(checkpoint *Checkpoint) error {
+	if f.checkpoints == nil {
+		return nil
+	}
+	if err := f.checkpoints.Save(checkpoint); err != nil {
+		return fmt.Errorf("could not save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// fetchWindow fetches the logs of [from, to], or of the part of the range next to the checkpoint if it has too many
+// results: the lower part when going forwards, the upper part when going backwards. The returned batch says which
+// blocks were fetched, next is the window of the following request.
+func (f *LogFetcher) fetchWindow(ctx context.Context, address common.Address, topics [][]common.Hash, from, to, window uint64, forwards bool) (LogBatch, uint64, error) {
+	for {
+		logs, err := f.filterLogs(ctx, ethereum.FilterQuery{
+			Addresses: []common.Address{address},
+			Topics:    topics,
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+		})
+		if err == nil {
+			if len(logs) < f.config.GrowBelow {
+				window = min(window*2, f.config.MaxWindow)
+			}
+			return LogBatch{FromBlock: from, ToBlock: to, Logs: logs}, window, nil
+		}
+		if !IsTooManyResults(err) || to == from {
+			return LogBatch{}, 0, fmt.Errorf("could not get logs of blocks [%d, %d]: %w", from, to, err)
+		}
+		window = max((to-from+1)/2, 1)
+		logger.Debugw("too many results, shrinking window", "from", from, "to", to, "window", window, "error", err)
+		if forwards {
+			to = from + window - 1
+		} else {
+			from = to - window + 1
+		}
+	}
+}
+
+// filterLogs sends eth_getLogs, again after a backoff if it fails with a transient error.
+func (f *LogFetcher) filterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := f.config.Retry(ctx, func(err error) bool {
+		// a smaller window is needed instead
+		return jsonrpc.IsRetryable(err) && !IsTooManyResults(err)
+	}, func() (err error) {
+		logs, err = f.client.FilterLogs(ctx, query)
+		return err
+	}, "method", "eth_getLogs", "from", query.FromBlock, "to", query.ToBlock)
+	return logs, err
+}
+
+// CreationBlock returns the first block at which address has code, by binary search over eth_getCode up to head.
+// Transient errors are retried. It needs an archive node: blocks whose state was pruned are taken as before the
+// creation, so on a full node the creation block is the first block with state and code.
+func (f *LogFetcher) CreationBlock(ctx context.Context, address common.Address, head uint64) (uint64, error) {
+	hasCode := func(block uint64) (ok bool, err error) {
+		err = f.config.Retry(ctx, jsonrpc.IsRetryable, func() error {
+			code, err := f.client.CodeAt(ctx, address, new(big.Int).SetUint64(block))
+			ok = len(code) > 0
+			return err
+		}, "method", "eth_getCode", "block", block)
+		return ok, err
+	}
+	ok, err := hasCode(head)
+	if err != nil {
+		return 0, fmt.Errorf("could not get code: %w", err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w at block %d", ErrNoCode, head)
+	}
+	low, high := uint64(0), head
+	for low < high {
+		mid := low + (high-low)/2
+		ok, err := hasCode(mid)
+		switch {
+		case IsMissingState(err):
+			logger.Debugw("no state at block, searching the creation block above", "block", mid, "error", err)
+			low = mid + 1
+		case err != nil:
+			return 0, fmt.Errorf("could not get code at block %d: %w", mid, err)
+		case ok:
+			high = mid
+		default:
+			low = mid + 1
+		}
+	}
+	return low, nil
+}
+
+// Recent returns the most recent logs of address matching topics, until limit logs or its creation block. A fetch
+// stopped by a transient error is resumed from a checkpoint of this call, so the logs already received are kept and
+// their blocks are not fetched again. The checkpoint is dropped at the end of the call, the next call fetches the
+// most recent logs again.
+func (f *LogFetcher) Recent(ctx context.Context, address common.Address, topics [][]common.Hash, limit int) ([]types.Log, error) {
+	var (
+		resumable = NewLogFetcher(f.client, f.config, NewMemoryCheckpointStore())
+		logs      []types.Log
+	)
+	err := f.config.Retry(ctx, jsonrpc.IsRetryable, func() error {
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		batches, errc := resumable.Stream(streamCtx, address, topics)
+		for batch := range batches {
+			logs = append(logs, batch.Logs...)
+			if limit > 0 && len(logs) >= limit {
+				// the stream is canceled once there are enough logs
+				cancel()
+				<-errc
+				return nil
+			}
+		}
+		return <-errc
+	}, "method", "eth_getLogs", "address", address)
+	return logs, err
+}
+
+// missingStateMessages are parts of the errors nodes return when the state of a block was pruned.
+var missingStateMessages = []string{
+	"missing trie node",
+	"historical state",
+	"state is not available",
+	"state not available",
+	"pruned",
+}
+
+// IsMissingState returns true if err says the node does not have the state of the requested block.
+func IsMissingState(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range missingStateMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// tooManyResultsMessages are parts of the errors providers return when a eth_getLogs range has too many results or
+// is too wide.
+var tooManyResultsMessages = []string{
+	"more than",
+	"too many",
+	"response size",
+	"block range",
+	"range is too large",
+	"limit exceeded",
+	"query timeout",
+}
+
+// IsTooManyResults returns true if err says a eth_getLogs request should be sent again with a smaller range.
+func IsTooManyResults(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range tooManyResultsMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+var token = common.HexToAddress("0x5732046a883704404f284ce41ffadd5b007fd668")
+
+// fakeLogClient is a chain whose token is created at block creation and emits a log at each block of logBlocks.
+// Like most providers, it rejects eth_getLogs requests with more than maxResults results.
+type fakeLogClient struct {
+	head       uint64
+	creation   uint64
+	logBlocks  []uint64
+	maxResults int
+	// pruned is the number of old blocks whose state is missing
+	pruned uint64
+	// codeFailures and logFailures are the errors of the next eth_getCode and eth_getLogs requests, nil succeeds
+	codeFailures []error
+	logFailures  []error
+	requests     []ethereum.FilterQuery
+}
+
+func fail(failures *[]error) error {
+	if len(*failures) == 0 {
+		return nil
+	}
+	err := (*failures)[0]
+	*failures = (*failures)[1:]
+	return err
+}
+
+func (c *fakeLogClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeLogClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if err := fail(&c.codeFailures); err != nil {
+		return nil, err
+	}
+	if blockNumber.Uint64() < c.pruned {
+		return nil, errors.New("missing trie node 4f3e2a (path ) state 0x4f3e2a is not available")
+	}
+	if blockNumber.Uint64() < c.creation {
+		return nil, nil
+	}
+	return []byte{0x60}, nil
+}
+
+func (c *fakeLogClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.requests = append(c.requests, q)
+	if err := fail(&c.logFailures); err != nil {
+		return nil, err
+	}
+	var logs []types.Log
+	for _, block := range c.logBlocks {
+		if block >= q.FromBlock.Uint64() && block <= q.ToBlock.Uint64() {
+			logs = append(logs, types.Log{Address: token, BlockNumber: block})
+		}
+	}
+	if len(logs) > c.maxResults {
+		return nil, fmt.Errorf("query returned more than %d results", c.maxResults)
+	}
+	return logs, nil
+}
+
+var testLogFetcherConfig = LogFetcherConfig{
+	InitialWindow: 100,
+	MaxWindow:     1000,
+	GrowBelow:     2,
+	RetryConfig: jsonrpc.RetryConfig{
+		MaxRetries: 2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	},
+}
+
+var unavailable = rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}
+
+func collect(t *testing.T, fetcher *LogFetcher, limit int) ([]uint64, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var blocks []uint64
+	batches, errc := fetcher.Stream(ctx, token, nil)
+	for batch := range batches {
+		require.LessOrEqual(t, batch.FromBlock, batch.ToBlock)
+		for _, l := range batch.Logs {
+			blocks = append(blocks, l.BlockNumber)
+		}
+		if limit > 0 && len(blocks) >= limit {
+			cancel()
+			break
+		}
+	}
+	return blocks, <-errc
+}
+
+func TestLogFetcherStream(t *testing.T) {
+	client := &fakeLogClient{
+		head:       1000,
+		creation:   400,
+		logBlocks:  []uint64{400, 401, 402, 403, 404, 405, 600, 999, 1000},
+		maxResults: 2,
+	}
+	blocks, err := collect(t, NewLogFetcher(client, testLogFetcherConfig, nil), 0)
+	require.NoError(t, err)
+	// most recent logs first, down to the creation block
+	assert.ElementsMatch(t, client.logBlocks, blocks)
+	assert.Equal(t, []uint64{999, 1000, 600}, blocks[:3])
+	for _, q := range client.requests {
+		assert.GreaterOrEqual(t, q.FromBlock.Uint64(), client.creation)
+	}
+}
+
+func TestLogFetcherResume(t *testing.T) {
+	client := &fakeLogClient{
+		head:       1000,
+		creation:   400,
+		logBlocks:  []uint64{400, 450, 600, 999},
+		maxResults: 10,
+	}
+	checkpoints := NewMemoryCheckpointStore()
+	fetcher := NewLogFetcher(client, LogFetcherConfig{InitialWindow: 100, MaxWindow: 100}, checkpoints)
+
+	// stop after the first log
+	blocks, err := collect(t, fetcher, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []uint64{999}, blocks)
+
+	// blocks mined since the checkpoint come first, then the older ones
+	client.head = 1200
+	client.logBlocks = append(client.logBlocks, 1100)
+	blocks, err = collect(t, fetcher, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1100, 600, 450, 400}, blocks)
+
+	checkpoint, err := checkpoints.Load(token)
+	require.NoError(t, err)
+	assert.Equal(t, &Checkpoint{Address: token, Creation: 400, Low: 400, High: 1200}, checkpoint)
+
+	// nothing new
+	blocks, err = collect(t, fetcher, 0)
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+}
+
+func TestLogFetcherErrors(t *testing.T) {
+	// no code at head
+	_, err := collect(t, NewLogFetcher(&fakeLogClient{head: 100, creation: 200}, testLogFetcherConfig, nil), 0)
+	assert.ErrorIs(t, err, ErrNoCode)
+
+	// a single block with too many results can not be split
+	client := &fakeLogClient{head: 100, logBlocks: []uint64{50, 50, 50}, maxResults: 2}
+	_, err = collect(t, NewLogFetcher(client, testLogFetcherConfig, nil), 0)
+	assert.ErrorContains(t, err, "blocks [50, 50]")
+}
+
+func TestCreationBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *fakeLogClient
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name:   "archive node",
+			client: &fakeLogClient{head: 1000, creation: 400},
+			want:   400,
+		},
+		{
+			name:   "transient errors",
+			client: &fakeLogClient{head: 1000, creation: 400, codeFailures: []error{unavailable, unavailable}},
+			want:   400,
+		},
+		{
+			name:   "pruned state before the creation",
+			client: &fakeLogClient{head: 1000, creation: 400, pruned: 300},
+			want:   400,
+		},
+		{
+			name:   "pruned state after the creation",
+			client: &fakeLogClient{head: 1000, creation: 400, pruned: 700},
+			want:   700,
+		},
+		{
+			name:    "other error",
+			client:  &fakeLogClient{head: 1000, creation: 400, codeFailures: []error{nil, errors.New("header not found")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLogFetcher(tt.client, testLogFetcherConfig, nil).CreationBlock(context.Background(), token, tt.client.head)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLogFetcherRecent(t *testing.T) {
+	client := &fakeLogClient{
+		head:       1000,
+		creation:   400,
+		logBlocks:  []uint64{400, 450, 600, 999},
+		maxResults: 10,
+	}
+	config := testLogFetcherConfig
+	config.MaxWindow, config.GrowBelow = 100, 0
+	fetcher := NewLogFetcher(client, config, nil)
+
+	logs, err := fetcher.Recent(context.Background(), token, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{999, 600}, logBlocks(logs))
+
+	// a later call starts from the head again
+	logs, err = fetcher.Recent(context.Background(), token, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{999, 600, 450, 400}, logBlocks(logs))
+
+	// the second window fails past its retries, the fetch resumes without requesting the first window again
+	client.requests = nil
+	client.logFailures = []error{nil, unavailable, unavailable, unavailable}
+	logs, err = fetcher.Recent(context.Background(), token, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{999, 600, 450, 400}, logBlocks(logs))
+	var firstWindow int
+	for _, q := range client.requests {
+		if q.ToBlock.Uint64() == 1000 {
+			firstWindow++
+		}
+	}
+	assert.Equal(t, 1, firstWindow)
+}
+
+func logBlocks(logs []types.Log) []uint64 {
+	var blocks []uint64
+	for _, l := range logs {
+		blocks = append(blocks, l.BlockNumber)
+	}
+	return blocks
+}
+
+func TestIsMissingState(t *testing.T) {
+	assert.True(t, IsMissingState(errors.New("missing trie node 4f3e2a (path ) <nil>")))
+	assert.True(t, IsMissingState(errors.New("historical state 0x4f3e2a is not available")))
+	assert.False(t, IsMissingState(errors.New("header not found")))
+	assert.False(t, IsMissingState(nil))
+}
+
+func TestIsTooManyResults(t *testing.T) {
+	assert.True(t, IsTooManyResults(errors.New("query returned more than 10000 results")))
+	assert.True(t, IsTooManyResults(errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range")))
+	assert.False(t, IsTooManyResults(errors.New("header not found")))
+	assert.False(t, IsTooManyResults(nil))
+}
@@ -0,0 +1,1087 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        (unknown)
+// source: classifier.proto
+
+package classifierpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Verdict int32
+
+const (
+	Verdict_VERDICT_UNSPECIFIED         Verdict = 0
+	Verdict_VERDICT_NOT_ERC20           Verdict = 1
+	Verdict_VERDICT_NOT_FEE_ON_TRANSFER Verdict = 2
+	Verdict_VERDICT_FEE_ON_TRANSFER     Verdict = 3
+	// VERDICT_UNDECIDED is returned when too few transfers could be simulated or found, error tells why
+	Verdict_VERDICT_UNDECIDED Verdict = 4
+)
+
+// Enum value maps for Verdict.
+var (
+	Verdict_name = map[int32]string{
+		0: "VERDICT_UNSPECIFIED",
+		1: "VERDICT_NOT_ERC20",
+		2: "VERDICT_NOT_FEE_ON_TRANSFER",
+		3: "VERDICT_FEE_ON_TRANSFER",
+		4: "VERDICT_UNDECIDED",
+	}
+	Verdict_value = map[string]int32{
+		"VERDICT_UNSPECIFIED":         0,
+		"VERDICT_NOT_ERC20":           1,
+		"VERDICT_NOT_FEE_ON_TRANSFER": 2,
+		"VERDICT_FEE_ON_TRANSFER":     3,
+		"VERDICT_UNDECIDED":           4,
+	}
+)
+
+func (x Verdict) Enum() *Verdict {
+	p := new(Verdict)
+	*p = x
+	return p
+}
+
+func (x Verdict) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Verdict) Descriptor() protoreflect.EnumDescriptor {
+	return file_classifier_proto_enumTypes[0].Descriptor()
+}
+
+func (Verdict) Type() protoreflect.EnumType {
+	return &file_classifier_proto_enumTypes[0]
+}
+
+func (x Verdict) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Verdict.Descriptor instead.
+func (Verdict) EnumDescriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{0}
+}
+
+// TransferScenario is a transfer() or transferFrom() call of a token to simulate.
+type TransferScenario struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MsgSender      string `protobuf:"bytes,1,opt,name=msg_sender,json=msgSender,proto3" json:"msg_sender,omitempty"`
+	IsTransferFrom bool   `protobuf:"varint,2,opt,name=is_transfer_from,json=isTransferFrom,proto3" json:"is_transfer_from,omitempty"`
+	From           string `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To             string `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	Amount         string `protobuf:"bytes,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	// block_number is the block whose state the transfer is simulated on
+	// the latest block when omitted
+	BlockNumber uint64 `protobuf:"varint,6,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	// gas_price is for legacy txs, gas_fee_cap and gas_tip_cap for EIP-1559 txs. All are optional.
+	GasPrice  string `protobuf:"bytes,7,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	GasFeeCap string `protobuf:"bytes,8,opt,name=gas_fee_cap,json=gasFeeCap,proto3" json:"gas_fee_cap,omitempty"`
+	GasTipCap string `protobuf:"bytes,9,opt,name=gas_tip_cap,json=gasTipCap,proto3" json:"gas_tip_cap,omitempty"`
+}
+
+func (x *TransferScenario) Reset() {
+	*x = TransferScenario{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransferScenario) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferScenario) ProtoMessage() {}
+
+func (x *TransferScenario) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferScenario.ProtoReflect.Descriptor instead.
+func (*TransferScenario) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TransferScenario) GetMsgSender() string {
+	if x != nil {
+		return x.MsgSender
+	}
+	return ""
+}
+
+func (x *TransferScenario) GetIsTransferFrom() bool {
+	if x != nil {
+		return x.IsTransferFrom
+	}
+	return false
+}
+
+func (x *TransferScenario) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *TransferScenario) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *TransferScenario) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *TransferScenario) GetBlockNumber() uint64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *TransferScenario) GetGasPrice() string {
+	if x != nil {
+		return x.GasPrice
+	}
+	return ""
+}
+
+func (x *TransferScenario) GetGasFeeCap() string {
+	if x != nil {
+		return x.GasFeeCap
+	}
+	return ""
+}
+
+func (x *TransferScenario) GetGasTipCap() string {
+	if x != nil {
+		return x.GasTipCap
+	}
+	return ""
+}
+
+// FeeOnTransferResult is the classification of a token from its Transfer events.
+type FeeOnTransferResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsFeeOnTransfer bool `protobuf:"varint,1,opt,name=is_fee_on_transfer,json=isFeeOnTransfer,proto3" json:"is_fee_on_transfer,omitempty"`
+	// fee_receiver is the address receiving the fee, the zero address if the fee is burnt
+	FeeReceiver string `protobuf:"bytes,2,opt,name=fee_receiver,json=feeReceiver,proto3" json:"fee_receiver,omitempty"`
+	// coefficients of the linear fee formula, fee = coefficients[0] * amount + coefficients[1]
+	Coefficients []float64 `protobuf:"fixed64,3,rep,packed,name=coefficients,proto3" json:"coefficients,omitempty"`
+	Formula      string    `protobuf:"bytes,4,opt,name=formula,proto3" json:"formula,omitempty"`
+	// override is set if the token is overridden, the token was then not classified
+	Override *Override `protobuf:"bytes,5,opt,name=override,proto3" json:"override,omitempty"`
+}
+
+func (x *FeeOnTransferResult) Reset() {
+	*x = FeeOnTransferResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FeeOnTransferResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeeOnTransferResult) ProtoMessage() {}
+
+func (x *FeeOnTransferResult) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeeOnTransferResult.ProtoReflect.Descriptor instead.
+func (*FeeOnTransferResult) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FeeOnTransferResult) GetIsFeeOnTransfer() bool {
+	if x != nil {
+		return x.IsFeeOnTransfer
+	}
+	return false
+}
+
+func (x *FeeOnTransferResult) GetFeeReceiver() string {
+	if x != nil {
+		return x.FeeReceiver
+	}
+	return ""
+}
+
+func (x *FeeOnTransferResult) GetCoefficients() []float64 {
+	if x != nil {
+		return x.Coefficients
+	}
+	return nil
+}
+
+func (x *FeeOnTransferResult) GetFormula() string {
+	if x != nil {
+		return x.Formula
+	}
+	return ""
+}
+
+func (x *FeeOnTransferResult) GetOverride() *Override {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+type IsErc20Request struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain string `protobuf:"bytes,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Token string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *IsErc20Request) Reset() {
+	*x = IsErc20Request{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IsErc20Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsErc20Request) ProtoMessage() {}
+
+func (x *IsErc20Request) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsErc20Request.ProtoReflect.Descriptor instead.
+func (*IsErc20Request) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *IsErc20Request) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *IsErc20Request) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type IsErc20Response struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsErc20 bool `protobuf:"varint,1,opt,name=is_erc20,json=isErc20,proto3" json:"is_erc20,omitempty"`
+	// override is set if the token is overridden, is_erc20 is still told by the code
+	Override *Override `protobuf:"bytes,2,opt,name=override,proto3" json:"override,omitempty"`
+}
+
+func (x *IsErc20Response) Reset() {
+	*x = IsErc20Response{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IsErc20Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsErc20Response) ProtoMessage() {}
+
+func (x *IsErc20Response) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsErc20Response.ProtoReflect.Descriptor instead.
+func (*IsErc20Response) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IsErc20Response) GetIsErc20() bool {
+	if x != nil {
+		return x.IsErc20
+	}
+	return false
+}
+
+func (x *IsErc20Response) GetOverride() *Override {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+// Override is a verdict pinned by a human, it replaces the classification of a token until it expires.
+type Override struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsFeeOnTransfer bool   `protobuf:"varint,1,opt,name=is_fee_on_transfer,json=isFeeOnTransfer,proto3" json:"is_fee_on_transfer,omitempty"`
+	FeeBps          uint32 `protobuf:"varint,2,opt,name=fee_bps,json=feeBps,proto3" json:"fee_bps,omitempty"`
+	Reason          string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Author          string `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	// expires_at is a unix timestamp in seconds, 0 if the override never expires
+	ExpiresAt int64 `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+}
+
+func (x *Override) Reset() {
+	*x = Override{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Override) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Override) ProtoMessage() {}
+
+func (x *Override) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Override.ProtoReflect.Descriptor instead.
+func (*Override) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Override) GetIsFeeOnTransfer() bool {
+	if x != nil {
+		return x.IsFeeOnTransfer
+	}
+	return false
+}
+
+func (x *Override) GetFeeBps() uint32 {
+	if x != nil {
+		return x.FeeBps
+	}
+	return 0
+}
+
+func (x *Override) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Override) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Override) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type IsFeeOnTransferRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain string `protobuf:"bytes,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Token string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *IsFeeOnTransferRequest) Reset() {
+	*x = IsFeeOnTransferRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IsFeeOnTransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsFeeOnTransferRequest) ProtoMessage() {}
+
+func (x *IsFeeOnTransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsFeeOnTransferRequest.ProtoReflect.Descriptor instead.
+func (*IsFeeOnTransferRequest) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IsFeeOnTransferRequest) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *IsFeeOnTransferRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ClassifyNewTokenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain     string              `protobuf:"bytes,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Token     string              `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Scenarios []*TransferScenario `protobuf:"bytes,3,rep,name=scenarios,proto3" json:"scenarios,omitempty"`
+}
+
+func (x *ClassifyNewTokenRequest) Reset() {
+	*x = ClassifyNewTokenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClassifyNewTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClassifyNewTokenRequest) ProtoMessage() {}
+
+func (x *ClassifyNewTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClassifyNewTokenRequest.ProtoReflect.Descriptor instead.
+func (*ClassifyNewTokenRequest) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ClassifyNewTokenRequest) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *ClassifyNewTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ClassifyNewTokenRequest) GetScenarios() []*TransferScenario {
+	if x != nil {
+		return x.Scenarios
+	}
+	return nil
+}
+
+type ClassifyBatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain string `protobuf:"bytes,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Token string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	// scenarios to simulate, for new tokens. Without scenarios, the token is classified from its Transfer events.
+	Scenarios []*TransferScenario `protobuf:"bytes,3,rep,name=scenarios,proto3" json:"scenarios,omitempty"`
+}
+
+func (x *ClassifyBatchRequest) Reset() {
+	*x = ClassifyBatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClassifyBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClassifyBatchRequest) ProtoMessage() {}
+
+func (x *ClassifyBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClassifyBatchRequest.ProtoReflect.Descriptor instead.
+func (*ClassifyBatchRequest) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ClassifyBatchRequest) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *ClassifyBatchRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ClassifyBatchRequest) GetScenarios() []*TransferScenario {
+	if x != nil {
+		return x.Scenarios
+	}
+	return nil
+}
+
+// TokenResult is the classification of a token by ClassifyNewToken or ClassifyBatch.
+type TokenResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chain   string  `protobuf:"bytes,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	Token   string  `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Verdict Verdict `protobuf:"varint,3,opt,name=verdict,proto3,enum=erc20class.v1.Verdict" json:"verdict,omitempty"`
+	// result is set when the token was classified from its Transfer events
+	Result *FeeOnTransferResult `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	// the number of simulated scenarios where the receiver got the amount sent, got less, or that failed
+	NumEqual  uint32 `protobuf:"varint,5,opt,name=num_equal,json=numEqual,proto3" json:"num_equal,omitempty"`
+	NumLess   uint32 `protobuf:"varint,6,opt,name=num_less,json=numLess,proto3" json:"num_less,omitempty"`
+	NumFailed uint32 `protobuf:"varint,7,opt,name=num_failed,json=numFailed,proto3" json:"num_failed,omitempty"`
+	Error     string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	// override is set if the token is overridden, the token was then not classified
+	Override *Override `protobuf:"bytes,9,opt,name=override,proto3" json:"override,omitempty"`
+}
+
+func (x *TokenResult) Reset() {
+	*x = TokenResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_classifier_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TokenResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenResult) ProtoMessage() {}
+
+func (x *TokenResult) ProtoReflect() protoreflect.Message {
+	mi := &file_classifier_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenResult.ProtoReflect.Descriptor instead.
+func (*TokenResult) Descriptor() ([]byte, []int) {
+	return file_classifier_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TokenResult) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *TokenResult) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *TokenResult) GetVerdict() Verdict {
+	if x != nil {
+		return x.Verdict
+	}
+	return Verdict_VERDICT_UNSPECIFIED
+}
+
+func (x *TokenResult) GetResult() *FeeOnTransferResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *TokenResult) GetNumEqual() uint32 {
+	if x != nil {
+		return x.NumEqual
+	}
+	return 0
+}
+
+func (x *TokenResult) GetNumLess() uint32 {
+	if x != nil {
+		return x.NumLess
+	}
+	return 0
+}
+
+func (x *TokenResult) GetNumFailed() uint32 {
+	if x != nil {
+		return x.NumFailed
+	}
+	return 0
+}
+
+func (x *TokenResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TokenResult) GetOverride() *Override {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+var File_classifier_proto protoreflect.FileDescriptor
+
+var file_classifier_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x69, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x0d, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76,
+	0x31, 0x22, 0x97, 0x02, 0x0a, 0x10, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x53, 0x63,
+	0x65, 0x6e, 0x61, 0x72, 0x69, 0x6f, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x73, 0x67, 0x5f, 0x73, 0x65,
+	0x6e, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6d, 0x73, 0x67, 0x53,
+	0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x10, 0x69, 0x73, 0x5f, 0x74, 0x72, 0x61, 0x6e,
+	0x73, 0x66, 0x65, 0x72, 0x5f, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0e, 0x69, 0x73, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x46, 0x72, 0x6f, 0x6d, 0x12,
+	0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66,
+	0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x74, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x1b,
+	0x0a, 0x09, 0x67, 0x61, 0x73, 0x5f, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x67, 0x61, 0x73, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0b, 0x67,
+	0x61, 0x73, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x63, 0x61, 0x70, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x67, 0x61, 0x73, 0x46, 0x65, 0x65, 0x43, 0x61, 0x70, 0x12, 0x1e, 0x0a, 0x0b, 0x67,
+	0x61, 0x73, 0x5f, 0x74, 0x69, 0x70, 0x5f, 0x63, 0x61, 0x70, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x67, 0x61, 0x73, 0x54, 0x69, 0x70, 0x43, 0x61, 0x70, 0x22, 0xd8, 0x01, 0x0a, 0x13,
+	0x46, 0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x2b, 0x0a, 0x12, 0x69, 0x73, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x6f, 0x6e,
+	0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0f, 0x69, 0x73, 0x46, 0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72,
+	0x12, 0x21, 0x0a, 0x0c, 0x66, 0x65, 0x65, 0x5f, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x65, 0x65, 0x52, 0x65, 0x63, 0x65, 0x69,
+	0x76, 0x65, 0x72, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f, 0x65, 0x66, 0x66, 0x69, 0x63, 0x69, 0x65,
+	0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x01, 0x52, 0x0c, 0x63, 0x6f, 0x65, 0x66, 0x66,
+	0x69, 0x63, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x66, 0x6f, 0x72, 0x6d, 0x75,
+	0x6c, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x66, 0x6f, 0x72, 0x6d, 0x75, 0x6c,
+	0x61, 0x12, 0x33, 0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x52, 0x08, 0x6f, 0x76,
+	0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x22, 0x3c, 0x0a, 0x0e, 0x49, 0x73, 0x45, 0x72, 0x63, 0x32,
+	0x30, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x14,
+	0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74,
+	0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x61, 0x0a, 0x0f, 0x49, 0x73, 0x45, 0x72, 0x63, 0x32, 0x30, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x69, 0x73, 0x5f, 0x65, 0x72,
+	0x63, 0x32, 0x30, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x69, 0x73, 0x45, 0x72, 0x63,
+	0x32, 0x30, 0x12, 0x33, 0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x52, 0x08, 0x6f,
+	0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x22, 0x9f, 0x01, 0x0a, 0x08, 0x4f, 0x76, 0x65, 0x72,
+	0x72, 0x69, 0x64, 0x65, 0x12, 0x2b, 0x0a, 0x12, 0x69, 0x73, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x6f,
+	0x6e, 0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0f, 0x69, 0x73, 0x46, 0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65,
+	0x72, 0x12, 0x17, 0x0a, 0x07, 0x66, 0x65, 0x65, 0x5f, 0x62, 0x70, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x06, 0x66, 0x65, 0x65, 0x42, 0x70, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78,
+	0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x22, 0x44, 0x0a, 0x16, 0x49, 0x73, 0x46,
+	0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22,
+	0x84, 0x01, 0x0a, 0x17, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x79, 0x4e, 0x65, 0x77, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x3d, 0x0a, 0x09, 0x73, 0x63, 0x65, 0x6e, 0x61,
+	0x72, 0x69, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x65, 0x72, 0x63,
+	0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x53, 0x63, 0x65, 0x6e, 0x61, 0x72, 0x69, 0x6f, 0x52, 0x09, 0x73, 0x63, 0x65,
+	0x6e, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x22, 0x81, 0x01, 0x0a, 0x14, 0x43, 0x6c, 0x61, 0x73, 0x73,
+	0x69, 0x66, 0x79, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x3d, 0x0a, 0x09, 0x73,
+	0x63, 0x65, 0x6e, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f,
+	0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x53, 0x63, 0x65, 0x6e, 0x61, 0x72, 0x69, 0x6f, 0x52,
+	0x09, 0x73, 0x63, 0x65, 0x6e, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x22, 0xc9, 0x02, 0x0a, 0x0b, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x68, 0x61, 0x69, 0x6e,
+	0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x30, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x64, 0x69, 0x63,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63,
+	0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x65, 0x72, 0x64, 0x69, 0x63, 0x74, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x64, 0x69, 0x63, 0x74, 0x12, 0x3a, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30,
+	0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x06, 0x72, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x6e, 0x75, 0x6d, 0x5f, 0x65, 0x71, 0x75, 0x61,
+	0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x6e, 0x75, 0x6d, 0x45, 0x71, 0x75, 0x61,
+	0x6c, 0x12, 0x19, 0x0a, 0x08, 0x6e, 0x75, 0x6d, 0x5f, 0x6c, 0x65, 0x73, 0x73, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x07, 0x6e, 0x75, 0x6d, 0x4c, 0x65, 0x73, 0x73, 0x12, 0x1d, 0x0a, 0x0a,
+	0x6e, 0x75, 0x6d, 0x5f, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x09, 0x6e, 0x75, 0x6d, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x12, 0x33, 0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x52, 0x08, 0x6f, 0x76,
+	0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x2a, 0x8e, 0x01, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x64, 0x69,
+	0x63, 0x74, 0x12, 0x17, 0x0a, 0x13, 0x56, 0x45, 0x52, 0x44, 0x49, 0x43, 0x54, 0x5f, 0x55, 0x4e,
+	0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x15, 0x0a, 0x11, 0x56,
+	0x45, 0x52, 0x44, 0x49, 0x43, 0x54, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x45, 0x52, 0x43, 0x32, 0x30,
+	0x10, 0x01, 0x12, 0x1f, 0x0a, 0x1b, 0x56, 0x45, 0x52, 0x44, 0x49, 0x43, 0x54, 0x5f, 0x4e, 0x4f,
+	0x54, 0x5f, 0x46, 0x45, 0x45, 0x5f, 0x4f, 0x4e, 0x5f, 0x54, 0x52, 0x41, 0x4e, 0x53, 0x46, 0x45,
+	0x52, 0x10, 0x02, 0x12, 0x1b, 0x0a, 0x17, 0x56, 0x45, 0x52, 0x44, 0x49, 0x43, 0x54, 0x5f, 0x46,
+	0x45, 0x45, 0x5f, 0x4f, 0x4e, 0x5f, 0x54, 0x52, 0x41, 0x4e, 0x53, 0x46, 0x45, 0x52, 0x10, 0x03,
+	0x12, 0x15, 0x0a, 0x11, 0x56, 0x45, 0x52, 0x44, 0x49, 0x43, 0x54, 0x5f, 0x55, 0x4e, 0x44, 0x45,
+	0x43, 0x49, 0x44, 0x45, 0x44, 0x10, 0x04, 0x32, 0xe2, 0x02, 0x0a, 0x0a, 0x43, 0x6c, 0x61, 0x73,
+	0x73, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x48, 0x0a, 0x07, 0x49, 0x73, 0x45, 0x72, 0x63, 0x32,
+	0x30, 0x12, 0x1d, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x49, 0x73, 0x45, 0x72, 0x63, 0x32, 0x30, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1e, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x49, 0x73, 0x45, 0x72, 0x63, 0x32, 0x30, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x5c, 0x0a, 0x0f, 0x49, 0x73, 0x46, 0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x12, 0x25, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x49, 0x73, 0x46, 0x65, 0x65, 0x4f, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x65, 0x72, 0x63,
+	0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x65, 0x4f, 0x6e,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x56,
+	0x0a, 0x10, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x79, 0x4e, 0x65, 0x77, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x12, 0x26, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x79, 0x4e, 0x65, 0x77, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x65, 0x72, 0x63,
+	0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x54, 0x0a, 0x0d, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x69,
+	0x66, 0x79, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x23, 0x2e, 0x65, 0x72, 0x63, 0x32, 0x30, 0x63,
+	0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x79,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x65,
+	0x72, 0x63, 0x32, 0x30, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x28, 0x01, 0x30, 0x01, 0x42, 0x50, 0x5a, 0x4e,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4b, 0x79, 0x62, 0x65, 0x72,
+	0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x2f, 0x65, 0x72, 0x63, 0x32, 0x30, 0x2d, 0x63, 0x6f,
+	0x6e, 0x74, 0x72, 0x61, 0x63, 0x74, 0x2d, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70,
+	0x69, 0x2f, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x69, 0x65, 0x72, 0x70, 0x62, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_classifier_proto_rawDescOnce sync.Once
+	file_classifier_proto_rawDescData = file_classifier_proto_rawDesc
+)
+
+func file_classifier_proto_rawDescGZIP() []byte {
+	file_classifier_proto_rawDescOnce.Do(func() {
+		file_classifier_proto_rawDescData = protoimpl.X.CompressGZIP(file_classifier_proto_rawDescData)
+	})
+	return file_classifier_proto_rawDescData
+}
+
+var file_classifier_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_classifier_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_classifier_proto_goTypes = []interface{}{
+	(Verdict)(0),                    // 0: erc20class.v1.Verdict
+	(*TransferScenario)(nil),        // 1: erc20class.v1.TransferScenario
+	(*FeeOnTransferResult)(nil),     // 2: erc20class.v1.FeeOnTransferResult
+	(*IsErc20Request)(nil),          // 3: erc20class.v1.IsErc20Request
+	(*IsErc20Response)(nil),         // 4: erc20class.v1.IsErc20Response
+	(*Override)(nil),                // 5: erc20class.v1.Override
+	(*IsFeeOnTransferRequest)(nil),  // 6: erc20class.v1.IsFeeOnTransferRequest
+	(*ClassifyNewTokenRequest)(nil), // 7: erc20class.v1.ClassifyNewTokenRequest
+	(*ClassifyBatchRequest)(nil),    // 8: erc20class.v1.ClassifyBatchRequest
+	(*TokenResult)(nil),             // 9: erc20class.v1.TokenResult
+}
+var file_classifier_proto_depIdxs = []int32{
+	5,  // 0: erc20class.v1.FeeOnTransferResult.override:type_name -> erc20class.v1.Override
+	5,  // 1: erc20class.v1.IsErc20Response.override:type_name -> erc20class.v1.Override
+	1,  // 2: erc20class.v1.ClassifyNewTokenRequest.scenarios:type_name -> erc20class.v1.TransferScenario
+	1,  // 3: erc20class.v1.ClassifyBatchRequest.scenarios:type_name -> erc20class.v1.TransferScenario
+	0,  // 4: erc20class.v1.TokenResult.verdict:type_name -> erc20class.v1.Verdict
+	2,  // 5: erc20class.v1.TokenResult.result:type_name -> erc20class.v1.FeeOnTransferResult
+	5,  // 6: erc20class.v1.TokenResult.override:type_name -> erc20class.v1.Override
+	3,  // 7: erc20class.v1.Classifier.IsErc20:input_type -> erc20class.v1.IsErc20Request
+	6,  // 8: erc20class.v1.Classifier.IsFeeOnTransfer:input_type -> erc20class.v1.IsFeeOnTransferRequest
+	7,  // 9: erc20class.v1.Classifier.ClassifyNewToken:input_type -> erc20class.v1.ClassifyNewTokenRequest
+	8,  // 10: erc20class.v1.Classifier.ClassifyBatch:input_type -> erc20class.v1.ClassifyBatchRequest
+	4,  // 11: erc20class.v1.Classifier.IsErc20:output_type -> erc20class.v1.IsErc20Response
+	2,  // 12: erc20class.v1.Classifier.IsFeeOnTransfer:output_type -> erc20class.v1.FeeOnTransferResult
+	9,  // 13: erc20class.v1.Classifier.ClassifyNewToken:output_type -> erc20class.v1.TokenResult
+	9,  // 14: erc20class.v1.Classifier.ClassifyBatch:output_type -> erc20class.v1.TokenResult
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_classifier_proto_init() }
+func file_classifier_proto_init() {
+	if File_classifier_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_classifier_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransferScenario); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FeeOnTransferResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IsErc20Request); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IsErc20Response); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Override); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IsFeeOnTransferRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClassifyNewTokenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClassifyBatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_classifier_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TokenResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_classifier_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_classifier_proto_goTypes,
+		DependencyIndexes: file_classifier_proto_depIdxs,
+		EnumInfos:         file_classifier_proto_enumTypes,
+		MessageInfos:      file_classifier_proto_msgTypes,
+	}.Build()
+	File_classifier_proto = out.File
+	file_classifier_proto_rawDesc = nil
+	file_classifier_proto_goTypes = nil
+	file_classifier_proto_depIdxs = nil
+}
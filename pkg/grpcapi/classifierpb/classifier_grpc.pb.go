@@ -0,0 +1,263 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: classifier.proto
+
+package classifierpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Classifier_IsErc20_FullMethodName          = "/erc20class.v1.Classifier/IsErc20"
+	Classifier_IsFeeOnTransfer_FullMethodName  = "/erc20class.v1.Classifier/IsFeeOnTransfer"
+	Classifier_ClassifyNewToken_FullMethodName = "/erc20class.v1.Classifier/ClassifyNewToken"
+	Classifier_ClassifyBatch_FullMethodName    = "/erc20class.v1.Classifier/ClassifyBatch"
+)
+
+// ClassifierClient is the client API for Classifier service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ClassifierClient interface {
+	// IsErc20 checks that the code of a contract implements ERC20.
+	IsErc20(ctx context.Context, in *IsErc20Request, opts ...grpc.CallOption) (*IsErc20Response, error)
+	// IsFeeOnTransfer classifies a token from its recent Transfer events.
+	IsFeeOnTransfer(ctx context.Context, in *IsFeeOnTransferRequest, opts ...grpc.CallOption) (*FeeOnTransferResult, error)
+	// ClassifyNewToken classifies a token without enough Transfer events by simulating transfer scenarios.
+	ClassifyNewToken(ctx context.Context, in *ClassifyNewTokenRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	// ClassifyBatch classifies the tokens sent on the stream concurrently, their results are sent as they finish, in
+	// any order. The stream ends once the client closed its side and all tokens are classified.
+	ClassifyBatch(ctx context.Context, opts ...grpc.CallOption) (Classifier_ClassifyBatchClient, error)
+}
+
+type classifierClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClassifierClient(cc grpc.ClientConnInterface) ClassifierClient {
+	return &classifierClient{cc}
+}
+
+func (c *classifierClient) IsErc20(ctx context.Context, in *IsErc20Request, opts ...grpc.CallOption) (*IsErc20Response, error) {
+	out := new(IsErc20Response)
+	err := c.cc.Invoke(ctx, Classifier_IsErc20_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *classifierClient) IsFeeOnTransfer(ctx context.Context, in *IsFeeOnTransferRequest, opts ...grpc.CallOption) (*FeeOnTransferResult, error) {
+	out := new(FeeOnTransferResult)
+	err := c.cc.Invoke(ctx, Classifier_IsFeeOnTransfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *classifierClient) ClassifyNewToken(ctx context.Context, in *ClassifyNewTokenRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, Classifier_ClassifyNewToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *classifierClient) ClassifyBatch(ctx context.Context, opts ...grpc.CallOption) (Classifier_ClassifyBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Classifier_ServiceDesc.Streams[0], Classifier_ClassifyBatch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &classifierClassifyBatchClient{stream}
+	return x, nil
+}
+
+type Classifier_ClassifyBatchClient interface {
+	Send(*ClassifyBatchRequest) error
+	Recv() (*TokenResult, error)
+	grpc.ClientStream
+}
+
+type classifierClassifyBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *classifierClassifyBatchClient) Send(m *ClassifyBatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *classifierClassifyBatchClient) Recv() (*TokenResult, error) {
+	m := new(TokenResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ClassifierServer is the server API for Classifier service.
+// All implementations must embed UnimplementedClassifierServer
+// for forward compatibility
+type ClassifierServer interface {
+	// IsErc20 checks that the code of a contract implements ERC20.
+	IsErc20(context.Context, *IsErc20Request) (*IsErc20Response, error)
+	// IsFeeOnTransfer classifies a token from its recent Transfer events.
+	IsFeeOnTransfer(context.Context, *IsFeeOnTransferRequest) (*FeeOnTransferResult, error)
+	// ClassifyNewToken classifies a token without enough Transfer events by simulating transfer scenarios.
+	ClassifyNewToken(context.Context, *ClassifyNewTokenRequest) (*TokenResult, error)
+	// ClassifyBatch classifies the tokens sent on the stream concurrently, their results are sent as they finish, in
+	// any order. The stream ends once the client closed its side and all tokens are classified.
+	ClassifyBatch(Classifier_ClassifyBatchServer) error
+	mustEmbedUnimplementedClassifierServer()
+}
+
+// UnimplementedClassifierServer must be embedded to have forward compatible implementations.
+type UnimplementedClassifierServer struct {
+}
+
+func (UnimplementedClassifierServer) IsErc20(context.Context, *IsErc20Request) (*IsErc20Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsErc20 not implemented")
+}
+func (UnimplementedClassifierServer) IsFeeOnTransfer(context.Context, *IsFeeOnTransferRequest) (*FeeOnTransferResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsFeeOnTransfer not implemented")
+}
+func (UnimplementedClassifierServer) ClassifyNewToken(context.Context, *ClassifyNewTokenRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClassifyNewToken not implemented")
+}
+func (UnimplementedClassifierServer) ClassifyBatch(Classifier_ClassifyBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method ClassifyBatch not implemented")
+}
+func (UnimplementedClassifierServer) mustEmbedUnimplementedClassifierServer() {}
+
+// UnsafeClassifierServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClassifierServer will
+// result in compilation errors.
+type UnsafeClassifierServer interface {
+	mustEmbedUnimplementedClassifierServer()
+}
+
+func RegisterClassifierServer(s grpc.ServiceRegistrar, srv ClassifierServer) {
+	s.RegisterService(&Classifier_ServiceDesc, srv)
+}
+
+func _Classifier_IsErc20_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsErc20Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClassifierServer).IsErc20(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Classifier_IsErc20_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClassifierServer).IsErc20(ctx, req.(*IsErc20Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Classifier_IsFeeOnTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsFeeOnTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClassifierServer).IsFeeOnTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Classifier_IsFeeOnTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClassifierServer).IsFeeOnTransfer(ctx, req.(*IsFeeOnTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Classifier_ClassifyNewToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClassifyNewTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClassifierServer).ClassifyNewToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Classifier_ClassifyNewToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClassifierServer).ClassifyNewToken(ctx, req.(*ClassifyNewTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Classifier_ClassifyBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClassifierServer).ClassifyBatch(&classifierClassifyBatchServer{stream})
+}
+
+type Classifier_ClassifyBatchServer interface {
+	Send(*TokenResult) error
+	Recv() (*ClassifyBatchRequest, error)
+	grpc.ServerStream
+}
+
+type classifierClassifyBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *classifierClassifyBatchServer) Send(m *TokenResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *classifierClassifyBatchServer) Recv() (*ClassifyBatchRequest, error) {
+	m := new(ClassifyBatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Classifier_ServiceDesc is the grpc.ServiceDesc for Classifier service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Classifier_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "erc20class.v1.Classifier",
+	HandlerType: (*ClassifierServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IsErc20",
+			Handler:    _Classifier_IsErc20_Handler,
+		},
+		{
+			MethodName: "IsFeeOnTransfer",
+			Handler:    _Classifier_IsFeeOnTransfer_Handler,
+		},
+		{
+			MethodName: "ClassifyNewToken",
+			Handler:    _Classifier_ClassifyNewToken_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ClassifyBatch",
+			Handler:       _Classifier_ClassifyBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "classifier.proto",
+}
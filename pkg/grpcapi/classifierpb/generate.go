@@ -0,0 +1,4 @@
+// Package classifierpb holds the protobuf messages and the gRPC service of classifier.proto.
+package classifierpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative classifier.proto
@@ -0,0 +1,340 @@
+// Package grpcapi serves the classifiers over gRPC, the service is defined in classifierpb/classifier.proto.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/grpcapi/classifierpb"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+// Config configures a Service.
+type Config struct {
+	// Workers is the number of tokens of a ClassifyBatch stream classified concurrently
+	Workers int
+	// Overrides pin the verdicts of tokens, overridden tokens are not classified. Optional.
+	Overrides server.Overrides
+}
+
+var DefaultConfig = Config{
+	Workers: 8,
+}
+
+// Service implements classifierpb.ClassifierServer with the chains of a server.Server, register it with
+// classifierpb.RegisterClassifierServer.
+type Service struct {
+	classifierpb.UnimplementedClassifierServer
+	chains map[string]*server.Chain
+	config Config
+}
+
+func NewService(chains map[string]*server.Chain, config Config) *Service {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	return &Service{
+		chains: chains,
+		config: config,
+	}
+}
+
+func (s *Service) chain(name string) (*server.Chain, error) {
+	chain, ok := s.chains[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown chain %q", name)
+	}
+	return chain, nil
+}
+
+// override returns the active override of a token as a proto, nil if there is none.
+func (s *Service) override(chain string, token common.Address) (*classifierpb.Override, error) {
+	override, err := server.ActiveOverride(s.config.Overrides, chain, token, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if override == nil {
+		return nil, nil
+	}
+	pbOverride := &classifierpb.Override{
+		IsFeeOnTransfer: override.IsFeeOnTransfer,
+		FeeBps:          uint32(override.FeeBps),
+		Reason:          override.Reason,
+		Author:          override.Author,
+	}
+	if override.ExpiresAt != nil {
+		pbOverride.ExpiresAt = override.ExpiresAt.Unix()
+	}
+	return pbOverride, nil
+}
+
+func (s *Service) IsErc20(ctx context.Context, req *classifierpb.IsErc20Request) (*classifierpb.IsErc20Response, error) {
+	chain, err := s.chain(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+	token, err := parseAddress("token", req.Token)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.override(req.Chain, token)
+	if err != nil {
+		return nil, err
+	}
+	// an override pins the fee of a token, whether it is ERC20 is still told by its code
+	isErc20, err := chain.IsErc20(token)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &classifierpb.IsErc20Response{IsErc20: isErc20, Override: override}, nil
+}
+
+func (s *Service) IsFeeOnTransfer(ctx context.Context, req *classifierpb.IsFeeOnTransferRequest) (*classifierpb.FeeOnTransferResult, error) {
+	chain, err := s.chain(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+	token, err := parseAddress("token", req.Token)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.override(req.Chain, token)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		return &classifierpb.FeeOnTransferResult{IsFeeOnTransfer: override.IsFeeOnTransfer, Override: override}, nil
+	}
+	result, err := chain.ClassifyLogs(ctx, token)
+	if errors.Is(err, classifier.ErrCouldNotDecide) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return feeOnTransferResultToProto(result), nil
+}
+
+func (s *Service) ClassifyNewToken(ctx context.Context, req *classifierpb.ClassifyNewTokenRequest) (*classifierpb.TokenResult, error) {
+	if len(req.Scenarios) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no scenario")
+	}
+	return s.classify(ctx, req.Chain, req.Token, req.Scenarios)
+}
+
+func (s *Service) ClassifyBatch(stream classifierpb.Classifier_ClassifyBatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		sendErr error
+		workers = make(chan struct{}, s.config.Workers)
+	)
+	send := func(result *classifierpb.TokenResult) {
+		// Send must not be called concurrently
+		mu.Lock()
+		defer mu.Unlock()
+		if sendErr != nil {
+			return
+		}
+		if sendErr = stream.Send(result); sendErr != nil {
+			cancel()
+		}
+	}
+
+	var recvErr error
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			recvErr = err
+			cancel()
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case workers <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			result, err := s.classify(ctx, req.Chain, req.Token, req.Scenarios)
+			if err != nil {
+				// invalid requests don't end the stream
+				result = &classifierpb.TokenResult{Chain: req.Chain, Token: req.Token, Error: status.Convert(err).Message()}
+			}
+			send(result)
+		}()
+	}
+	wg.Wait()
+
+	if recvErr != nil {
+		return recvErr
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sendErr != nil {
+		return sendErr
+	}
+	return stream.Context().Err()
+}
+
+// classify classifies a token with its scenarios if any or else with its recent Transfer events. Errors are status
+// errors for invalid requests, the result holds the errors of the classification.
+func (s *Service) classify(ctx context.Context, chainName, tokenHex string, pbScenarios []*classifierpb.TransferScenario) (*classifierpb.TokenResult, error) {
+	chain, err := s.chain(chainName)
+	if err != nil {
+		return nil, err
+	}
+	token, err := parseAddress("token", tokenHex)
+	if err != nil {
+		return nil, err
+	}
+	scenarios, err := scenariosFromProto(pbScenarios)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &classifierpb.TokenResult{
+		Chain:   chainName,
+		Token:   token.Hex(),
+		Verdict: classifierpb.Verdict_VERDICT_UNDECIDED,
+	}
+	if result.Override, err = s.override(chainName, token); err != nil {
+		return nil, err
+	}
+	isErc20, err := chain.IsErc20(token)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	if !isErc20 {
+		result.Verdict = classifierpb.Verdict_VERDICT_NOT_ERC20
+		return result, nil
+	}
+	if result.Override != nil {
+		result.Verdict = verdict(result.Override.IsFeeOnTransfer)
+		return result, nil
+	}
+
+	if len(scenarios) > 0 {
+		batchResult := chain.ClassifyScenarios(ctx, token, scenarios)
+		result.NumEqual = uint32(batchResult.NumEqual)
+		result.NumLess = uint32(batchResult.NumLess)
+		result.NumFailed = uint32(batchResult.NumFailed)
+		if batchResult.Err != nil {
+			result.Error = batchResult.Err.Error()
+			return result, nil
+		}
+		result.Verdict = verdict(batchResult.IsFeeOnTransfer)
+		return result, nil
+	}
+
+	fotResult, err := chain.ClassifyLogs(ctx, token)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Verdict = verdict(fotResult.IsFeeOnTransfer)
+	result.Result = feeOnTransferResultToProto(fotResult)
+	return result, nil
+}
+
+func verdict(isFeeOnTransfer bool) classifierpb.Verdict {
+	if isFeeOnTransfer {
+		return classifierpb.Verdict_VERDICT_FEE_ON_TRANSFER
+	}
+	return classifierpb.Verdict_VERDICT_NOT_FEE_ON_TRANSFER
+}
+
+func parseAddress(field, value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, status.Errorf(codes.InvalidArgument, "invalid %s %q", field, value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+// parseAmount parses a decimal amount, an empty optional amount is nil.
+func parseAmount(field, value string, optional bool) (*big.Int, error) {
+	if value == "" && optional {
+		return nil, nil
+	}
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s %q", field, value)
+	}
+	return amount, nil
+}
+
+// scenariosFromProto converts scenarios, their token is set by the classifier.
+func scenariosFromProto(pbScenarios []*classifierpb.TransferScenario) ([]*jsonrpc.TransferScenario, error) {
+	scenarios := make([]*jsonrpc.TransferScenario, 0, len(pbScenarios))
+	for i, p := range pbScenarios {
+		var (
+			s   = &jsonrpc.TransferScenario{IsTransferFrom: p.IsTransferFrom}
+			err error
+		)
+		prefix := fmt.Sprintf("scenarios[%d].", i)
+		if s.MsgSender, err = parseAddress(prefix+"msg_sender", p.MsgSender); err != nil {
+			return nil, err
+		}
+		if p.From == "" && !p.IsTransferFrom {
+			// transfer() sends from the msg sender
+			s.From = s.MsgSender
+		} else if s.From, err = parseAddress(prefix+"from", p.From); err != nil {
+			return nil, err
+		}
+		if s.To, err = parseAddress(prefix+"to", p.To); err != nil {
+			return nil, err
+		}
+		if s.Amount, err = parseAmount(prefix+"amount", p.Amount, false); err != nil {
+			return nil, err
+		}
+		if s.GasPrice, err = parseAmount(prefix+"gas_price", p.GasPrice, true); err != nil {
+			return nil, err
+		}
+		if s.GasFeeCap, err = parseAmount(prefix+"gas_fee_cap", p.GasFeeCap, true); err != nil {
+			return nil, err
+		}
+		if s.GasTipCap, err = parseAmount(prefix+"gas_tip_cap", p.GasTipCap, true); err != nil {
+			return nil, err
+		}
+		// leave the block number empty when omitted so the latest block is used
+		if p.BlockNumber != 0 {
+			s.BlockNumber = hexutil.EncodeUint64(p.BlockNumber)
+		}
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+func feeOnTransferResultToProto(result classifier.FeeOnTransferResult) *classifierpb.FeeOnTransferResult {
+	return &classifierpb.FeeOnTransferResult{
+		IsFeeOnTransfer: result.IsFeeOnTransfer,
+		FeeReceiver:     result.FeeReceiver.Hex(),
+		Coefficients:    result.Coefficients,
+		Formula:         result.Formular,
+	}
+}
@@ -0,0 +1,193 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/grpcapi/classifierpb"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+// codeClient returns a code that is not ERC20 for any contract.
+type codeClient struct{}
+
+func (codeClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return json.Unmarshal([]byte(`"0x6000"`), result)
+}
+
+func (c codeClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for i := range b {
+		b[i].Error = c.CallContext(ctx, b[i].Result, b[i].Method, b[i].Args...)
+	}
+	return nil
+}
+
+func newTestClient(t *testing.T, config Config) classifierpb.ClassifierClient {
+	chains := map[string]*server.Chain{
+		"ethereum": server.NewChain(codeClient{}, nil, fetcher.DefaultConfig, classifier.DefaultBatchClassifierConfig, 100),
+	}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	classifierpb.RegisterClassifierServer(srv, NewService(chains, config))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return classifierpb.NewClassifierClient(conn)
+}
+
+func TestIsErc20(t *testing.T) {
+	client := newTestClient(t, DefaultConfig)
+	ctx := context.Background()
+
+	resp, err := client.IsErc20(ctx, &classifierpb.IsErc20Request{Chain: "ethereum", Token: "0x0000000000000000000000000000000000000001"})
+	require.NoError(t, err)
+	assert.False(t, resp.IsErc20)
+
+	tests := []struct {
+		name     string
+		req      *classifierpb.IsErc20Request
+		wantCode codes.Code
+	}{
+		{"unknown chain", &classifierpb.IsErc20Request{Chain: "bsc", Token: "0x0000000000000000000000000000000000000001"}, codes.NotFound},
+		{"invalid token", &classifierpb.IsErc20Request{Chain: "ethereum", Token: "0x01"}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.IsErc20(ctx, tt.req)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestClassifyBatch(t *testing.T) {
+	client := newTestClient(t, DefaultConfig)
+	stream, err := client.ClassifyBatch(context.Background())
+	require.NoError(t, err)
+
+	tokens := []string{
+		"0x0000000000000000000000000000000000000001",
+		"0x0000000000000000000000000000000000000002",
+		"0x0000000000000000000000000000000000000003",
+	}
+	for _, token := range tokens {
+		require.NoError(t, stream.Send(&classifierpb.ClassifyBatchRequest{Chain: "ethereum", Token: token}))
+	}
+	require.NoError(t, stream.Send(&classifierpb.ClassifyBatchRequest{Chain: "ethereum", Token: "0x04"}))
+	require.NoError(t, stream.CloseSend())
+
+	verdicts := make(map[string]classifierpb.Verdict)
+	errs := make(map[string]string)
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		verdicts[result.Token] = result.Verdict
+		if result.Error != "" {
+			errs[result.Token] = result.Error
+		}
+	}
+	require.Len(t, verdicts, len(tokens)+1)
+	for _, token := range tokens {
+		assert.Equal(t, classifierpb.Verdict_VERDICT_NOT_ERC20, verdicts[token], token)
+	}
+	// invalid requests get a result with an error rather than ending the stream
+	assert.Contains(t, errs["0x04"], "invalid token")
+}
+
+func TestOverrides(t *testing.T) {
+	var (
+		token     = common.HexToAddress("0x01")
+		expiresAt = time.Now().Add(time.Hour)
+	)
+	overrides, err := server.NewOverrideList([]*server.Override{
+		{Chain: "ethereum", Token: token, IsFeeOnTransfer: true, FeeBps: 300, Reason: "tax", ExpiresAt: &expiresAt},
+	})
+	require.NoError(t, err)
+	config := DefaultConfig
+	config.Overrides = overrides
+	client := newTestClient(t, config)
+	ctx := context.Background()
+	want := &classifierpb.Override{IsFeeOnTransfer: true, FeeBps: 300, Reason: "tax", ExpiresAt: expiresAt.Unix()}
+
+	// the fake client has no ERC20 code, an override does not make the token ERC20
+	resp, err := client.IsErc20(ctx, &classifierpb.IsErc20Request{Chain: "ethereum", Token: token.Hex()})
+	require.NoError(t, err)
+	assert.False(t, resp.IsErc20)
+	assert.True(t, proto.Equal(want, resp.Override))
+
+	fot, err := client.IsFeeOnTransfer(ctx, &classifierpb.IsFeeOnTransferRequest{Chain: "ethereum", Token: token.Hex()})
+	require.NoError(t, err)
+	assert.True(t, fot.IsFeeOnTransfer)
+	assert.True(t, proto.Equal(want, fot.Override))
+
+	stream, err := client.ClassifyBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&classifierpb.ClassifyBatchRequest{Chain: "ethereum", Token: token.Hex()}))
+	require.NoError(t, stream.CloseSend())
+	result, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, classifierpb.Verdict_VERDICT_NOT_ERC20, result.Verdict)
+	assert.True(t, proto.Equal(want, result.Override))
+
+	// other tokens are classified
+	resp, err = client.IsErc20(ctx, &classifierpb.IsErc20Request{Chain: "ethereum", Token: "0x0000000000000000000000000000000000000002"})
+	require.NoError(t, err)
+	assert.False(t, resp.IsErc20)
+	assert.Nil(t, resp.Override)
+}
+
+func TestScenariosFromProto(t *testing.T) {
+	const (
+		sender = "0x0000000000000000000000000000000000000001"
+		to     = "0x0000000000000000000000000000000000000002"
+	)
+	tests := []struct {
+		name      string
+		scenario  *classifierpb.TransferScenario
+		wantBlock string
+		wantErr   bool
+	}{
+		{"transfer without from", &classifierpb.TransferScenario{MsgSender: sender, To: to, Amount: "100", BlockNumber: 1}, "0x1", false},
+		{"no block number", &classifierpb.TransferScenario{MsgSender: sender, To: to, Amount: "100"}, "", false},
+		{"transferFrom without from", &classifierpb.TransferScenario{MsgSender: sender, IsTransferFrom: true, To: to, Amount: "100"}, "", true},
+		{"invalid amount", &classifierpb.TransferScenario{MsgSender: sender, To: to, Amount: "-1"}, "", true},
+		{"invalid gas price", &classifierpb.TransferScenario{MsgSender: sender, To: to, Amount: "1", GasPrice: "0x1"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenarios, err := scenariosFromProto([]*classifierpb.TransferScenario{tt.scenario})
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, scenarios[0].MsgSender, scenarios[0].From)
+			assert.Equal(t, tt.wantBlock, scenarios[0].BlockNumber)
+			assert.Equal(t, int64(100), scenarios[0].Amount.Int64())
+		})
+	}
+}
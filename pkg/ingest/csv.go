@@ -0,0 +1,47 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+// NewCSVReader reads CSV rows with a header naming their columns.
+func NewCSVReader(r io.Reader) (Reader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing CSV header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %w", err)
+	}
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = strings.TrimSpace(name)
+	}
+	// exports saved by spreadsheets start with a byte order mark
+	names[0] = strings.TrimPrefix(names[0], "\ufeff")
+	return &csvReader{r: cr, header: names}, nil
+}
+
+func (r *csvReader) Read() (Row, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	row := make(Row, len(r.header))
+	for i, name := range r.header {
+		row[name] = record[i]
+	}
+	return row, nil
+}
+
+func (r *csvReader) Close() error { return nil }
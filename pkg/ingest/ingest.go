@@ -0,0 +1,169 @@
+// Package ingest reads the transfers tokens are classified from out of data exports: CSV, JSONL and Parquet exports
+// of Dune queries, BigQuery tables or this repo's commands. Readers return rows of named values whatever their format,
+// the rows are then mapped to the fields of the records, validated and streamed as transfer calls, transfer scenarios
+// or transfer events.
+package ingest
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidRow is returned for a row missing a field or with a field that does not parse.
+var ErrInvalidRow = errors.New("invalid row")
+
+// Row is a row of an export, its values by column name. A null value is empty.
+type Row map[string]string
+
+// Reader reads the rows of an export.
+type Reader interface {
+	// Read returns the next row, io.EOF after the last one
+	Read() (Row, error)
+	Close() error
+}
+
+// Format is the file format of an export.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// FormatOf returns the format of a file from its extension, ignoring a .gz compression extension.
+func FormatOf(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+	switch ext {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson", ".json":
+		return FormatJSONL, nil
+	case ".parquet":
+		return FormatParquet, nil
+	}
+	return "", fmt.Errorf("unknown format of %s", path)
+}
+
+// Open opens an export of a format, found from its extension if empty. CSV and JSONL exports can be gzipped, with a
+// .gz extension.
+func Open(path string, format Format) (Reader, error) {
+	if format == "" {
+		var err error
+		if format, err = FormatOf(path); err != nil {
+			return nil, err
+		}
+	}
+	if format == FormatParquet {
+		return NewParquetReader(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open input: %w", err)
+	}
+	var (
+		in      io.Reader = f
+		closers           = []io.Closer{f}
+	)
+	if strings.EqualFold(filepath.Ext(path), ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not decompress %s: %w", path, err)
+		}
+		in, closers = gz, append([]io.Closer{gz}, closers...)
+	}
+
+	var r Reader
+	switch format {
+	case FormatCSV:
+		r, err = NewCSVReader(in)
+	case FormatJSONL:
+		r = NewJSONLReader(in)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		closeAll(closers)
+		return nil, err
+	}
+	return closingReader{Reader: r, closers: closers}, nil
+}
+
+// closingReader closes the files a reader reads from along with it.
+type closingReader struct {
+	Reader
+	closers []io.Closer
+}
+
+func (r closingReader) Close() error {
+	return errors.Join(r.Reader.Close(), closeAll(r.closers))
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, c := range closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Mapping maps the fields of records to the columns of an export. A field not mapped is read from the first of its
+// usual columns in the row, e.g. contract_address for the token of a Dune evt_Transfer export.
+type Mapping map[string]string
+
+// ParseMapping parses comma separated field=column pairs, e.g. token=contract_address,amount=value.
+func ParseMapping(s string) (Mapping, error) {
+	mapping := make(Mapping)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=column", pair)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// check fails if the mapping has a field the records don't have.
+func (m Mapping) check(columns map[string][]string) error {
+	for field := range m {
+		if _, ok := columns[field]; !ok {
+			fields := make([]string, 0, len(columns))
+			for f := range columns {
+				fields = append(fields, f)
+			}
+			sort.Strings(fields)
+			return fmt.Errorf("unknown field %q in column mapping, fields are %v", field, fields)
+		}
+	}
+	return nil
+}
+
+// value returns the value of a field in a row and whether the row has it.
+func (m Mapping) value(row Row, columns map[string][]string, field string) (string, bool) {
+	if column, ok := m[field]; ok {
+		v, ok := row[column]
+		return strings.TrimSpace(v), ok
+	}
+	for _, column := range columns[field] {
+		if v, ok := row[column]; ok {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
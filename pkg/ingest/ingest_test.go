@@ -0,0 +1,115 @@
+package ingest
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, r Reader) []Row {
+	var rows []Row
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    Format
+		wantErr bool
+	}{
+		{path: "transfers.csv", want: FormatCSV},
+		{path: "transfers.CSV.gz", want: FormatCSV},
+		{path: "bq-results.json", want: FormatJSONL},
+		{path: "export.ndjson.gz", want: FormatJSONL},
+		{path: "dune/result.parquet", want: FormatParquet},
+		{path: "transfers.xlsx", wantErr: true},
+		{path: "transfers", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := FormatOf(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	got, err := ParseMapping(" token = contract_address, amount=value,")
+	require.NoError(t, err)
+	assert.Equal(t, Mapping{"token": "contract_address", "amount": "value"}, got)
+
+	got, err = ParseMapping("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	for _, s := range []string{"token", "token=", "=value"} {
+		_, err := ParseMapping(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	r, err := NewCSVReader(strings.NewReader("\ufefftoken, amount\n0x01,10\n0x02,\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{"token": "0x01", "amount": "10"},
+		{"token": "0x02", "amount": ""},
+	}, readAll(t, r))
+
+	_, err = NewCSVReader(strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestJSONLReader(t *testing.T) {
+	r := NewJSONLReader(strings.NewReader(`{"value": 123456789012345678901234567890, "from_address": "0x01", "log_index": null}
+{"value": "10", "removed": false, "topics": ["0x02"]}
+`))
+	assert.Equal(t, []Row{
+		{"value": "123456789012345678901234567890", "from_address": "0x01", "log_index": ""},
+		{"value": "10", "removed": "false", "topics": `["0x02"]`},
+	}, readAll(t, r))
+
+	r = NewJSONLReader(strings.NewReader(`{"value": 1`))
+	_, err := r.Read()
+	assert.Error(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transfers.csv.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte("token,amount\n0x01,10\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	r, err := Open(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"token": "0x01", "amount": "10"}}, readAll(t, r))
+	assert.NoError(t, r.Close())
+
+	_, err = Open(filepath.Join(dir, "missing.csv"), "")
+	assert.Error(t, err)
+	_, err = Open(path, "xlsx")
+	assert.Error(t, err)
+}
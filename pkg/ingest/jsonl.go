@@ -0,0 +1,52 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type jsonlReader struct {
+	dec *json.Decoder
+}
+
+// NewJSONLReader reads rows of JSON objects, one per line, like the JSON exports of BigQuery. Numbers are kept as
+// written so large amounts are not rounded, nested values are kept as JSON.
+func NewJSONLReader(r io.Reader) Reader {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &jsonlReader{dec: dec}
+}
+
+func (r *jsonlReader) Read() (Row, error) {
+	var object map[string]interface{}
+	if err := r.dec.Decode(&object); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not decode JSON row: %w", err)
+	}
+	row := make(Row, len(object))
+	for name, v := range object {
+		switch v := v.(type) {
+		case nil:
+			row[name] = ""
+		case string:
+			row[name] = v
+		case json.Number:
+			row[name] = v.String()
+		case bool:
+			row[name] = strconv.FormatBool(v)
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode column %s: %w", name, err)
+			}
+			row[name] = string(encoded)
+		}
+	}
+	return row, nil
+}
+
+func (r *jsonlReader) Close() error { return nil }
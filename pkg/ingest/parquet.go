@@ -0,0 +1,176 @@
+package ingest
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetColumn is a flat column and how its values are formatted.
+type parquetColumn struct {
+	name     string
+	kind     parquet.Kind
+	decimal  bool
+	scale    int32
+	text     bool
+	unsigned bool
+}
+
+type parquetReader struct {
+	f       *os.File
+	r       *parquet.Reader
+	columns []parquetColumn
+	// buf is the row read, its byte values are only valid until the next read
+	buf []parquet.Row
+}
+
+// NewParquetReader reads the rows of a Parquet file with flat columns, like the Parquet exports of Dune and BigQuery.
+// Strings are read as they are, other binary values as hex (e.g. Dune's varbinary addresses and hashes) and decimals
+// as decimal numbers (e.g. BigQuery's NUMERIC).
+func NewParquetReader(path string) (Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open input: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not open input: %w", err)
+	}
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not read Parquet file %s: %w", path, err)
+	}
+	columns, err := parquetColumns(file.Schema())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not read Parquet schema of %s: %w", path, err)
+	}
+	return &parquetReader{f: f, r: parquet.NewReader(file), columns: columns, buf: make([]parquet.Row, 1)}, nil
+}
+
+// parquetColumns returns the columns of a flat schema: a root whose children are all leaves.
+func parquetColumns(schema *parquet.Schema) ([]parquetColumn, error) {
+	fields := schema.Fields()
+	columns := make([]parquetColumn, 0, len(fields))
+	for _, field := range fields {
+		if !field.Leaf() || field.Repeated() {
+			return nil, fmt.Errorf("nested column %s is not supported", field.Name())
+		}
+		c := parquetColumn{
+			name: field.Name(),
+			kind: field.Type().Kind(),
+		}
+		// converted types of older writers are read as their logical types
+		if logical := field.Type().LogicalType(); logical != nil {
+			switch {
+			case logical.UTF8 != nil, logical.Enum != nil, logical.Json != nil:
+				c.text = true
+			case logical.Decimal != nil:
+				c.decimal, c.scale = true, logical.Decimal.Scale
+			case logical.Integer != nil:
+				c.unsigned = !logical.Integer.IsSigned
+			}
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+func (r *parquetReader) Read() (Row, error) {
+	n, err := r.r.ReadRows(r.buf)
+	if n == 0 {
+		if err == nil || errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("could not read Parquet row: %w", err)
+	}
+	row := make(Row, len(r.columns))
+	for _, c := range r.columns {
+		row[c.name] = ""
+	}
+	for _, v := range r.buf[0] {
+		if i := v.Column(); i >= 0 && i < len(r.columns) {
+			row[r.columns[i].name] = r.columns[i].format(v)
+		}
+	}
+	return row, nil
+}
+
+func (r *parquetReader) Close() error {
+	return errors.Join(r.r.Close(), r.f.Close())
+}
+
+// format formats a value, nulls are empty.
+func (c *parquetColumn) format(v parquet.Value) string {
+	if v.IsNull() {
+		return ""
+	}
+	switch c.kind {
+	case parquet.Boolean:
+		return strconv.FormatBool(v.Boolean())
+	case parquet.Int32:
+		if c.unsigned {
+			return strconv.FormatUint(uint64(uint32(v.Int32())), 10)
+		}
+		return c.formatInt(int64(v.Int32()))
+	case parquet.Int64:
+		if c.unsigned {
+			return strconv.FormatUint(uint64(v.Int64()), 10)
+		}
+		return c.formatInt(v.Int64())
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'f', -1, 64)
+	case parquet.Int96:
+		return "0x" + hex.EncodeToString(v.Bytes())
+	}
+	return c.formatBytes(v.ByteArray())
+}
+
+func (c *parquetColumn) formatInt(v int64) string {
+	if c.decimal {
+		return formatDecimal(big.NewInt(v), c.scale)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func (c *parquetColumn) formatBytes(b []byte) string {
+	switch {
+	case c.decimal:
+		// big-endian two's complement
+		unscaled := new(big.Int).SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+		}
+		return formatDecimal(unscaled, c.scale)
+	case c.text:
+		return string(b)
+	default:
+		return "0x" + hex.EncodeToString(b)
+	}
+}
+
+// formatDecimal formats unscaled×10^-scale, e.g. 1500 with scale 3 is 1.500.
+func formatDecimal(unscaled *big.Int, scale int32) string {
+	s := unscaled.String()
+	if scale <= 0 {
+		return s
+	}
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	for len(s) <= int(scale) {
+		s = "0" + s
+	}
+	return sign + s[:len(s)-int(scale)] + "." + s[len(s)-int(scale):]
+}
@@ -0,0 +1,165 @@
+package ingest
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
+)
+
+// The files of testdata come from the Apache parquet-testing suite: arrow_cpp.snappy.parquet is written by the Arrow
+// C++ writer pyarrow uses, parquet_mr_decimal.parquet by parquet-mr, the writer of Spark and Hive.
+func TestParquetReader(t *testing.T) {
+	decimals := make([]Row, 24)
+	for i := range decimals {
+		decimals[i] = Row{"value": strconv.Itoa(i+1) + ".00"}
+	}
+	tests := []struct {
+		name string
+		path string
+		want []Row
+	}{
+		{
+			name: "Arrow C++",
+			path: "testdata/arrow_cpp.snappy.parquet",
+			want: []Row{
+				{
+					"timestamp_tz": "1642416249291", "timestamp_ltz": "1642416249291", "timestamp_ntz": "1642387449291",
+					"varchar": "first", "boolean": "true", "int": "42",
+				},
+				{
+					"timestamp_tz": "1642416263571", "timestamp_ltz": "1642416263571", "timestamp_ntz": "1642387463571",
+					"varchar": "second", "boolean": "false", "int": "99",
+				},
+				{
+					"timestamp_tz": "", "timestamp_ltz": "", "timestamp_ntz": "",
+					"varchar": "third", "boolean": "", "int": "11",
+				},
+			},
+		},
+		{
+			name: "parquet-mr decimal",
+			path: "testdata/parquet_mr_decimal.parquet",
+			want: decimals,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Open(tt.path, "")
+			require.NoError(t, err)
+			defer r.Close()
+			assert.Equal(t, tt.want, readAll(t, r))
+		})
+	}
+}
+
+// duneTransfer is a row of a Parquet export of Dune's erc20_ethereum.evt_Transfer: varbinary addresses and hashes,
+// uint256 values as strings.
+type duneTransfer struct {
+	ContractAddress []byte `parquet:"contract_address,dict"`
+	EvtTxHash       []byte `parquet:"evt_tx_hash"`
+	EvtBlockNumber  int64  `parquet:"evt_block_number"`
+	From            []byte `parquet:"from"`
+	To              []byte `parquet:"to"`
+	Value           string `parquet:"value,zstd"`
+}
+
+// bigQueryTransfer is a row of a Parquet export of BigQuery's crypto_ethereum.token_transfers, with the value cast
+// to NUMERIC.
+type bigQueryTransfer struct {
+	TokenAddress    string   `parquet:"token_address,dict,snappy"`
+	FromAddress     string   `parquet:"from_address"`
+	ToAddress       string   `parquet:"to_address"`
+	Value           [16]byte `parquet:"value,decimal(9:38)"`
+	TransactionHash string   `parquet:"transaction_hash"`
+	BlockNumber     int64    `parquet:"block_number"`
+}
+
+func writeParquetFile[T any](t *testing.T, rows []T) string {
+	path := filepath.Join(t.TempDir(), "transfers.parquet")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	// small row groups so rows are read across several
+	w := parquet.NewGenericWriter[T](f, parquet.MaxRowsPerRowGroup(2))
+	_, err = w.Write(rows)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return path
+}
+
+// numeric is v scaled by 10^9, big-endian two's complement.
+func numeric(v int64) [16]byte {
+	var b [16]byte
+	new(big.Int).Mul(big.NewInt(v), big.NewInt(1e9)).FillBytes(b[:])
+	return b
+}
+
+func TestParquetTransferEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "Dune",
+			path: writeParquetFile(t, []duneTransfer{
+				{tokenA.Bytes(), txHash.Bytes(), 1, alice.Bytes(), bob.Bytes(), "100"},
+				{tokenB.Bytes(), txHash.Bytes(), 1, alice.Bytes(), bob.Bytes(), "200"},
+				{tokenA.Bytes(), txHash.Bytes(), 2, bob.Bytes(), alice.Bytes(), "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+			}),
+		},
+		{
+			name: "BigQuery",
+			path: writeParquetFile(t, []bigQueryTransfer{
+				{tokenA.Hex(), alice.Hex(), bob.Hex(), numeric(100), txHash.Hex(), 1},
+				{tokenB.Hex(), alice.Hex(), bob.Hex(), numeric(200), txHash.Hex(), 1},
+				{tokenA.Hex(), bob.Hex(), alice.Hex(), numeric(1e18), txHash.Hex(), 2},
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Open(tt.path, "")
+			require.NoError(t, err)
+			defer r.Close()
+			got, err := Collect(TransferEvents(context.Background(), r, Options{Tokens: []common.Address{tokenA}}))
+			require.NoError(t, err)
+			require.Len(t, got, 2)
+			assert.Equal(t, &types.TxFromTransferEvent{From: alice, To: bob, TxHash: txHash, Amount: big.NewInt(100)}, got[0])
+			assert.Equal(t, bob, got[1].From)
+			assert.Equal(t, alice, got[1].To)
+		})
+	}
+}
+
+func TestParquetReaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transfers.parquet")
+	require.NoError(t, os.WriteFile(path, []byte("contract_address,value\n0xaa,1\n"), 0o644))
+	_, err := NewParquetReader(path)
+	assert.Error(t, err)
+
+	type transfer struct {
+		Token    string `parquet:"token"`
+		Transfer struct {
+			From string `parquet:"from"`
+		} `parquet:"transfer"`
+	}
+	_, err = NewParquetReader(writeParquetFile(t, []transfer{{Token: tokenA.Hex()}}))
+	assert.ErrorContains(t, err, "nested")
+}
+
+func TestFormatDecimal(t *testing.T) {
+	assert.Equal(t, "123.45", formatDecimal(big.NewInt(12345), 2))
+	assert.Equal(t, "-0.005", formatDecimal(big.NewInt(-5), 3))
+	assert.Equal(t, "1000", formatDecimal(big.NewInt(1000), 0))
+}
@@ -0,0 +1,346 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
+)
+
+// fields of the records, the keys of a Mapping
+const (
+	FieldToken                = "token"
+	FieldFrom                 = "from"
+	FieldTo                   = "to"
+	FieldAmount               = "amount"
+	FieldTxHash               = "tx_hash"
+	FieldTxIndex              = "tx_index"
+	FieldBlockNumber          = "block_number"
+	FieldMsgSender            = "msg_sender"
+	FieldIsTransferFrom       = "is_transfer_from"
+	FieldSender               = "sender"
+	FieldReceiver             = "receiver"
+	FieldGasPrice             = "gas_price"
+	FieldMaxFeePerGas         = "max_fee_per_gas"
+	FieldMaxPriorityFeePerGas = "max_priority_fee_per_gas"
+)
+
+// EventColumns are the usual columns of the fields of transfer events, by order of preference: the columns of this
+// repo's exports, of Dune's evt_Transfer and tokens.transfers tables and of BigQuery's token_transfers tables.
+var EventColumns = map[string][]string{
+	FieldToken:       {"token", "contract_address", "token_address"},
+	FieldFrom:        {"from", "from_address", "sender_address", "sender"},
+	FieldTo:          {"to", "to_address", "receiver_address", "receiver"},
+	FieldAmount:      {"amount_raw", "value", "total_tokens_transferred", "amount"},
+	FieldTxHash:      {"tx_hash", "evt_tx_hash", "transaction_hash"},
+	FieldBlockNumber: {"block_number", "evt_block_number"},
+}
+
+// CallColumns are the usual columns of the fields of transfer calls, by order of preference: the columns of the
+// TransferCall exports. Traces are not supported, their from, to and value are the ones of the call, not of the
+// transfer it decodes to: map the columns of exports of decoded calls with a Mapping.
+var CallColumns = map[string][]string{
+	FieldMsgSender:            {"msg_sender"},
+	FieldToken:                {"token", "contract_address", "token_address"},
+	FieldIsTransferFrom:       {"is_transfer_from"},
+	FieldSender:               {"sender"},
+	FieldReceiver:             {"receiver"},
+	FieldAmount:               {"amount"},
+	FieldBlockNumber:          {"block_number"},
+	FieldGasPrice:             {"gas_price"},
+	FieldMaxFeePerGas:         {"max_fee_per_gas"},
+	FieldMaxPriorityFeePerGas: {"max_priority_fee_per_gas"},
+	FieldTxHash:               {"tx_hash", "transaction_hash"},
+	FieldTxIndex:              {"tx_index", "transaction_index"},
+}
+
+// TransferCall result from this query https://dune.com/queries/3038453
+// The query reads the ethereum tables, run it on the tables of another chain for that chain, e.g. bnb.traces.
+// max_fee_per_gas and max_priority_fee_per_gas are empty on chains with legacy gas only.
+// msg_sender,token,is_transfer_from,sender,receiver,amount,block_number,gas_price,max_fee_per_gas,max_priority_fee_per_gas,tx_hash,tx_index
+type TransferCall struct {
+	MsgSender            common.Address `csv:"msg_sender"`
+	Token                common.Address `csv:"token"`
+	IsTransferFrom       bool           `csv:"is_transfer_from"`
+	Sender               common.Address `csv:"sender"`
+	Receiver             common.Address `csv:"receiver"`
+	Amount               *big.Int       `csv:"amount"`
+	BlockNumber          string         `csv:"block_number"`
+	GasPrice             string         `csv:"gas_price"`
+	MaxFeePerGas         string         `csv:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string         `csv:"max_priority_fee_per_gas"`
+	TxHash               common.Hash    `csv:"tx_hash"`
+	TxIndex              uint64         `csv:"tx_index"`
+}
+
+// Scenario returns the scenario simulating the call at its block.
+func (c *TransferCall) Scenario() (*jsonrpc.TransferScenario, error) {
+	blockNumber, ok := new(big.Int).SetString(c.BlockNumber, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid block number %q of tx %s", c.BlockNumber, c.TxHash)
+	}
+	// gas prices are optional
+	gasPrice, _ := new(big.Int).SetString(c.GasPrice, 0)
+	gasFeeCap, _ := new(big.Int).SetString(c.MaxFeePerGas, 0)
+	gasTipCap, _ := new(big.Int).SetString(c.MaxPriorityFeePerGas, 0)
+	return &jsonrpc.TransferScenario{
+		MsgSender:      c.MsgSender,
+		Token:          c.Token,
+		IsTransferFrom: c.IsTransferFrom,
+		From:           c.Sender,
+		To:             c.Receiver,
+		Amount:         c.Amount,
+		BlockNumber:    hexutil.EncodeBig(blockNumber),
+		GasPrice:       gasPrice,
+		GasFeeCap:      gasFeeCap,
+		GasTipCap:      gasTipCap,
+	}, nil
+}
+
+// Options are the options of the streams.
+type Options struct {
+	// Mapping maps fields to the columns of the export, the fields not mapped are read from their usual columns
+	Mapping Mapping
+	// Tokens keeps the rows of these tokens only, all rows are kept if empty
+	Tokens []common.Address
+	// SkipInvalid skips the invalid rows with a warning, the stream fails on the first one otherwise
+	SkipInvalid bool
+}
+
+// TransferCalls streams the transfer calls of the rows of r. is_transfer_from is optional: a call is a transferFrom
+// if its sender is not msg_sender. The error channel receives nil once all rows are read, or the error the stream
+// stopped on.
+func TransferCalls(ctx context.Context, r Reader, opts Options) (<-chan *TransferCall, <-chan error) {
+	return stream(ctx, r, opts, CallColumns, parseTransferCall)
+}
+
+// Scenarios streams the scenarios simulating the transfer calls of the rows of r, see TransferCalls.
+func Scenarios(ctx context.Context, r Reader, opts Options) (<-chan *jsonrpc.TransferScenario, <-chan error) {
+	return stream(ctx, r, opts, CallColumns, func(p *rowParser) *jsonrpc.TransferScenario {
+		call := parseTransferCall(p)
+		if p.err != nil {
+			return nil
+		}
+		s, err := call.Scenario()
+		p.fail(err)
+		return s
+	})
+}
+
+// TransferEvents streams the Transfer events of the rows of r. The token is only needed to filter tokens.
+func TransferEvents(ctx context.Context, r Reader, opts Options) (<-chan *types.TxFromTransferEvent, <-chan error) {
+	return stream(ctx, r, opts, EventColumns, func(p *rowParser) *types.TxFromTransferEvent {
+		return &types.TxFromTransferEvent{
+			From:   p.address(FieldFrom),
+			To:     p.address(FieldTo),
+			TxHash: p.hash(FieldTxHash),
+			Amount: p.number(FieldAmount, true),
+		}
+	})
+}
+
+// TransferRecords streams the rows of r as TransferRecords, the rows of the exports of transfer txs, e.g.
+// erc20_transfer_tx.csv. They have the columns of the Transfer events, see TransferEvents.
+func TransferRecords(ctx context.Context, r Reader, opts Options) (<-chan *types.TransferRecord, <-chan error) {
+	return stream(ctx, r, opts, EventColumns, func(p *rowParser) *types.TransferRecord {
+		return &types.TransferRecord{
+			Sender:      p.address(FieldFrom),
+			Receiver:    p.address(FieldTo),
+			TxHash:      p.hash(FieldTxHash),
+			TotalAmount: p.number(FieldAmount, true),
+		}
+	})
+}
+
+// Collect returns the records of a stream.
+func Collect[T any](records <-chan T, errc <-chan error) ([]T, error) {
+	var collected []T
+	for record := range records {
+		collected = append(collected, record)
+	}
+	return collected, <-errc
+}
+
+func parseTransferCall(p *rowParser) *TransferCall {
+	c := &TransferCall{
+		MsgSender:            p.address(FieldMsgSender),
+		Token:                p.address(FieldToken),
+		Sender:               p.address(FieldSender),
+		Receiver:             p.address(FieldReceiver),
+		Amount:               p.number(FieldAmount, true),
+		BlockNumber:          p.numberString(FieldBlockNumber, true),
+		GasPrice:             p.numberString(FieldGasPrice, false),
+		MaxFeePerGas:         p.numberString(FieldMaxFeePerGas, false),
+		MaxPriorityFeePerGas: p.numberString(FieldMaxPriorityFeePerGas, false),
+		TxHash:               p.hash(FieldTxHash),
+	}
+	var ok bool
+	if c.IsTransferFrom, ok = p.boolean(FieldIsTransferFrom); !ok {
+		c.IsTransferFrom = c.MsgSender != c.Sender
+	}
+	if txIndex := p.number(FieldTxIndex, false); txIndex != nil {
+		if !txIndex.IsUint64() {
+			p.fail(fmt.Errorf("invalid %s %s", FieldTxIndex, txIndex))
+		} else {
+			c.TxIndex = txIndex.Uint64()
+		}
+	}
+	return c
+}
+
+func stream[T any](ctx context.Context, r Reader, opts Options, columns map[string][]string, parse func(p *rowParser) T) (<-chan T, <-chan error) {
+	var (
+		records = make(chan T)
+		errc    = make(chan error, 1)
+	)
+	go func() {
+		defer close(records)
+		errc <- readRecords(ctx, r, opts, columns, parse, records)
+	}()
+	return records, errc
+}
+
+func readRecords[T any](ctx context.Context, r Reader, opts Options, columns map[string][]string, parse func(p *rowParser) T, records chan<- T) error {
+	if err := opts.Mapping.check(columns); err != nil {
+		return err
+	}
+	tokens := make(map[common.Address]struct{}, len(opts.Tokens))
+	for _, token := range opts.Tokens {
+		tokens[token] = struct{}{}
+	}
+
+	var read, skipped int
+	for n := 1; ; n++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read row %d: %w", n, err)
+		}
+		read++
+
+		p := &rowParser{row: row, mapping: opts.Mapping, columns: columns}
+		if len(tokens) > 0 {
+			if _, ok := p.mapping.value(row, columns, FieldToken); !ok {
+				return fmt.Errorf("could not filter tokens: no %s column", FieldToken)
+			}
+			token := p.address(FieldToken)
+			if _, ok := tokens[token]; !ok && p.err == nil {
+				continue
+			}
+		}
+		record := parse(p)
+		if p.err != nil {
+			err := fmt.Errorf("%w %d: %v", ErrInvalidRow, n, p.err)
+			if !opts.SkipInvalid {
+				return err
+			}
+			logger.Warnw("skipping row", "error", err)
+			skipped++
+			continue
+		}
+
+		select {
+		case records <- record:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if skipped > 0 {
+		logger.Infow("skipped invalid rows", "rows", read, "skipped", skipped)
+	}
+	return nil
+}
+
+// rowParser parses the fields of a row, keeping the first error.
+type rowParser struct {
+	row     Row
+	mapping Mapping
+	columns map[string][]string
+	err     error
+}
+
+func (p *rowParser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// value returns the value of a field, failing if it's required and missing or empty.
+func (p *rowParser) value(field string, required bool) string {
+	v, ok := p.mapping.value(p.row, p.columns, field)
+	if required && (!ok || v == "") {
+		p.fail(fmt.Errorf("missing %s", field))
+	}
+	return v
+}
+
+func (p *rowParser) address(field string) common.Address {
+	v := p.value(field, true)
+	if v != "" && !common.IsHexAddress(v) {
+		p.fail(fmt.Errorf("invalid %s %q", field, v))
+	}
+	return common.HexToAddress(v)
+}
+
+func (p *rowParser) hash(field string) common.Hash {
+	v := p.value(field, true)
+	if b, err := hexutil.Decode(v); v != "" && (err != nil || len(b) != common.HashLength) {
+		p.fail(fmt.Errorf("invalid %s %q", field, v))
+	}
+	return common.HexToHash(v)
+}
+
+// number parses a non-negative integer, decimal or hex. Exports of numeric columns can have a zero fraction, e.g.
+// 1000.0, which is dropped. It returns nil for an empty optional field.
+func (p *rowParser) number(field string, required bool) *big.Int {
+	v := p.value(field, required)
+	if v == "" {
+		return nil
+	}
+	s := v
+	if integer, fraction, ok := strings.Cut(s, "."); ok && strings.Trim(fraction, "0") == "" {
+		s = integer
+	}
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	n, ok := new(big.Int).SetString(s, base)
+	if !ok || n.Sign() < 0 {
+		p.fail(fmt.Errorf("invalid %s %q", field, v))
+		return nil
+	}
+	return n
+}
+
+// numberString returns a number as a decimal string, empty for an empty optional field.
+func (p *rowParser) numberString(field string, required bool) string {
+	if n := p.number(field, required); n != nil {
+		return n.String()
+	}
+	return ""
+}
+
+// boolean parses a bool and returns whether the field is set.
+func (p *rowParser) boolean(field string) (bool, bool) {
+	v := p.value(field, false)
+	if v == "" {
+		return false, false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail(fmt.Errorf("invalid %s %q", field, v))
+	}
+	return b, err == nil
+}
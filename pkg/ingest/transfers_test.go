@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/types"
+)
+
+var (
+	tokenA = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tokenB = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	alice  = common.HexToAddress("0x0000000000000000000000000000000000000001")
+	bob    = common.HexToAddress("0x0000000000000000000000000000000000000002")
+	router = common.HexToAddress("0x0000000000000000000000000000000000000003")
+	txHash = common.HexToHash("0x01")
+)
+
+func newCSV(t *testing.T, s string) Reader {
+	r, err := NewCSVReader(strings.NewReader(s))
+	require.NoError(t, err)
+	return r
+}
+
+func TestTransferEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   Reader
+		opts    Options
+		want    []*types.TxFromTransferEvent
+		wantErr error
+	}{
+		{
+			name: "legacy export",
+			input: newCSV(t, "sender_address,receiver_address,tx_hash,total_tokens_transferred\n"+
+				alice.Hex()+","+bob.Hex()+","+txHash.Hex()+",100\n"),
+			want: []*types.TxFromTransferEvent{{From: alice, To: bob, TxHash: txHash, Amount: big.NewInt(100)}},
+		},
+		{
+			name: "Dune evt_Transfer filtered on a token",
+			input: newCSV(t, "contract_address,evt_tx_hash,evt_block_number,from,to,value\n"+
+				tokenA.Hex()+","+txHash.Hex()+",1,"+alice.Hex()+","+bob.Hex()+",100\n"+
+				tokenB.Hex()+","+txHash.Hex()+",1,"+alice.Hex()+","+bob.Hex()+",200\n"),
+			opts: Options{Tokens: []common.Address{tokenB}},
+			want: []*types.TxFromTransferEvent{{From: alice, To: bob, TxHash: txHash, Amount: big.NewInt(200)}},
+		},
+		{
+			name: "BigQuery token_transfers",
+			input: NewJSONLReader(strings.NewReader(`{"token_address":"` + tokenA.Hex() + `","from_address":"` + alice.Hex() +
+				`","to_address":"` + bob.Hex() + `","value":"1000.000","transaction_hash":"` + txHash.Hex() + `"}`)),
+			want: []*types.TxFromTransferEvent{{From: alice, To: bob, TxHash: txHash, Amount: big.NewInt(1000)}},
+		},
+		{
+			name: "mapped columns",
+			input: newCSV(t, "src,dst,hash,raw\n"+
+				alice.Hex()+","+bob.Hex()+","+txHash.Hex()+",0x64\n"),
+			opts: Options{Mapping: Mapping{FieldFrom: "src", FieldTo: "dst", FieldTxHash: "hash", FieldAmount: "raw"}},
+			want: []*types.TxFromTransferEvent{{From: alice, To: bob, TxHash: txHash, Amount: big.NewInt(100)}},
+		},
+		{
+			name: "invalid amount",
+			input: newCSV(t, "from,to,tx_hash,amount\n"+
+				alice.Hex()+","+bob.Hex()+","+txHash.Hex()+",1.5\n"),
+			wantErr: ErrInvalidRow,
+		},
+		{
+			name: "skipped invalid rows",
+			input: newCSV(t, "from,to,tx_hash,amount\n"+
+				alice.Hex()+",0x02,"+txHash.Hex()+",100\n"+
+				alice.Hex()+","+bob.Hex()+",,100\n"+
+				alice.Hex()+","+bob.Hex()+","+txHash.Hex()+",-1\n"+
+				alice.Hex()+","+bob.Hex()+","+txHash.Hex()+",100\n"),
+			opts: Options{SkipInvalid: true},
+			want: []*types.TxFromTransferEvent{{From: alice, To: bob, TxHash: txHash, Amount: big.NewInt(100)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Collect(TransferEvents(context.Background(), tt.input, tt.opts))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTransferEventsErrors(t *testing.T) {
+	input := "from,to,tx_hash,amount\n" + alice.Hex() + "," + bob.Hex() + "," + txHash.Hex() + ",100\n"
+
+	_, err := Collect(TransferEvents(context.Background(), newCSV(t, input), Options{Tokens: []common.Address{tokenA}}))
+	assert.ErrorContains(t, err, "no token column")
+
+	_, err = Collect(TransferEvents(context.Background(), newCSV(t, input), Options{Mapping: Mapping{"value": "amount"}}))
+	assert.ErrorContains(t, err, "unknown field")
+}
+
+func TestTransferRecords(t *testing.T) {
+	input := "sender_address,receiver_address,tx_hash,total_tokens_transferred\n" +
+		alice.Hex() + "," + bob.Hex() + "," + txHash.Hex() + ",100\n"
+
+	got, err := Collect(TransferRecords(context.Background(), newCSV(t, input), Options{}))
+	require.NoError(t, err)
+	assert.Equal(t, []*types.TransferRecord{{Sender: alice, Receiver: bob, TxHash: txHash, TotalAmount: big.NewInt(100)}}, got)
+}
+
+func TestTransferCalls(t *testing.T) {
+	input := "msg_sender,token,sender,receiver,amount,block_number,gas_price,max_fee_per_gas,max_priority_fee_per_gas,tx_hash,tx_index\n" +
+		router.Hex() + "," + tokenA.Hex() + "," + alice.Hex() + "," + bob.Hex() + ",100,17000000,,30000000000,1000000000," + txHash.Hex() + ",4\n" +
+		alice.Hex() + "," + tokenA.Hex() + "," + alice.Hex() + "," + bob.Hex() + ",100,0x1036640,,,," + txHash.Hex() + ",\n"
+
+	calls, err := Collect(TransferCalls(context.Background(), newCSV(t, input), Options{}))
+	require.NoError(t, err)
+	assert.Equal(t, []*TransferCall{
+		{
+			MsgSender:            router,
+			Token:                tokenA,
+			IsTransferFrom:       true,
+			Sender:               alice,
+			Receiver:             bob,
+			Amount:               big.NewInt(100),
+			BlockNumber:          "17000000",
+			MaxFeePerGas:         "30000000000",
+			MaxPriorityFeePerGas: "1000000000",
+			TxHash:               txHash,
+			TxIndex:              4,
+		},
+		{
+			MsgSender:   alice,
+			Token:       tokenA,
+			Sender:      alice,
+			Receiver:    bob,
+			Amount:      big.NewInt(100),
+			BlockNumber: "17000000",
+			TxHash:      txHash,
+		},
+	}, calls)
+
+	scenarios, err := Collect(Scenarios(context.Background(), newCSV(t, input), Options{}))
+	require.NoError(t, err)
+	require.Len(t, scenarios, 2)
+	assert.Equal(t, "0x1036640", scenarios[0].BlockNumber)
+	assert.True(t, scenarios[0].IsTransferFrom)
+	assert.Equal(t, big.NewInt(30000000000), scenarios[0].GasFeeCap)
+	assert.Nil(t, scenarios[1].GasFeeCap)
+}
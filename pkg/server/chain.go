@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/chainprofile"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+)
+
+// Chain is what the server needs to classify the tokens of a chain.
+type Chain struct {
+	Client  jsonrpc.Client
+	Profile *chainprofile.Profile
+	// Classifier classifies tokens from their recent Transfer events
+	Classifier classifier.Classifier
+	// Batch simulates the transfer scenarios of new tokens
+	Batch *classifier.BatchClassifier
+	// Fetcher fetches the Transfer events of tokens
+	Fetcher *fetcher.Fetcher
+	// TxsThreshold is the number of recent Transfer events fetched to classify a token
+	TxsThreshold int
+
+	storage *classifier.StorageTraceClassifier
+}
+
+// NewChain rpcClient is either a *rpc.Client or a *jsonrpc.Pool to use several endpoints, its nodes must support
+// debug_traceTransaction and debug_traceCall with prestateTracer. profile is the chain of the nodes, Ethereum if nil.
+func NewChain(rpcClient jsonrpc.Client, profile *chainprofile.Profile, fetcherConfig fetcher.Config, batchConfig classifier.BatchClassifierConfig, txsThreshold int) *Chain {
+	if profile == nil {
+		profile = chainprofile.Ethereum
+	}
+	clz := classifier.NewClassifierWithGas(rpcClient, nil, profile.GasConfig())
+	return &Chain{
+		Client:       rpcClient,
+		Profile:      profile,
+		Classifier:   clz,
+		Batch:        classifier.NewBatchClassifier(clz, batchConfig),
+		Fetcher:      fetcher.NewFetcher(rpcClient, profile.FetcherConfig(fetcherConfig)),
+		TxsThreshold: txsThreshold,
+		storage:      clz,
+	}
+}
+
+// CheckChainID fails if the nodes are not on the chain of the profile.
+func (c *Chain) CheckChainID() error {
+	chainID, err := jsonrpc.ChainID(c.Client)
+	if err != nil {
+		return fmt.Errorf("could not get chain ID: %w", err)
+	}
+	if chainID != c.Profile.ChainID {
+		return fmt.Errorf("nodes of %s are on chain ID %d, expected %d", c.Profile.Name, chainID, c.Profile.ChainID)
+	}
+	return nil
+}
+
+// IsErc20 returns true if token has code implementing ERC20.
+func (c *Chain) IsErc20(token common.Address) (bool, error) {
+	code, err := jsonrpc.GetCode(c.Client, token, "latest")
+	if err != nil {
+		return false, fmt.Errorf("could not get code: %w", err)
+	}
+	return len(code) > 0 && c.Classifier.IsErc20(token, code), nil
+}
+
+// ClassifyScenarios classifies a new token by simulating transfer scenarios, the token of the scenarios is set to token.
+func (c *Chain) ClassifyScenarios(ctx context.Context, token common.Address, scenarios []*jsonrpc.TransferScenario) *classifier.BatchResult {
+	for _, s := range scenarios {
+		s.Token = token
+	}
+	return c.Batch.ClassifyNewTokens(ctx, scenarios)[token]
+}
+
+// ClassifyLogs classifies a token from its recent Transfer events.
+func (c *Chain) ClassifyLogs(ctx context.Context, token common.Address) (classifier.FeeOnTransferResult, error) {
+	logs, err := c.recentTransferLogs(ctx, token)
+	if err != nil {
+		return classifier.FeeOnTransferResult{}, err
+	}
+	return c.Classifier.IsFeeOnTransfer(token, logs)
+}
+
+// classify classifies the token of a job, with its scenarios if any or else with its recent Transfer events.
+func (c *Chain) classify(ctx context.Context, job *Job) (*Verdict, error) {
+	verdict, err := c.newVerdict(job.Chain, job.Token)
+	if err != nil || !verdict.IsErc20 {
+		return verdict, err
+	}
+	if len(job.Scenarios) > 0 {
+		return verdict, c.classifyScenarios(ctx, verdict, job.Scenarios)
+	}
+	return verdict, c.classifyLogs(ctx, verdict)
+}
+
+// reclassify classifies the token of prev again at the head block: from its pool if prev has one, or else from its
+// recent Transfer events. The scenarios of a verdict without a pool are not stored, it is then classified from its
+// Transfer events too and DiffVerdicts does not compare the fees of both methods.
+func (c *Chain) reclassify(ctx context.Context, prev *Verdict) (*Verdict, error) {
+	verdict, err := c.newVerdict(prev.Chain, prev.Token)
+	if err != nil || !verdict.IsErc20 {
+		return verdict, err
+	}
+	if prev.Pool == nil {
+		return verdict, c.classifyLogs(ctx, verdict)
+	}
+	verdict.Pool = prev.Pool
+	scenarios, err := c.SyntheticScenarios(prev.Token, []common.Address{*prev.Pool}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return verdict, c.classifyScenarios(ctx, verdict, scenarios)
+}
+
+// newVerdict checks the code of a token, the verdict of a contract that is not ERC20 is final.
+func (c *Chain) newVerdict(chain string, token common.Address) (*Verdict, error) {
+	verdict := &Verdict{
+		Chain:     chain,
+		Token:     token,
+		Method:    MethodCode,
+		UpdatedAt: time.Now(),
+	}
+	isErc20, err := c.IsErc20(token)
+	if err != nil {
+		return nil, err
+	}
+	if !isErc20 {
+		return verdict, nil
+	}
+	verdict.IsErc20 = true
+	if verdict.BlockNumber, err = jsonrpc.BlockNumber(c.Client); err != nil {
+		return nil, fmt.Errorf("could not get block number: %w", err)
+	}
+	implementation, err := classifier.ProxyImplementation(c.Client, token, hexutil.EncodeUint64(verdict.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	if implementation != (common.Address{}) {
+		verdict.Implementation = &implementation
+	}
+	return verdict, nil
+}
+
+func (c *Chain) classifyScenarios(ctx context.Context, verdict *Verdict, scenarios []*jsonrpc.TransferScenario) error {
+	verdict.Method = MethodScenarios
+	if err := c.Profile.CheckClassification(); err != nil {
+		return err
+	}
+	result := c.ClassifyScenarios(ctx, verdict.Token, scenarios)
+	if result.Err != nil {
+		return result.Err
+	}
+	verdict.IsFeeOnTransfer = result.IsFeeOnTransfer
+	verdict.FeeBps = result.FeeBps
+	for _, s := range scenarios {
+		verdict.Evidence = append(verdict.Evidence, scenarioEvidence(s))
+	}
+	if verdict.Pool != nil {
+		verdict.IsHoneypot = c.ProbeHoneypot(*verdict.Pool, scenarios)
+	}
+	return nil
+}
+
+func (c *Chain) classifyLogs(ctx context.Context, verdict *Verdict) error {
+	verdict.Method = MethodLogs
+	if err := c.Profile.CheckClassification(); err != nil {
+		return err
+	}
+	logs, err := c.recentTransferLogs(ctx, verdict.Token)
+	if err != nil {
+		return err
+	}
+	result, err := c.Classifier.IsFeeOnTransfer(verdict.Token, logs)
+	if err != nil {
+		return err
+	}
+	seen := make(map[common.Hash]bool)
+	for _, l := range logs {
+		if !seen[l.TxHash] {
+			seen[l.TxHash] = true
+			verdict.Evidence = append(verdict.Evidence, "tx:"+l.TxHash.Hex())
+		}
+	}
+	verdict.IsFeeOnTransfer = result.IsFeeOnTransfer
+	if result.IsFeeOnTransfer {
+		verdict.FeeBps = result.FeeBps()
+		verdict.FeeReceiver = &result.FeeReceiver
+		verdict.Formula = result.Formular
+	}
+	return nil
+}
+
+// scenarioEvidence references a simulated transfer in the evidence of a verdict.
+func scenarioEvidence(s *jsonrpc.TransferScenario) string {
+	blockNumber := s.BlockNumber
+	if blockNumber == "" {
+		blockNumber = "latest"
+	}
+	return fmt.Sprintf("transfer:%s->%s:%s@%s", s.From.Hex(), s.To.Hex(), s.Amount, blockNumber)
+}
+
+// ErrNoHolder is returned when no holder of a token has a balance to simulate transfers with
+var ErrNoHolder = errors.New("no holder has a balance")
+
+// scenarioShares are the shares of the balance of a holder transferred by the synthetic scenarios, small enough to
+// stay under the max-transaction limits of most new tokens
+var scenarioShares = []int64{1000, 100}
+
+// SyntheticScenarios returns transfers of shares of the balance of each holder of a token to random wallets, for new
+// tokens without Transfer events to classify them from. They are simulated on top of blockNumber, the head block if 0.
+func (c *Chain) SyntheticScenarios(token common.Address, holders []common.Address, blockNumber uint64) ([]*jsonrpc.TransferScenario, error) {
+	var blockNumberHex, scenarioBlockNumber = "latest", ""
+	if blockNumber > 0 {
+		blockNumberHex = hexutil.EncodeUint64(blockNumber)
+		scenarioBlockNumber = blockNumberHex
+	}
+	requests := make([]jsonrpc.EthCallRequest, len(holders))
+	for i, holder := range holders {
+		data, err := abis.ERC20.Pack("balanceOf", holder)
+		if err != nil {
+			return nil, err
+		}
+		requests[i] = jsonrpc.EthCallRequest{
+			Calldata: &jsonrpc.EthCallCalldataParam{
+				From: common.Address{}.String(),
+				To:   token.String(),
+				Data: hexutil.Encode(data),
+			},
+			BlockNumber: blockNumberHex,
+		}
+	}
+	results, err := jsonrpc.BatchEthCall(c.Client, requests)
+	if err != nil {
+		return nil, fmt.Errorf("could not get balances of holders: %w", err)
+	}
+
+	var scenarios []*jsonrpc.TransferScenario
+	for i, holder := range holders {
+		if results[i].Err != nil {
+			continue
+		}
+		output, err := hexutil.Decode(results[i].Result)
+		if err != nil {
+			continue
+		}
+		balance := new(big.Int).SetBytes(output)
+		for _, share := range scenarioShares {
+			amount := new(big.Int).Div(balance, big.NewInt(share))
+			if amount.Sign() == 0 {
+				continue
+			}
+			scenarios = append(scenarios, &jsonrpc.TransferScenario{
+				MsgSender:   holder,
+				Token:       token,
+				From:        holder,
+				To:          randomAddress(),
+				Amount:      amount,
+				BlockNumber: scenarioBlockNumber,
+			})
+		}
+	}
+	if len(scenarios) == 0 {
+		return nil, ErrNoHolder
+	}
+	return scenarios, nil
+}
+
+// ProbeHoneypot probes the scenarios transferring from pool, i.e. buys, for honeypots with
+// classifier.ProbeHoneypot. It returns nil if none could be probed.
+func (c *Chain) ProbeHoneypot(pool common.Address, scenarios []*jsonrpc.TransferScenario) *bool {
+	for _, s := range scenarios {
+		if s.MsgSender != pool {
+			continue
+		}
+		result, err := c.storage.ProbeHoneypot(s)
+		if err != nil {
+			logger.Debugw("could not probe honeypot", "token", s.Token, "error", err)
+			continue
+		}
+		return &result.IsHoneypot
+	}
+	return nil
+}
+
+func randomAddress() common.Address {
+	var address common.Address
+	rand.Read(address[:])
+	return address
+}
+
+// recentTransferLogs fetches the most recent Transfer events of a token, until TxsThreshold events or its creation block.
+func (c *Chain) recentTransferLogs(ctx context.Context, token common.Address) ([]ethtypes.Log, error) {
+	topics := [][]common.Hash{{abis.ERC20.Events["Transfer"].ID}}
+	logs, err := c.Fetcher.RecentLogs(ctx, token, topics, c.TxsThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transfer events: %w", err)
+	}
+	return logs, ctx.Err()
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ChangeKind is what changed between two verdicts of a token
+type ChangeKind string
+
+const (
+	ChangeErc20          ChangeKind = "erc20"
+	ChangeFeeOnTransfer  ChangeKind = "fee-on-transfer"
+	ChangeFeeRate        ChangeKind = "fee-rate"
+	ChangeHoneypot       ChangeKind = "honeypot"
+	ChangeImplementation ChangeKind = "implementation"
+)
+
+// Change is a difference between two verdicts of a token.
+type Change struct {
+	Kind        ChangeKind `json:"kind"`
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	Description string     `json:"description"`
+}
+
+// ChangeEvent is emitted when a token classified again got a different verdict.
+type ChangeEvent struct {
+	Chain      string         `json:"chain"`
+	Token      common.Address `json:"token"`
+	Changes    []Change       `json:"changes"`
+	Previous   *Verdict       `json:"previous"`
+	Current    *Verdict       `json:"current"`
+	DetectedAt time.Time      `json:"detectedAt"`
+}
+
+// DiffVerdicts returns the changes from prev to cur, two verdicts of the same token. The fees of verdicts of different
+// methods are not compared: a fee simulated on a few transfers and the fee of historical transfers can differ while
+// the token does not change.
+func DiffVerdicts(prev, cur *Verdict) []Change {
+	var changes []Change
+	if prev.IsErc20 != cur.IsErc20 {
+		description := "token is no longer ERC20"
+		if cur.IsErc20 {
+			description = "contract became ERC20"
+		}
+		changes = append(changes, boolChange(ChangeErc20, prev.IsErc20, cur.IsErc20, description))
+	}
+	if !prev.IsErc20 || !cur.IsErc20 {
+		return changes
+	}
+
+	if prev.Method == cur.Method {
+		changes = append(changes, diffFees(prev, cur)...)
+	}
+	// a honeypot verdict is only compared once known, an unknown one is no news
+	if cur.IsHoneypot != nil && (prev.IsHoneypot == nil && *cur.IsHoneypot || prev.IsHoneypot != nil && *prev.IsHoneypot != *cur.IsHoneypot) {
+		description := "token is no longer a honeypot"
+		if *cur.IsHoneypot {
+			description = "token became a honeypot"
+		}
+		from := "unknown"
+		if prev.IsHoneypot != nil {
+			from = fmt.Sprint(*prev.IsHoneypot)
+		}
+		changes = append(changes, Change{
+			Kind:        ChangeHoneypot,
+			From:        from,
+			To:          fmt.Sprint(*cur.IsHoneypot),
+			Description: description,
+		})
+	}
+	if from, to := addressString(prev.Implementation), addressString(cur.Implementation); from != to {
+		changes = append(changes, Change{
+			Kind:        ChangeImplementation,
+			From:        from,
+			To:          to,
+			Description: fmt.Sprintf("proxy implementation changed from %s to %s", from, to),
+		})
+	}
+	return changes
+}
+
+func diffFees(prev, cur *Verdict) []Change {
+	var changes []Change
+	if prev.IsFeeOnTransfer != cur.IsFeeOnTransfer {
+		description := "token is no longer fee-on-transfer"
+		if cur.IsFeeOnTransfer {
+			description = "token became fee-on-transfer"
+		}
+		changes = append(changes, boolChange(ChangeFeeOnTransfer, prev.IsFeeOnTransfer, cur.IsFeeOnTransfer, description))
+	}
+	if prev.FeeBps != cur.FeeBps {
+		changes = append(changes, Change{
+			Kind:        ChangeFeeRate,
+			From:        fmt.Sprint(prev.FeeBps),
+			To:          fmt.Sprint(cur.FeeBps),
+			Description: fmt.Sprintf("fee rate changed from %d to %d bps", prev.FeeBps, cur.FeeBps),
+		})
+	}
+	return changes
+}
+
+func boolChange(kind ChangeKind, from, to bool, description string) Change {
+	return Change{
+		Kind:        kind,
+		From:        fmt.Sprint(from),
+		To:          fmt.Sprint(to),
+		Description: description,
+	}
+}
+
+func addressString(address *common.Address) string {
+	if address == nil {
+		return "none"
+	}
+	return address.Hex()
+}
+
+// ChangeSink receives the change events of a Scheduler.
+type ChangeSink interface {
+	Put(ctx context.Context, event *ChangeEvent) error
+}
+
+// ChangeSinkFunc is a ChangeSink calling a function.
+type ChangeSinkFunc func(ctx context.Context, event *ChangeEvent) error
+
+func (f ChangeSinkFunc) Put(ctx context.Context, event *ChangeEvent) error {
+	return f(ctx, event)
+}
+
+// WebhookSink POSTs change events as JSON to a URL, retrying on transport errors and 5xx responses.
+type WebhookSink struct {
+	url        string
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// NewWebhookSink uses http.DefaultClient if client is nil.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookSink{
+		url:        url,
+		client:     client,
+		maxRetries: 3,
+		backoff:    time.Second,
+	}
+}
+
+func (s *WebhookSink) Put(ctx context.Context, event *ChangeEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for retry := 0; ; retry++ {
+		retryable, err := s.post(ctx, body)
+		if err == nil || !retryable || retry >= s.maxRetries {
+			return err
+		}
+		logger.Debugw("retrying webhook", "retry", retry+1, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.backoff << retry):
+		}
+	}
+}
+
+func (s *WebhookSink) post(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("could not post change event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Publisher publishes messages to a message bus topic, e.g. a Kafka, NATS or Pub/Sub client.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key, payload []byte) error
+}
+
+// PublisherSink publishes change events as JSON to a message bus topic, keyed by chain and token so the events of a
+// token stay ordered.
+type PublisherSink struct {
+	publisher Publisher
+	topic     string
+}
+
+func NewPublisherSink(publisher Publisher, topic string) *PublisherSink {
+	return &PublisherSink{
+		publisher: publisher,
+		topic:     topic,
+	}
+}
+
+func (s *PublisherSink) Put(ctx context.Context, event *ChangeEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := s.publisher.Publish(ctx, s.topic, []byte(verdictKey(event.Chain, event.Token)), payload); err != nil {
+		return fmt.Errorf("could not publish change event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// JobStatus is the state of a classification job
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// classification methods of a Verdict
+const (
+	// MethodLogs classifies a token from its recent Transfer events with the chain's Classifier
+	MethodLogs = "logs"
+	// MethodScenarios classifies a new token by simulating transfer scenarios
+	MethodScenarios = "scenarios"
+	// MethodCode only checks the code, for contracts that are not ERC20
+	MethodCode = "code"
+	// MethodOverride is a verdict pinned by an Override of a token never classified
+	MethodOverride = "override"
+)
+
+// Verdict is the classification of a token.
+type Verdict struct {
+	Chain           string         `json:"chain"`
+	Token           common.Address `json:"token"`
+	IsErc20         bool           `json:"isErc20"`
+	IsFeeOnTransfer bool           `json:"isFeeOnTransfer"`
+	// FeeBps is the proportional fee in basis points, 0 if unknown
+	FeeBps int `json:"feeBps"`
+	// FeeReceiver and Formula are only known by MethodLogs
+	FeeReceiver *common.Address `json:"feeReceiver,omitempty"`
+	Formula     string          `json:"formula,omitempty"`
+	// Pool is a pool holding the token, its transfers to buyers are simulated when the token is classified again
+	Pool *common.Address `json:"pool,omitempty"`
+	// IsHoneypot is only known for tokens classified with a pool holding them, nil otherwise
+	IsHoneypot *bool `json:"isHoneypot,omitempty"`
+	// Implementation is set if the token is an EIP-1967 proxy
+	Implementation *common.Address `json:"implementation,omitempty"`
+	Method         string          `json:"method"`
+	// BlockNumber is the block the token was classified at, 0 if unknown
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	// Evidence references what the verdict is based on: tx:<hash> for Transfer events, transfer:<from>-><to>:<amount>@<block>
+	// for simulated transfers
+	Evidence []string `json:"evidence,omitempty"`
+	// Override is set if the verdict was pinned by an override, the other fields hold the pinned values
+	Override  *Override `json:"override,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ClassifyRequest is the body of POST /classify.
+type ClassifyRequest struct {
+	Chain string         `json:"chain"`
+	Token common.Address `json:"token"`
+	// Scenarios are transfers of Token to simulate, for new tokens without enough Transfer events. Without
+	// scenarios, the token is classified from its recent Transfer events.
+	Scenarios []*jsonrpc.TransferScenario `json:"scenarios,omitempty"`
+}
+
+// Job is an asynchronous classification of a token.
+type Job struct {
+	ID        string                      `json:"id"`
+	Chain     string                      `json:"chain"`
+	Token     common.Address              `json:"token"`
+	Scenarios []*jsonrpc.TransferScenario `json:"scenarios,omitempty"`
+	Status    JobStatus                   `json:"status"`
+	// Verdict is set once the job is done
+	Verdict *Verdict `json:"verdict,omitempty"`
+	// Error is set if the job failed
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Pending returns true if the job is queued or running.
+func (j *Job) Pending() bool {
+	return j.Status == JobQueued || j.Status == JobRunning
+}
+
+func newJobID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// sortJobs sorts jobs by creation so they are run again in order after a restart.
+func sortJobs(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
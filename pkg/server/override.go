@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Override pins the verdict of a token, for tokens the classifiers get wrong, e.g. rebasing tokens like stETH or
+// fee-on-transfer tokens exempting our router from the fee.
+type Override struct {
+	Chain           string         `json:"chain"`
+	Token           common.Address `json:"token"`
+	IsFeeOnTransfer bool           `json:"isFeeOnTransfer"`
+	// FeeBps is the fee charged to us in basis points
+	FeeBps int `json:"feeBps"`
+	// Reason tells why the classification is overridden
+	Reason string `json:"reason"`
+	// Author is who pinned the verdict
+	Author string `json:"author,omitempty"`
+	// ExpiresAt is when the token is classified again, never if nil
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Active returns whether the override applies at now.
+func (o *Override) Active(now time.Time) bool {
+	return o.ExpiresAt == nil || now.Before(*o.ExpiresAt)
+}
+
+// Apply returns a copy of verdict with the pinned values of o and the override reported. The fee receiver, formula
+// and honeypot result of verdict are cleared since they may contradict the pinned fee. An override does not tell
+// whether the token is ERC20: IsErc20 is kept from verdict, and is false for tokens never classified (verdict nil).
+func (o *Override) Apply(verdict *Verdict) *Verdict {
+	var overridden Verdict
+	if verdict != nil {
+		overridden = *verdict
+	} else {
+		overridden = Verdict{
+			Chain:     o.Chain,
+			Token:     o.Token,
+			Method:    MethodOverride,
+			UpdatedAt: o.CreatedAt,
+		}
+	}
+	overridden.FeeReceiver = nil
+	overridden.Formula = ""
+	overridden.IsHoneypot = nil
+	overridden.IsFeeOnTransfer = o.IsFeeOnTransfer
+	overridden.FeeBps = o.FeeBps
+	overridden.Override = o
+	return &overridden
+}
+
+func (o *Override) validate() error {
+	switch {
+	case o.Chain == "":
+		return errors.New("missing chain")
+	case o.Token == (common.Address{}):
+		return errors.New("missing token")
+	case o.Reason == "":
+		return errors.New("missing reason")
+	case o.FeeBps < 0 || o.FeeBps > 10000:
+		return fmt.Errorf("fee %d bps out of range", o.FeeBps)
+	case o.FeeBps > 0 && !o.IsFeeOnTransfer:
+		return fmt.Errorf("fee %d bps but not fee-on-transfer", o.FeeBps)
+	}
+	return nil
+}
+
+// Overrides looks up the overrides of tokens.
+type Overrides interface {
+	// GetOverride returns the override of a token, expired or not, ErrNotFound if there is none
+	GetOverride(chain string, token common.Address) (*Override, error)
+}
+
+// ActiveOverride returns the override of a token active at now, nil if there is none. overrides may be nil.
+func ActiveOverride(overrides Overrides, chain string, token common.Address, now time.Time) (*Override, error) {
+	if overrides == nil {
+		return nil, nil
+	}
+	var (
+		override *Override
+		err      error
+	)
+	if multi, ok := overrides.(MultiOverrides); ok {
+		override, err = multi.getOverride(chain, token, now)
+	} else {
+		override, err = overrides.GetOverride(chain, token)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get override: %w", err)
+	}
+	if !override.Active(now) {
+		return nil, nil
+	}
+	return override, nil
+}
+
+// MultiOverrides looks up overrides in order, the first active one wins. An expired override doesn't hide the ones
+// after it, it is only returned if none of them is active.
+type MultiOverrides []Overrides
+
+func (m MultiOverrides) GetOverride(chain string, token common.Address) (*Override, error) {
+	return m.getOverride(chain, token, time.Now())
+}
+
+// getOverride returns the first override active at now, else the first expired one.
+func (m MultiOverrides) getOverride(chain string, token common.Address, now time.Time) (*Override, error) {
+	var expired *Override
+	for _, overrides := range m {
+		override, err := overrides.GetOverride(chain, token)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if override.Active(now) {
+			return override, nil
+		}
+		if expired == nil {
+			expired = override
+		}
+	}
+	if expired == nil {
+		return nil, ErrNotFound
+	}
+	return expired, nil
+}
+
+// OverrideList keeps overrides in memory, it is never modified so it is safe for concurrent use.
+type OverrideList struct {
+	overrides map[string]*Override
+}
+
+// NewOverrideList fails if an override is invalid or a token is overridden twice.
+func NewOverrideList(overrides []*Override) (*OverrideList, error) {
+	l := &OverrideList{
+		overrides: make(map[string]*Override, len(overrides)),
+	}
+	for i, override := range overrides {
+		if err := override.validate(); err != nil {
+			return nil, fmt.Errorf("invalid override %d: %w", i, err)
+		}
+		key := verdictKey(override.Chain, override.Token)
+		if _, ok := l.overrides[key]; ok {
+			return nil, fmt.Errorf("token %s overridden twice", key)
+		}
+		l.overrides[key] = override
+	}
+	return l, nil
+}
+
+// LoadOverrides reads a JSON array of overrides from a file.
+func LoadOverrides(path string) (*OverrideList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read overrides: %w", err)
+	}
+	var overrides []*Override
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("could not decode overrides %s: %w", path, err)
+	}
+	l, err := NewOverrideList(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("could not load overrides %s: %w", path, err)
+	}
+	return l, nil
+}
+
+func (l *OverrideList) GetOverride(chain string, token common.Address) (*Override, error) {
+	override, ok := l.overrides[verdictKey(chain, token)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return override, nil
+}
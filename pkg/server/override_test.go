@@ -0,0 +1,205 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `[{"chain": "ethereum", "token": "0x0000000000000000000000000000000000000001", "isFeeOnTransfer": true, "feeBps": 300, "reason": "tax"}]`, ""},
+		{"missing reason", `[{"chain": "ethereum", "token": "0x0000000000000000000000000000000000000001"}]`, "missing reason"},
+		{"fee without fot", `[{"chain": "ethereum", "token": "0x0000000000000000000000000000000000000001", "feeBps": 300, "reason": "tax"}]`, "not fee-on-transfer"},
+		{
+			"twice",
+			`[{"chain": "ethereum", "token": "0x0000000000000000000000000000000000000001", "reason": "a"}, {"chain": "ethereum", "token": "0x0000000000000000000000000000000000000001", "reason": "b"}]`,
+			"overridden twice",
+		},
+		{"invalid json", `{`, "could not decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "overrides.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			overrides, err := LoadOverrides(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			override, err := overrides.GetOverride("ethereum", common.HexToAddress("0x01"))
+			require.NoError(t, err)
+			assert.Equal(t, 300, override.FeeBps)
+			_, err = overrides.GetOverride("bsc", common.HexToAddress("0x01"))
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestActiveOverride(t *testing.T) {
+	var (
+		now     = time.Now()
+		expired = now.Add(-time.Hour)
+		later   = now.Add(time.Hour)
+		token   = common.HexToAddress("0x01")
+		other   = common.HexToAddress("0x02")
+		renewed = common.HexToAddress("0x03")
+	)
+	file, err := NewOverrideList([]*Override{
+		{Chain: "ethereum", Token: token, Reason: "file", ExpiresAt: &later},
+		{Chain: "ethereum", Token: other, Reason: "expired", ExpiresAt: &expired},
+		{Chain: "ethereum", Token: renewed, Reason: "expired", ExpiresAt: &expired},
+	})
+	require.NoError(t, err)
+	store, err := NewOverrideList([]*Override{
+		{Chain: "ethereum", Token: token, Reason: "store"},
+		{Chain: "ethereum", Token: renewed, Reason: "store"},
+	})
+	require.NoError(t, err)
+	overrides := MultiOverrides{file, store}
+
+	override, err := ActiveOverride(overrides, "ethereum", token, now)
+	require.NoError(t, err)
+	require.NotNil(t, override)
+	assert.Equal(t, "file", override.Reason)
+
+	override, err = ActiveOverride(overrides, "ethereum", other, now)
+	require.NoError(t, err)
+	assert.Nil(t, override)
+
+	// an expired override in the file doesn't hide the active one of the store
+	override, err = ActiveOverride(overrides, "ethereum", renewed, now)
+	require.NoError(t, err)
+	require.NotNil(t, override)
+	assert.Equal(t, "store", override.Reason)
+	override, err = overrides.GetOverride("ethereum", renewed)
+	require.NoError(t, err)
+	assert.Equal(t, "store", override.Reason)
+
+	// once the file's override expires, the store's one applies
+	override, err = ActiveOverride(overrides, "ethereum", token, later.Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, override)
+	assert.Equal(t, "store", override.Reason)
+
+	override, err = ActiveOverride(nil, "ethereum", token, now)
+	require.NoError(t, err)
+	assert.Nil(t, override)
+}
+
+func TestOverrideApply(t *testing.T) {
+	var (
+		token    = common.HexToAddress("0x01")
+		receiver = common.HexToAddress("0x02")
+		honeypot = true
+		override = &Override{Chain: "ethereum", Token: token, IsFeeOnTransfer: true, FeeBps: 300, Reason: "tax"}
+	)
+	tests := []struct {
+		name       string
+		verdict    *Verdict
+		wantErc20  bool
+		wantMethod string
+	}{
+		{"never classified", nil, false, MethodOverride},
+		{
+			"classified",
+			&Verdict{Chain: "ethereum", Token: token, IsErc20: true, FeeReceiver: &receiver, Formula: "1.0000*x", IsHoneypot: &honeypot, Method: MethodLogs},
+			true, MethodLogs,
+		},
+		{"not erc20", &Verdict{Chain: "ethereum", Token: token, Method: MethodCode}, false, MethodCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := override.Apply(tt.verdict)
+			assert.Equal(t, tt.wantErc20, verdict.IsErc20)
+			assert.Equal(t, tt.wantMethod, verdict.Method)
+			assert.True(t, verdict.IsFeeOnTransfer)
+			assert.Equal(t, 300, verdict.FeeBps)
+			assert.Same(t, override, verdict.Override)
+			// the values the classifiers found for another fee are not reported
+			assert.Nil(t, verdict.FeeReceiver)
+			assert.Empty(t, verdict.Formula)
+			assert.Nil(t, verdict.IsHoneypot)
+			if tt.verdict != nil {
+				assert.False(t, tt.verdict.IsFeeOnTransfer, "verdict is copied")
+			}
+		})
+	}
+}
+
+func TestServerOverrides(t *testing.T) {
+	store, err := NewSQLStore(filepath.Join(t.TempDir(), "verdicts.sqlite"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	var (
+		token      = common.HexToAddress("0x01")
+		classified = time.Now().Add(-72 * time.Hour)
+		pinned     = time.Now().Add(-48 * time.Hour)
+		deleted    = time.Now().Add(-24 * time.Hour)
+	)
+	require.NoError(t, store.PutVerdict(&Verdict{Chain: "ethereum", Token: token, IsErc20: true, IsFeeOnTransfer: true, FeeBps: 100, Method: MethodLogs, UpdatedAt: classified}))
+	require.NoError(t, store.PutOverride(&Override{Chain: "ethereum", Token: token, Reason: "router exempt", CreatedAt: pinned}))
+
+	config := DefaultConfig
+	config.Overrides = store
+	s := NewServer(store, newTestServer(&codeClient{}, store).chains, config)
+
+	verdict, err := s.Verdict("ethereum", token)
+	require.NoError(t, err)
+	assert.False(t, verdict.IsFeeOnTransfer)
+	assert.Equal(t, 0, verdict.FeeBps)
+	require.NotNil(t, verdict.Override)
+	assert.Equal(t, "router exempt", verdict.Override.Reason)
+	assert.Equal(t, MethodLogs, verdict.Method)
+
+	// overridden tokens are not checked again
+	due, err := NewScheduler(s, nil, SchedulerConfig{Interval: time.Hour}).Due(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// a token never classified is served its override, its code tells whether it is ERC20
+	unknown := common.HexToAddress("0x02")
+	require.NoError(t, store.PutOverride(&Override{Chain: "ethereum", Token: unknown, IsFeeOnTransfer: true, FeeBps: 300, Reason: "tax"}))
+	verdict, err = s.Verdict("ethereum", unknown)
+	require.NoError(t, err)
+	assert.Equal(t, MethodOverride, verdict.Method)
+	assert.Equal(t, 300, verdict.FeeBps)
+	assert.False(t, verdict.IsErc20)
+
+	require.NoError(t, store.DeleteOverride("ethereum", token, deleted))
+	assert.ErrorIs(t, store.DeleteOverride("ethereum", token, deleted), ErrNotFound)
+	verdict, err = s.Verdict("ethereum", token)
+	require.NoError(t, err)
+	assert.True(t, verdict.IsFeeOnTransfer)
+	assert.Nil(t, verdict.Override)
+
+	// the history shows the override of the day
+	tests := []struct {
+		name         string
+		at           time.Time
+		wantOverride bool
+	}{
+		{"before override", classified.Add(time.Hour), false},
+		{"overridden", pinned.Add(time.Hour), true},
+		{"after delete", deleted.Add(time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := s.VerdictAt("ethereum", token, tt.at)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOverride, verdict.Override != nil)
+			assert.Equal(t, !tt.wantOverride, verdict.IsFeeOnTransfer)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchedulerConfig configures a Scheduler.
+type SchedulerConfig struct {
+	// Interval is the time between two checks of an ERC20 token without risk nor traffic
+	Interval time.Duration
+	// MinInterval is the shortest time between two checks of a token, also waited after a failed check
+	MinInterval time.Duration
+	// Tick is how often the scheduler looks for the tokens due for a check
+	Tick time.Duration
+	// Workers is the number of tokens checked concurrently
+	Workers int
+	// CheckTimeout cancels checks running longer
+	CheckTimeout time.Duration
+}
+
+var DefaultSchedulerConfig = SchedulerConfig{
+	Interval:     24 * time.Hour,
+	MinInterval:  time.Hour,
+	Tick:         time.Minute,
+	Workers:      2,
+	CheckTimeout: 10 * time.Minute,
+}
+
+// Scheduler classifies the tokens of a Server's store again on a cadence weighted by their risk and traffic, and
+// emits a ChangeEvent when a verdict changes, e.g. when an owner raises the fee or a proxy is upgraded.
+type Scheduler struct {
+	server *Server
+	sink   ChangeSink
+	config SchedulerConfig
+
+	mu sync.Mutex
+	// failedAt is the time of the last failed check of the tokens, by verdictKey
+	failedAt map[string]time.Time
+}
+
+func NewScheduler(server *Server, sink ChangeSink, config SchedulerConfig) *Scheduler {
+	if config.Interval <= 0 {
+		config.Interval = DefaultSchedulerConfig.Interval
+	}
+	if config.MinInterval <= 0 || config.MinInterval > config.Interval {
+		config.MinInterval = config.Interval
+	}
+	if config.Tick <= 0 {
+		config.Tick = DefaultSchedulerConfig.Tick
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.CheckTimeout <= 0 {
+		config.CheckTimeout = DefaultSchedulerConfig.CheckTimeout
+	}
+	return &Scheduler{
+		server:   server,
+		sink:     sink,
+		config:   config,
+		failedAt: make(map[string]time.Time),
+	}
+}
+
+// interval returns the time between two checks of a token. Risky tokens, whose owners are more likely to change their
+// behavior, and tokens looked up often are checked sooner; contracts that are not ERC20 rarely.
+func (s *Scheduler) interval(verdict *Verdict, lookups int) time.Duration {
+	weight := 1.0
+	if !verdict.IsErc20 {
+		weight = 0.25
+	}
+	if verdict.IsFeeOnTransfer {
+		weight++
+	}
+	if verdict.IsHoneypot != nil && *verdict.IsHoneypot {
+		weight += 2
+	}
+	if verdict.Implementation != nil {
+		// upgradeable
+		weight += 2
+	}
+	weight += math.Log2(1 + float64(lookups))
+
+	interval := time.Duration(float64(s.config.Interval) / weight)
+	if interval < s.config.MinInterval {
+		interval = s.config.MinInterval
+	}
+	return interval
+}
+
+// Due returns the verdicts due for a check at now, most overdue first. Overridden tokens are never due.
+func (s *Scheduler) Due(now time.Time) ([]*Verdict, error) {
+	verdicts, err := s.server.store.Verdicts()
+	if err != nil {
+		return nil, fmt.Errorf("could not get verdicts: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		due     []*Verdict
+		overdue = make(map[*Verdict]time.Duration)
+	)
+	for _, verdict := range verdicts {
+		if _, ok := s.server.chains[verdict.Chain]; !ok {
+			continue
+		}
+		// a pinned verdict is not checked until its override expires
+		override, err := ActiveOverride(s.server.config.Overrides, verdict.Chain, verdict.Token, now)
+		if err != nil {
+			return nil, err
+		}
+		if override != nil {
+			continue
+		}
+		key := verdictKey(verdict.Chain, verdict.Token)
+		if failedAt, ok := s.failedAt[key]; ok && now.Sub(failedAt) < s.config.MinInterval {
+			continue
+		}
+		next := verdict.UpdatedAt.Add(s.interval(verdict, s.server.peekLookups(key)))
+		if next.After(now) {
+			continue
+		}
+		due = append(due, verdict)
+		overdue[verdict] = now.Sub(next)
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return overdue[due[i]] > overdue[due[j]]
+	})
+	return due, nil
+}
+
+// Run checks the tokens due every Tick until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.Tick)
+	defer ticker.Stop()
+	for {
+		due, err := s.Due(time.Now())
+		if err != nil {
+			logger.Errorw("could not schedule checks", "error", err)
+		}
+		if len(due) > 0 {
+			logger.Infow("checking tokens again", "tokens", len(due))
+		}
+
+		var (
+			wg      sync.WaitGroup
+			workers = make(chan struct{}, s.config.Workers)
+		)
+		for _, verdict := range due {
+			select {
+			case <-ctx.Done():
+			case workers <- struct{}{}:
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(verdict *Verdict) {
+				defer func() {
+					<-workers
+					wg.Done()
+				}()
+				if _, err := s.Check(ctx, verdict); err != nil && ctx.Err() == nil {
+					logger.Infow("could not check token", "chain", verdict.Chain, "token", verdict.Token, "error", err)
+				}
+			}(verdict)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check classifies the token of prev again, emits a ChangeEvent if its new verdict differs from prev and stores it.
+// The verdict is only stored once the event is emitted, so a failed delivery is retried at the next check.
+// The event is also returned, nil if nothing changed. A failed check is not retried before MinInterval.
+func (s *Scheduler) Check(ctx context.Context, prev *Verdict) (*ChangeEvent, error) {
+	key := verdictKey(prev.Chain, prev.Token)
+	event, err := s.check(ctx, prev)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failedAt[key] = time.Now()
+		return event, err
+	}
+	delete(s.failedAt, key)
+	return event, nil
+}
+
+func (s *Scheduler) check(ctx context.Context, prev *Verdict) (*ChangeEvent, error) {
+	chain, ok := s.server.chains[prev.Chain]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownChain, prev.Chain)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, s.config.CheckTimeout)
+	defer cancel()
+	cur, err := chain.reclassify(checkCtx, prev)
+	if err != nil {
+		return nil, err
+	}
+	s.server.takeLookups(verdictKey(prev.Chain, prev.Token))
+
+	changes := DiffVerdicts(prev, cur)
+	if len(changes) == 0 {
+		if err := s.server.store.PutVerdict(cur); err != nil {
+			return nil, fmt.Errorf("could not store verdict: %w", err)
+		}
+		return nil, nil
+	}
+	event := &ChangeEvent{
+		Chain:      prev.Chain,
+		Token:      prev.Token,
+		Changes:    changes,
+		Previous:   prev,
+		Current:    cur,
+		DetectedAt: cur.UpdatedAt,
+	}
+	for _, change := range changes {
+		logger.Infow("verdict changed", "chain", prev.Chain, "token", prev.Token, "change", change.Description)
+	}
+	if err := s.sink.Put(ctx, event); err != nil {
+		return event, fmt.Errorf("could not emit change event: %w", err)
+	}
+	if err := s.server.store.PutVerdict(cur); err != nil {
+		return event, fmt.Errorf("could not store verdict: %w", err)
+	}
+	return event, nil
+}
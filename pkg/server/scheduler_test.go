@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestDiffVerdicts(t *testing.T) {
+	implementation := common.HexToAddress("0x0a")
+	erc20 := Verdict{IsErc20: true}
+	tests := []struct {
+		name      string
+		prev, cur Verdict
+		want      []ChangeKind
+		wantDesc  string
+	}{
+		{"unchanged", erc20, erc20, nil, ""},
+		{"no longer erc20", erc20, Verdict{}, []ChangeKind{ChangeErc20}, "token is no longer ERC20"},
+		{
+			"fee raised",
+			erc20, Verdict{IsErc20: true, IsFeeOnTransfer: true, FeeBps: 500},
+			[]ChangeKind{ChangeFeeOnTransfer, ChangeFeeRate}, "token became fee-on-transfer",
+		},
+		{
+			"fee rate",
+			Verdict{IsErc20: true, IsFeeOnTransfer: true, FeeBps: 100}, Verdict{IsErc20: true, IsFeeOnTransfer: true, FeeBps: 500},
+			[]ChangeKind{ChangeFeeRate}, "fee rate changed from 100 to 500 bps",
+		},
+		{
+			"fee of another method",
+			Verdict{IsErc20: true, Method: MethodScenarios, IsFeeOnTransfer: true, FeeBps: 100},
+			Verdict{IsErc20: true, Method: MethodLogs},
+			nil, "",
+		},
+		{"became honeypot", erc20, Verdict{IsErc20: true, IsHoneypot: boolPtr(true)}, []ChangeKind{ChangeHoneypot}, "token became a honeypot"},
+		{"honeypot unknown", Verdict{IsErc20: true, IsHoneypot: boolPtr(true)}, erc20, nil, ""},
+		{"honeypot known", erc20, Verdict{IsErc20: true, IsHoneypot: boolPtr(false)}, nil, ""},
+		{"upgraded", erc20, Verdict{IsErc20: true, Implementation: &implementation}, []ChangeKind{ChangeImplementation}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffVerdicts(&tt.prev, &tt.cur)
+			var kinds []ChangeKind
+			for _, c := range changes {
+				kinds = append(kinds, c.Kind)
+			}
+			assert.Equal(t, tt.want, kinds)
+			if tt.wantDesc != "" {
+				assert.Equal(t, tt.wantDesc, changes[0].Description)
+			}
+		})
+	}
+}
+
+func TestSchedulerInterval(t *testing.T) {
+	s := NewScheduler(newTestServer(&codeClient{}, NewMemoryStore()), nil, SchedulerConfig{Interval: 24 * time.Hour, MinInterval: time.Hour})
+	tests := []struct {
+		name    string
+		verdict Verdict
+		lookups int
+		want    time.Duration
+	}{
+		{"not erc20", Verdict{}, 0, 96 * time.Hour},
+		{"erc20", Verdict{IsErc20: true}, 0, 24 * time.Hour},
+		{"fee on transfer", Verdict{IsErc20: true, IsFeeOnTransfer: true}, 0, 12 * time.Hour},
+		{"looked up", Verdict{IsErc20: true}, 1, 12 * time.Hour},
+		{"risky and popular", Verdict{IsErc20: true, IsFeeOnTransfer: true, IsHoneypot: boolPtr(true)}, 1 << 20, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.interval(&tt.verdict, tt.lookups))
+		})
+	}
+}
+
+func TestSchedulerCheckFailedDelivery(t *testing.T) {
+	var (
+		store    = NewMemoryStore()
+		srv      = newTestServer(&codeClient{}, store)
+		failing  = true
+		received []*ChangeEvent
+		sink     = ChangeSinkFunc(func(ctx context.Context, event *ChangeEvent) error {
+			if failing {
+				return errors.New("broker unavailable")
+			}
+			received = append(received, event)
+			return nil
+		})
+		s      = NewScheduler(srv, sink, DefaultSchedulerConfig)
+		stored = &Verdict{Chain: "ethereum", Token: common.HexToAddress("0x01"), IsErc20: true, UpdatedAt: time.Now().Add(-48 * time.Hour)}
+	)
+	require.NoError(t, store.PutVerdict(stored))
+
+	// the verdict is not stored if its change event is lost
+	_, err := s.Check(context.Background(), stored)
+	require.Error(t, err)
+	verdict, err := store.GetVerdict("ethereum", stored.Token)
+	require.NoError(t, err)
+	assert.True(t, verdict.IsErc20)
+
+	// and the token is not due again before MinInterval
+	due, err := s.Due(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = s.Due(time.Now().Add(DefaultSchedulerConfig.MinInterval))
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	// so the change is emitted at the next check
+	failing = false
+	event, err := s.Check(context.Background(), verdict)
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, []*ChangeEvent{event}, received)
+	verdict, err = store.GetVerdict("ethereum", stored.Token)
+	require.NoError(t, err)
+	assert.False(t, verdict.IsErc20)
+}
+
+func TestSchedulerCheck(t *testing.T) {
+	events := make(chan *ChangeEvent, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event ChangeEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- &event
+	}))
+	defer webhook.Close()
+
+	var (
+		store  = NewMemoryStore()
+		srv    = newTestServer(&codeClient{}, store)
+		s      = NewScheduler(srv, NewWebhookSink(webhook.URL, nil), DefaultSchedulerConfig)
+		token  = common.HexToAddress("0x01")
+		stored = &Verdict{Chain: "ethereum", Token: token, IsErc20: true, UpdatedAt: time.Now().Add(-48 * time.Hour)}
+		fresh  = &Verdict{Chain: "ethereum", Token: common.HexToAddress("0x02"), UpdatedAt: time.Now()}
+	)
+	require.NoError(t, store.PutVerdict(stored))
+	require.NoError(t, store.PutVerdict(fresh))
+
+	due, err := s.Due(time.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, token, due[0].Token)
+
+	// the code of the fake client is not ERC20
+	event, err := s.Check(context.Background(), due[0])
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, ChangeErc20, event.Changes[0].Kind)
+
+	received := <-events
+	assert.Equal(t, token, received.Token)
+	assert.Equal(t, "token is no longer ERC20", received.Changes[0].Description)
+
+	verdict, err := store.GetVerdict("ethereum", token)
+	require.NoError(t, err)
+	assert.False(t, verdict.IsErc20)
+
+	// checked again, nothing changes
+	event, err = s.Check(context.Background(), verdict)
+	require.NoError(t, err)
+	assert.Nil(t, event)
+}
@@ -0,0 +1,449 @@
+// Package server serves token classifications over HTTP. Verdicts are cached in a Store; classifications run
+// asynchronously as jobs, queued in the Store so they survive restarts.
+//
+//	GET  /tokens/{chain}/{address}          the cached verdict of a token, ?at=RFC3339 for the one it had then
+//	GET  /tokens/{chain}/{address}/history  every verdict of a token, with a HistoryStore
+//	POST /classify                          queue a job classifying a token, body is a ClassifyRequest
+//	GET  /jobs/{id}                         the status of a job, with its verdict once done
+//
+// Overrides pin the verdicts of the tokens the classifiers get wrong, they are applied to the verdicts served.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrUnknownChain = errors.New("unknown chain")
+	ErrQueueFull    = errors.New("job queue is full")
+)
+
+// Config configures a Server.
+type Config struct {
+	// Workers is the number of jobs run concurrently
+	Workers int
+	// QueueSize is the number of jobs that can wait for a worker, more jobs are rejected
+	QueueSize int
+	// JobTimeout cancels jobs running longer
+	JobTimeout time.Duration
+	// Overrides pin the verdicts of tokens, they are applied to the verdicts served, not the ones stored. Optional.
+	Overrides Overrides
+}
+
+var DefaultConfig = Config{
+	Workers:    4,
+	QueueSize:  1024,
+	JobTimeout: 10 * time.Minute,
+}
+
+// Server classifies the tokens of several chains, named in requests by the keys of chains.
+type Server struct {
+	store  Store
+	chains map[string]*Chain
+	config Config
+	queue  chan string
+
+	mu sync.Mutex
+	// inflight maps the tokens being classified, by verdictKey, to their job so concurrent requests share one job
+	inflight map[string]string
+	// lookups counts the verdict requests of tokens by verdictKey since they were last classified again
+	lookups map[string]int
+}
+
+func NewServer(store Store, chains map[string]*Chain, config Config) *Server {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 1 {
+		config.QueueSize = DefaultConfig.QueueSize
+	}
+	return &Server{
+		store:    store,
+		chains:   chains,
+		config:   config,
+		queue:    make(chan string, config.QueueSize),
+		inflight: make(map[string]string),
+		lookups:  make(map[string]int),
+	}
+}
+
+// Submit queues a job classifying the token of req. If the token is already being classified, its job is returned.
+func (s *Server) Submit(req *ClassifyRequest) (*Job, error) {
+	if _, ok := s.chains[req.Chain]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownChain, req.Chain)
+	}
+	key := verdictKey(req.Chain, req.Token)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.inflight[key]; ok {
+		return s.store.GetJob(id)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        newJobID(),
+		Chain:     req.Chain,
+		Token:     req.Token,
+		Scenarios: req.Scenarios,
+		Status:    JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.PutJob(job); err != nil {
+		return nil, fmt.Errorf("could not store job: %w", err)
+	}
+	select {
+	case s.queue <- job.ID:
+	default:
+		// fail the job so it is not run after a restart either
+		job.Status = JobFailed
+		job.Error = ErrQueueFull.Error()
+		if err := s.store.PutJob(job); err != nil {
+			logger.Errorw("could not store job", "id", job.ID, "error", err)
+		}
+		return nil, ErrQueueFull
+	}
+	s.inflight[key] = job.ID
+	return job, nil
+}
+
+// Job returns the job with the given id, ErrNotFound if there is none. The override of its token, if any, is applied
+// to its verdict.
+func (s *Server) Job(id string) (*Job, error) {
+	job, err := s.store.GetJob(id)
+	if err != nil || job.Verdict == nil {
+		return job, err
+	}
+	override, err := ActiveOverride(s.config.Overrides, job.Chain, job.Token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		job.Verdict = override.Apply(job.Verdict)
+	}
+	return job, nil
+}
+
+// Verdict returns the cached verdict of a token with its override applied, ErrNotFound if it was never classified
+// nor overridden.
+func (s *Server) Verdict(chain string, token common.Address) (*Verdict, error) {
+	if _, ok := s.chains[chain]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownChain, chain)
+	}
+	override, err := ActiveOverride(s.config.Overrides, chain, token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	verdict, err := s.store.GetVerdict(chain, token)
+	if err != nil && !(errors.Is(err, ErrNotFound) && override != nil) {
+		return nil, err
+	}
+	if override != nil && verdict == nil {
+		// the override does not tell whether a token never classified is ERC20, its code does
+		overridden := override.Apply(nil)
+		if overridden.IsErc20, err = s.chains[chain].IsErc20(token); err != nil {
+			return nil, err
+		}
+		return overridden, nil
+	}
+	if override != nil {
+		return override.Apply(verdict), nil
+	}
+	s.mu.Lock()
+	s.lookups[verdictKey(chain, token)]++
+	s.mu.Unlock()
+	return verdict, nil
+}
+
+// ErrNoHistory is returned when the store of a Server does not keep the history of verdicts
+var ErrNoHistory = errors.New("store does not keep verdict history")
+
+// History returns every verdict of a token, oldest first.
+func (s *Server) History(chain string, token common.Address) ([]*Verdict, error) {
+	store, err := s.historyStore(chain)
+	if err != nil {
+		return nil, err
+	}
+	verdicts, err := store.History(chain, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(verdicts) == 0 {
+		return nil, ErrNotFound
+	}
+	return verdicts, nil
+}
+
+// VerdictAt returns the verdict a token had at a time, with the override of the store it had then applied.
+// ErrNotFound is returned if it was not classified nor overridden yet. The verdict of a token overridden before it was
+// classified has IsErc20 false since its code then is not known.
+func (s *Server) VerdictAt(chain string, token common.Address, at time.Time) (*Verdict, error) {
+	store, err := s.historyStore(chain)
+	if err != nil {
+		return nil, err
+	}
+	override, err := store.OverrideAt(chain, token, at)
+	if errors.Is(err, ErrNotFound) || err == nil && !override.Active(at) {
+		override, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get override: %w", err)
+	}
+	verdict, err := store.VerdictAt(chain, token, at)
+	if err != nil && !(errors.Is(err, ErrNotFound) && override != nil) {
+		return nil, err
+	}
+	if override != nil {
+		return override.Apply(verdict), nil
+	}
+	return verdict, nil
+}
+
+func (s *Server) historyStore(chain string) (HistoryStore, error) {
+	if _, ok := s.chains[chain]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownChain, chain)
+	}
+	store, ok := s.store.(HistoryStore)
+	if !ok {
+		return nil, ErrNoHistory
+	}
+	return store, nil
+}
+
+// takeLookups returns the number of verdict requests of a token since the last call.
+func (s *Server) takeLookups(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lookups := s.lookups[key]
+	delete(s.lookups, key)
+	return lookups
+}
+
+// peekLookups returns the number of verdict requests of a token since the last takeLookups.
+func (s *Server) peekLookups(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookups[key]
+}
+
+// Run queues the pending jobs of the store again then runs jobs until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	pending, err := s.store.PendingJobs()
+	if err != nil {
+		return fmt.Errorf("could not get pending jobs: %w", err)
+	}
+	if len(pending) > 0 {
+		logger.Infow("resuming pending jobs", "jobs", len(pending))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, job := range pending {
+			key := verdictKey(job.Chain, job.Token)
+			s.mu.Lock()
+			if _, ok := s.inflight[key]; ok {
+				// submitted since the server started
+				s.mu.Unlock()
+				continue
+			}
+			s.inflight[key] = job.ID
+			s.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case s.queue <- job.ID:
+			}
+		}
+	}()
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-s.queue:
+					s.run(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// run runs a job and stores its result. Jobs interrupted by ctx stay pending so they run again after a restart.
+func (s *Server) run(ctx context.Context, id string) {
+	job, err := s.store.GetJob(id)
+	if err != nil {
+		logger.Errorw("could not get job", "id", id, "error", err)
+		return
+	}
+	key := verdictKey(job.Chain, job.Token)
+	defer func() {
+		s.mu.Lock()
+		if s.inflight[key] == id {
+			delete(s.inflight, key)
+		}
+		s.mu.Unlock()
+	}()
+	if !job.Pending() {
+		// queued again on start while it was already queued
+		return
+	}
+
+	job.Status = JobRunning
+	job.UpdatedAt = time.Now()
+	if err := s.store.PutJob(job); err != nil {
+		logger.Errorw("could not store job", "id", id, "error", err)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.config.JobTimeout)
+	defer cancel()
+	verdict, err := s.chains[job.Chain].classify(jobCtx, job)
+	if ctx.Err() != nil {
+		// stopping, the job is run again on restart
+		return
+	}
+
+	job.UpdatedAt = time.Now()
+	if err != nil {
+		logger.Infow("could not classify token", "chain", job.Chain, "token", job.Token, "error", err)
+		job.Status = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobDone
+		job.Verdict = verdict
+		if err := s.store.PutVerdict(verdict); err != nil {
+			logger.Errorw("could not store verdict", "chain", job.Chain, "token", job.Token, "error", err)
+		}
+	}
+	if err := s.store.PutJob(job); err != nil {
+		logger.Errorw("could not store job", "id", id, "error", err)
+	}
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tokens/", s.handleGetVerdict)
+	mux.HandleFunc("/classify", s.handleClassify)
+	mux.HandleFunc("/jobs/", s.handleGetJob)
+	return mux
+}
+
+func (s *Server) handleGetVerdict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tokens/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || !common.IsHexAddress(parts[1]) || len(parts) == 3 && parts[2] != "history" {
+		writeError(w, http.StatusBadRequest, errors.New("expected /tokens/{chain}/{address} or /tokens/{chain}/{address}/history"))
+		return
+	}
+	chain, token := parts[0], common.HexToAddress(parts[1])
+	if len(parts) == 3 {
+		history, err := s.History(chain, token)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, history)
+		return
+	}
+
+	var (
+		verdict *Verdict
+		err     error
+	)
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, parseErr := time.Parse(time.RFC3339, at)
+		if parseErr != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("could not parse at: %w", parseErr))
+			return
+		}
+		verdict, err = s.VerdictAt(chain, token, t)
+	} else {
+		verdict, err = s.Verdict(chain, token)
+	}
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, verdict)
+}
+
+func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var req ClassifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("could not decode request: %w", err))
+		return
+	}
+	if req.Token == (common.Address{}) {
+		writeError(w, http.StatusBadRequest, errors.New("missing token"))
+		return
+	}
+	job, err := s.Submit(&req)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	job, err := s.Job(strings.TrimPrefix(r.URL.Path, "/jobs/"))
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrUnknownChain):
+		return http.StatusNotFound
+	case errors.Is(err, ErrQueueFull):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrNoHistory):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Debugw("could not write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+)
+
+// codeClient returns a code that is not ERC20 for any contract, once gate is closed if it is not nil.
+type codeClient struct {
+	gate     chan struct{}
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *codeClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if c.gate != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.gate:
+		}
+	}
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+	return json.Unmarshal([]byte(`"0x6000"`), result)
+}
+
+func (c *codeClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for i := range b {
+		b[i].Error = c.CallContext(ctx, b[i].Result, b[i].Method, b[i].Args...)
+	}
+	return nil
+}
+
+func newTestServer(client *codeClient, store Store) *Server {
+	chain := NewChain(client, nil, fetcher.DefaultConfig, classifier.DefaultBatchClassifierConfig, 100)
+	return NewServer(store, map[string]*Chain{"ethereum": chain}, DefaultConfig)
+}
+
+func waitJob(t *testing.T, s *Server, id string) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = s.Job(id)
+		require.NoError(t, err)
+		return !job.Pending()
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestServerClassify(t *testing.T) {
+	var (
+		client = &codeClient{gate: make(chan struct{})}
+		s      = newTestServer(client, NewMemoryStore())
+		token  = common.HexToAddress("0x01")
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	// concurrent requests for a token share one job
+	job, err := s.Submit(&ClassifyRequest{Chain: "ethereum", Token: token})
+	require.NoError(t, err)
+	other, err := s.Submit(&ClassifyRequest{Chain: "ethereum", Token: token})
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, other.ID)
+
+	close(client.gate)
+	job = waitJob(t, s, job.ID)
+	assert.Equal(t, JobDone, job.Status)
+	require.NotNil(t, job.Verdict)
+	assert.False(t, job.Verdict.IsErc20)
+	assert.Equal(t, MethodCode, job.Verdict.Method)
+	assert.Equal(t, 1, client.requests)
+
+	verdict, err := s.Verdict("ethereum", token)
+	require.NoError(t, err)
+	assert.Equal(t, token, verdict.Token)
+
+	// done jobs don't dedupe later requests
+	next, err := s.Submit(&ClassifyRequest{Chain: "ethereum", Token: token})
+	require.NoError(t, err)
+	assert.NotEqual(t, job.ID, next.ID)
+
+	_, err = s.Submit(&ClassifyRequest{Chain: "bsc", Token: token})
+	assert.ErrorIs(t, err, ErrUnknownChain)
+}
+
+func TestServerResumesPendingJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.db")
+	store, err := NewBoltStore(path)
+	require.NoError(t, err)
+
+	// submitted but never run
+	job, err := newTestServer(&codeClient{}, store).Submit(&ClassifyRequest{Chain: "ethereum", Token: common.HexToAddress("0x01")})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	s := newTestServer(&codeClient{}, store)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	assert.Equal(t, JobDone, waitJob(t, s, job.ID).Status)
+}
+
+func TestHandler(t *testing.T) {
+	s := newTestServer(&codeClient{}, NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	handler := s.Handler()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"unknown verdict", http.MethodGet, "/tokens/ethereum/0x0000000000000000000000000000000000000002", "", http.StatusNotFound},
+		{"unknown chain", http.MethodGet, "/tokens/bsc/0x0000000000000000000000000000000000000002", "", http.StatusNotFound},
+		{"invalid address", http.MethodGet, "/tokens/ethereum/0x02", "", http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/jobs/missing", "", http.StatusNotFound},
+		{"invalid body", http.MethodPost, "/classify", "{", http.StatusBadRequest},
+		{"missing token", http.MethodPost, "/classify", `{"chain": "ethereum"}`, http.StatusBadRequest},
+		{"get classify", http.MethodGet, "/classify", "", http.StatusMethodNotAllowed},
+		{"history without sql store", http.MethodGet, "/tokens/ethereum/0x0000000000000000000000000000000000000002/history", "", http.StatusNotImplemented},
+		{"invalid subpath", http.MethodGet, "/tokens/ethereum/0x0000000000000000000000000000000000000002/jobs", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, do(tt.method, tt.path, tt.body).Code)
+		})
+	}
+
+	rec := do(http.MethodPost, "/classify", `{"chain": "ethereum", "token": "0x0000000000000000000000000000000000000003"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var job Job
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	waitJob(t, s, job.ID)
+
+	rec = do(http.MethodGet, "/jobs/"+job.ID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, JobDone, job.Status)
+
+	rec = do(http.MethodGet, "/tokens/ethereum/0x0000000000000000000000000000000000000003", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var verdict Verdict
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &verdict))
+	assert.Equal(t, common.HexToAddress("0x03"), verdict.Token)
+}
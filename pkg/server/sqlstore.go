@@ -0,0 +1,363 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// HistoryStore is a Store keeping every verdict of a token, not only the latest one.
+type HistoryStore interface {
+	Store
+	// History returns the verdicts of a token, oldest first
+	History(chain string, token common.Address) ([]*Verdict, error)
+	// VerdictAt returns the verdict a token had at a time, ErrNotFound if it was not classified yet
+	VerdictAt(chain string, token common.Address, at time.Time) (*Verdict, error)
+	// OverrideAt returns the override a token had at a time, expired or not, ErrNotFound if there was none
+	OverrideAt(chain string, token common.Address, at time.Time) (*Override, error)
+}
+
+// sqlMigrations are applied in order to a database, the schema version of a database is the number of migrations
+// applied. Never change a released migration, append a new one.
+var sqlMigrations = []string{
+	`CREATE TABLE jobs (
+		id         TEXT PRIMARY KEY,
+		status     TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		data       BLOB NOT NULL
+	);
+	CREATE INDEX jobs_status ON jobs (status);
+	CREATE TABLE verdicts (
+		id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+		chain              TEXT NOT NULL,
+		token              TEXT NOT NULL,
+		block_number       INTEGER NOT NULL,
+		method             TEXT NOT NULL,
+		is_erc20           BOOLEAN NOT NULL,
+		is_fee_on_transfer BOOLEAN NOT NULL,
+		is_honeypot        BOOLEAN,
+		fee_bps            INTEGER NOT NULL,
+		fee_receiver       TEXT,
+		formula            TEXT,
+		evidence           TEXT NOT NULL,
+		data               BLOB NOT NULL,
+		created_at         INTEGER NOT NULL
+	);
+	CREATE INDEX verdicts_token ON verdicts (chain, token, created_at);`,
+	// overrides are never deleted either, a replaced or deleted override gets a deleted_at
+	`CREATE TABLE overrides (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		chain      TEXT NOT NULL,
+		token      TEXT NOT NULL,
+		data       BLOB NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER,
+		deleted_at INTEGER
+	);
+	CREATE INDEX overrides_token ON overrides (chain, token, created_at);`,
+}
+
+// SQLStore keeps jobs, verdicts and overrides in a SQLite database. Verdicts are never overwritten, every
+// classification of a token is recorded with its block, method, fee parameters and evidence, and so is every override,
+// so the verdict a token had on a given day can be audited.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore opens or creates the SQLite database at path and migrates it to the latest schema.
+func NewSQLStore(path string) (*SQLStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("could not open store %s: %w", path, err)
+	}
+	// sqlite serializes writers, one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not migrate store %s: %w", path, err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// migrate applies the migrations not applied to db yet, each in its own transaction.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqlMigrations) {
+		return fmt.Errorf("schema version %d is newer than the latest known %d", version, len(sqlMigrations))
+	}
+	for i := version; i < len(sqlMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqlMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		logger.Infow("migrated store", "version", i+1)
+	}
+	return nil
+}
+
+func (s *SQLStore) PutJob(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO jobs (id, status, created_at, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, data = excluded.data`,
+		job.ID, job.Status, job.CreatedAt.UnixNano(), data,
+	)
+	return err
+}
+
+func (s *SQLStore) GetJob(id string) (*Job, error) {
+	var data []byte
+	if err := s.db.QueryRow(`SELECT data FROM jobs WHERE id = ?`, id).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *SQLStore) PendingJobs() ([]*Job, error) {
+	rows, err := s.db.Query(`SELECT data FROM jobs WHERE status IN (?, ?) ORDER BY created_at`, JobQueued, JobRunning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var pending []*Job
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, err
+		}
+		pending = append(pending, &job)
+	}
+	return pending, rows.Err()
+}
+
+// PutVerdict records a new classification of a token, it does not replace the previous ones.
+func (s *SQLStore) PutVerdict(verdict *Verdict) error {
+	data, err := json.Marshal(verdict)
+	if err != nil {
+		return err
+	}
+	var feeReceiver *string
+	if verdict.FeeReceiver != nil {
+		hex := verdict.FeeReceiver.Hex()
+		feeReceiver = &hex
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO verdicts (chain, token, block_number, method, is_erc20, is_fee_on_transfer, is_honeypot, fee_bps,
+			fee_receiver, formula, evidence, data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		verdict.Chain, verdict.Token.Hex(), verdict.BlockNumber, verdict.Method, verdict.IsErc20,
+		verdict.IsFeeOnTransfer, verdict.IsHoneypot, verdict.FeeBps, feeReceiver, verdict.Formula,
+		strings.Join(verdict.Evidence, "\n"), data, verdict.UpdatedAt.UnixNano(),
+	)
+	return err
+}
+
+// the latest of verdicts recorded at the same time is the last inserted
+const sqlLatestVerdict = ` ORDER BY created_at DESC, id DESC LIMIT 1`
+
+func (s *SQLStore) GetVerdict(chain string, token common.Address) (*Verdict, error) {
+	return s.queryVerdict(`SELECT data FROM verdicts WHERE chain = ? AND token = ?`+sqlLatestVerdict, chain, token.Hex())
+}
+
+func (s *SQLStore) VerdictAt(chain string, token common.Address, at time.Time) (*Verdict, error) {
+	return s.queryVerdict(
+		`SELECT data FROM verdicts WHERE chain = ? AND token = ? AND created_at <= ?`+sqlLatestVerdict,
+		chain, token.Hex(), at.UnixNano(),
+	)
+}
+
+func (s *SQLStore) History(chain string, token common.Address) ([]*Verdict, error) {
+	return s.queryVerdicts(`SELECT data FROM verdicts WHERE chain = ? AND token = ? ORDER BY created_at, id`, chain, token.Hex())
+}
+
+// Verdicts returns the latest verdict of every token.
+func (s *SQLStore) Verdicts() ([]*Verdict, error) {
+	return s.queryVerdicts(
+		`SELECT data FROM verdicts v WHERE id = (
+			SELECT id FROM verdicts WHERE chain = v.chain AND token = v.token` + sqlLatestVerdict + `
+		)`,
+	)
+}
+
+// PutOverride pins the verdict of a token, replacing its current override if any.
+func (s *SQLStore) PutOverride(override *Override) error {
+	if override.CreatedAt.IsZero() {
+		override.CreatedAt = time.Now()
+	}
+	if err := override.validate(); err != nil {
+		return fmt.Errorf("invalid override: %w", err)
+	}
+	data, err := json.Marshal(override)
+	if err != nil {
+		return err
+	}
+	var expiresAt *int64
+	if override.ExpiresAt != nil {
+		nanos := override.ExpiresAt.UnixNano()
+		expiresAt = &nanos
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(
+		`UPDATE overrides SET deleted_at = ? WHERE chain = ? AND token = ? AND deleted_at IS NULL`,
+		override.CreatedAt.UnixNano(), override.Chain, override.Token.Hex(),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO overrides (chain, token, data, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		override.Chain, override.Token.Hex(), data, override.CreatedAt.UnixNano(), expiresAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteOverride ends the override of a token at a time, ErrNotFound if it has none.
+func (s *SQLStore) DeleteOverride(chain string, token common.Address, at time.Time) error {
+	res, err := s.db.Exec(
+		`UPDATE overrides SET deleted_at = ? WHERE chain = ? AND token = ? AND deleted_at IS NULL`,
+		at.UnixNano(), chain, token.Hex(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) GetOverride(chain string, token common.Address) (*Override, error) {
+	return s.queryOverride(
+		`SELECT data FROM overrides WHERE chain = ? AND token = ? AND deleted_at IS NULL ORDER BY id DESC LIMIT 1`,
+		chain, token.Hex(),
+	)
+}
+
+func (s *SQLStore) OverrideAt(chain string, token common.Address, at time.Time) (*Override, error) {
+	return s.queryOverride(
+		`SELECT data FROM overrides WHERE chain = ? AND token = ? AND created_at <= ? AND (deleted_at IS NULL OR deleted_at > ?)
+		ORDER BY created_at DESC, id DESC LIMIT 1`,
+		chain, token.Hex(), at.UnixNano(), at.UnixNano(),
+	)
+}
+
+// Overrides returns the current overrides of all tokens, expired or not.
+func (s *SQLStore) Overrides() ([]*Override, error) {
+	rows, err := s.db.Query(`SELECT data FROM overrides WHERE deleted_at IS NULL ORDER BY chain, token`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var overrides []*Override
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var override Override
+		if err := json.Unmarshal(data, &override); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, &override)
+	}
+	return overrides, rows.Err()
+}
+
+func (s *SQLStore) queryOverride(query string, args ...interface{}) (*Override, error) {
+	var data []byte
+	if err := s.db.QueryRow(query, args...).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var override Override
+	if err := json.Unmarshal(data, &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+func (s *SQLStore) queryVerdict(query string, args ...interface{}) (*Verdict, error) {
+	var data []byte
+	if err := s.db.QueryRow(query, args...).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var verdict Verdict
+	if err := json.Unmarshal(data, &verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+func (s *SQLStore) queryVerdicts(query string, args ...interface{}) ([]*Verdict, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var verdicts []*Verdict
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var verdict Verdict
+		if err := json.Unmarshal(data, &verdict); err != nil {
+			return nil, err
+		}
+		verdicts = append(verdicts, &verdict)
+	}
+	return verdicts, rows.Err()
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
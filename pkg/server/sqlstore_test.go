@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStoreHistory(t *testing.T) {
+	store, err := NewSQLStore(filepath.Join(t.TempDir(), "verdicts.sqlite"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	var (
+		token = common.HexToAddress("0x01")
+		other = common.HexToAddress("0x02")
+		day   = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	)
+	_, err = store.GetVerdict("ethereum", token)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	verdicts := []*Verdict{
+		{Chain: "ethereum", Token: token, IsErc20: true, BlockNumber: 100, Method: MethodLogs, Evidence: []string{"tx:0x01"}, UpdatedAt: day},
+		{Chain: "ethereum", Token: other, IsErc20: true, Method: MethodScenarios, UpdatedAt: day.Add(time.Hour)},
+		{Chain: "ethereum", Token: token, IsErc20: true, IsFeeOnTransfer: true, FeeBps: 500, BlockNumber: 200, Method: MethodLogs, UpdatedAt: day.Add(48 * time.Hour)},
+		{Chain: "bsc", Token: token, Method: MethodCode, UpdatedAt: day},
+	}
+	for _, verdict := range verdicts {
+		require.NoError(t, store.PutVerdict(verdict))
+	}
+
+	latest, err := store.GetVerdict("ethereum", token)
+	require.NoError(t, err)
+	assert.Equal(t, 500, latest.FeeBps)
+
+	history, err := store.History("ethereum", token)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, uint64(100), history[0].BlockNumber)
+	assert.Equal(t, []string{"tx:0x01"}, history[0].Evidence)
+	assert.Equal(t, uint64(200), history[1].BlockNumber)
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		wantErr error
+		wantFOT bool
+	}{
+		{"before classification", day.Add(-time.Second), ErrNotFound, false},
+		{"at classification", day, nil, false},
+		{"next day", day.Add(24 * time.Hour), nil, false},
+		{"after fee", day.Add(72 * time.Hour), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := store.VerdictAt("ethereum", token, tt.at)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFOT, verdict.IsFeeOnTransfer)
+		})
+	}
+
+	all, err := store.Verdicts()
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestSQLStoreMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verdicts.sqlite")
+	store, err := NewSQLStore(path)
+	require.NoError(t, err)
+	job := &Job{ID: "1", Status: JobQueued, CreatedAt: time.Now()}
+	require.NoError(t, store.PutJob(job))
+	require.NoError(t, store.Close())
+
+	// reopening does not apply the migrations again
+	store, err = NewSQLStore(path)
+	require.NoError(t, err)
+	pending, err := store.PendingJobs()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, job.ID, pending[0].ID)
+
+	job.Status = JobDone
+	require.NoError(t, store.PutJob(job))
+	pending, err = store.PendingJobs()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	require.NoError(t, store.Close())
+
+	// a database migrated by a newer version is refused
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, 0)`, len(sqlMigrations)+1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	_, err = NewSQLStore(path)
+	assert.ErrorContains(t, err, "newer than the latest known")
+}
+
+func TestHandlerHistory(t *testing.T) {
+	store, err := NewSQLStore(filepath.Join(t.TempDir(), "verdicts.sqlite"))
+	require.NoError(t, err)
+	defer store.Close()
+	s := newTestServer(&codeClient{}, store)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	token := common.HexToAddress("0x03")
+	for i := 0; i < 2; i++ {
+		job, err := s.Submit(&ClassifyRequest{Chain: "ethereum", Token: token})
+		require.NoError(t, err)
+		waitJob(t, s, job.ID)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tokens/ethereum/"+token.Hex()+"/history", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var history []*Verdict
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	assert.Len(t, history, 2)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"now", "?at=" + time.Now().Add(time.Minute).Format(time.RFC3339), http.StatusOK},
+		{"before classification", "?at=2020-01-01T00:00:00Z", http.StatusNotFound},
+		{"invalid time", "?at=yesterday", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tokens/ethereum/"+token.Hex()+tt.query, nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned by a Store when there is no job or verdict for a key
+var ErrNotFound = errors.New("not found")
+
+// Store persists the jobs and the verdicts of a Server, so queued jobs survive restarts.
+type Store interface {
+	PutJob(job *Job) error
+	GetJob(id string) (*Job, error)
+	// PendingJobs returns the jobs that are queued or were running when the server stopped
+	PendingJobs() ([]*Job, error)
+	PutVerdict(verdict *Verdict) error
+	GetVerdict(chain string, token common.Address) (*Verdict, error)
+	// Verdicts returns the verdicts of all tokens, in no particular order
+	Verdicts() ([]*Verdict, error)
+	Close() error
+}
+
+func verdictKey(chain string, token common.Address) string {
+	return chain + "/" + token.Hex()
+}
+
+// MemoryStore keeps jobs and verdicts in memory, it is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	jobs     map[string][]byte
+	verdicts map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:     make(map[string][]byte),
+		verdicts: make(map[string][]byte),
+	}
+}
+
+// values are kept encoded so callers can't modify them without Put
+func (s *MemoryStore) put(values map[string][]byte, key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values[key] = encoded
+	return nil
+}
+
+func (s *MemoryStore) get(values map[string][]byte, key string, value interface{}) error {
+	s.mu.RLock()
+	encoded, ok := values[key]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal(encoded, value)
+}
+
+func (s *MemoryStore) PutJob(job *Job) error {
+	return s.put(s.jobs, job.ID, job)
+}
+
+func (s *MemoryStore) GetJob(id string) (*Job, error) {
+	var job Job
+	if err := s.get(s.jobs, id, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *MemoryStore) PendingJobs() ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var pending []*Job
+	for _, encoded := range s.jobs {
+		var job Job
+		if err := json.Unmarshal(encoded, &job); err != nil {
+			return nil, err
+		}
+		if job.Pending() {
+			pending = append(pending, &job)
+		}
+	}
+	sortJobs(pending)
+	return pending, nil
+}
+
+func (s *MemoryStore) PutVerdict(verdict *Verdict) error {
+	return s.put(s.verdicts, verdictKey(verdict.Chain, verdict.Token), verdict)
+}
+
+func (s *MemoryStore) GetVerdict(chain string, token common.Address) (*Verdict, error) {
+	var verdict Verdict
+	if err := s.get(s.verdicts, verdictKey(chain, token), &verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+func (s *MemoryStore) Verdicts() ([]*Verdict, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	verdicts := make([]*Verdict, 0, len(s.verdicts))
+	for _, encoded := range s.verdicts {
+		var verdict Verdict
+		if err := json.Unmarshal(encoded, &verdict); err != nil {
+			return nil, err
+		}
+		verdicts = append(verdicts, &verdict)
+	}
+	return verdicts, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+var (
+	boltJobsBucket     = []byte("jobs")
+	boltVerdictsBucket = []byte("verdicts")
+)
+
+// BoltStore keeps jobs and verdicts in a single-file bbolt database. Only one process can open the file at a time.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens or creates the database at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open store %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltJobsBucket, boltVerdictsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create store buckets: %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) put(bucket []byte, key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), encoded)
+	})
+}
+
+func (s *BoltStore) get(bucket []byte, key string, value interface{}) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		encoded := tx.Bucket(bucket).Get([]byte(key))
+		if encoded == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(encoded, value)
+	})
+}
+
+func (s *BoltStore) PutJob(job *Job) error {
+	return s.put(boltJobsBucket, job.ID, job)
+}
+
+func (s *BoltStore) GetJob(id string) (*Job, error) {
+	var job Job
+	if err := s.get(boltJobsBucket, id, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *BoltStore) PendingJobs() ([]*Job, error) {
+	var pending []*Job
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltJobsBucket).ForEach(func(_, encoded []byte) error {
+			var job Job
+			if err := json.Unmarshal(encoded, &job); err != nil {
+				return err
+			}
+			if job.Pending() {
+				pending = append(pending, &job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortJobs(pending)
+	return pending, nil
+}
+
+func (s *BoltStore) PutVerdict(verdict *Verdict) error {
+	return s.put(boltVerdictsBucket, verdictKey(verdict.Chain, verdict.Token), verdict)
+}
+
+func (s *BoltStore) GetVerdict(chain string, token common.Address) (*Verdict, error) {
+	var verdict Verdict
+	if err := s.get(boltVerdictsBucket, verdictKey(chain, token), &verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+func (s *BoltStore) Verdicts() ([]*Verdict, error) {
+	var verdicts []*Verdict
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltVerdictsBucket).ForEach(func(_, encoded []byte) error {
+			var verdict Verdict
+			if err := json.Unmarshal(encoded, &verdict); err != nil {
+				return err
+			}
+			verdicts = append(verdicts, &verdict)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verdicts, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
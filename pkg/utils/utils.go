@@ -9,5 +9,9 @@ import (
 // RemoveLeadingZerosFromHash remove the leading 0 in hash
 // fix "invalid argument 2: hex number with leading zero digits" error
 func RemoveLeadingZerosFromHash(h common.Hash) string {
-	return "0x" + strings.TrimLeft(strings.TrimPrefix(h.Hex(), "0x"), "0")
+	trimmed := strings.TrimLeft(strings.TrimPrefix(h.Hex(), "0x"), "0")
+	if trimmed == "" {
+		return "0x0"
+	}
+	return "0x" + trimmed
 }
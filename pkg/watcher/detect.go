@@ -0,0 +1,129 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/abis"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// Source is how a new token was detected
+type Source string
+
+const (
+	// SourceContractCreation is a contract created by a transaction or by another contract
+	SourceContractCreation Source = "contract-creation"
+	// SourcePairCreated is a Uniswap V2 PairCreated event
+	SourcePairCreated Source = "pair-created"
+	// SourcePoolCreated is a Uniswap V3 PoolCreated event
+	SourcePoolCreated Source = "pool-created"
+)
+
+var (
+	pairCreatedID = abis.UniswapV2Factory.Events["PairCreated"].ID
+	poolCreatedID = abis.UniswapV3Factory.Events["PoolCreated"].ID
+)
+
+// Candidate is a contract of a block that may be a new token.
+type Candidate struct {
+	Token       common.Address
+	Source      Source
+	BlockNumber uint64
+	TxHash      common.Hash
+	// Pool is the pair or pool the token was paired in, nil for contract creations
+	Pool *common.Address
+	// Holders are the addresses likely to hold the token after the block, transfers from them are simulated
+	Holders []common.Address
+}
+
+// decodeTraces decodes the callTracer traces of debug_traceBlockByNumber, in the order of the block's transactions.
+func decodeTraces(traces []jsonrpc.TxTraceResult) ([]*jsonrpc.CallFrame, error) {
+	frames := make([]*jsonrpc.CallFrame, len(traces))
+	for i, trace := range traces {
+		if trace.Error != "" {
+			return nil, fmt.Errorf("could not trace tx %d: %s", i, trace.Error)
+		}
+		frame := new(jsonrpc.CallFrame)
+		if err := json.Unmarshal(trace.Result, frame); err != nil {
+			return nil, fmt.Errorf("could not decode trace of tx %d: %w", i, err)
+		}
+		frames[i] = frame
+	}
+	return frames, nil
+}
+
+// contractCreations returns the contracts created by the transactions of a block, including those created by other
+// contracts (e.g. token factories). The holders of a contract are its deployer and itself.
+func contractCreations(blockNumber uint64, txHashes []common.Hash, frames []*jsonrpc.CallFrame) []*Candidate {
+	var candidates []*Candidate
+	for i, root := range frames {
+		deployer := root.From
+		var walk func(frame *jsonrpc.CallFrame)
+		walk = func(frame *jsonrpc.CallFrame) {
+			if frame.Error != "" {
+				// reverted with everything it created
+				return
+			}
+			if (frame.Type == "CREATE" || frame.Type == "CREATE2") && frame.To != nil {
+				candidates = append(candidates, &Candidate{
+					Token:       *frame.To,
+					Source:      SourceContractCreation,
+					BlockNumber: blockNumber,
+					TxHash:      txHashes[i],
+					Holders:     []common.Address{deployer, *frame.To},
+				})
+			}
+			for j := range frame.Calls {
+				walk(&frame.Calls[j])
+			}
+		}
+		walk(root)
+	}
+	return candidates
+}
+
+// poolCreations returns the tokens paired by the PairCreated and PoolCreated events of logs, except the ignored
+// ones. The holders of a token are its pool and the sender of the transaction, senders is indexed by tx index.
+func poolCreations(logs []ethtypes.Log, senders []common.Address, ignore map[common.Address]bool) []*Candidate {
+	var candidates []*Candidate
+	for _, l := range logs {
+		var (
+			source Source
+			pool   common.Address
+		)
+		switch {
+		case len(l.Topics) == 3 && l.Topics[0] == pairCreatedID && len(l.Data) >= 32:
+			// PairCreated(address indexed token0, address indexed token1, address pair, uint256)
+			source, pool = SourcePairCreated, common.BytesToAddress(l.Data[:32])
+		case len(l.Topics) == 4 && l.Topics[0] == poolCreatedID && len(l.Data) >= 64:
+			// PoolCreated(address indexed token0, address indexed token1, uint24 indexed fee, int24 tickSpacing, address pool)
+			source, pool = SourcePoolCreated, common.BytesToAddress(l.Data[32:64])
+		default:
+			continue
+		}
+		holders := []common.Address{pool}
+		if int(l.TxIndex) < len(senders) {
+			holders = append(holders, senders[l.TxIndex])
+		}
+		for _, topic := range l.Topics[1:3] {
+			token := common.BytesToAddress(topic.Bytes())
+			if ignore[token] {
+				continue
+			}
+			pool := pool
+			candidates = append(candidates, &Candidate{
+				Token:       token,
+				Source:      source,
+				BlockNumber: l.BlockNumber,
+				TxHash:      l.TxHash,
+				Pool:        &pool,
+				Holders:     holders,
+			})
+		}
+	}
+	return candidates
+}
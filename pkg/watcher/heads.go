@@ -0,0 +1,95 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+)
+
+// Heads notifies new chain heads.
+type Heads interface {
+	// Subscribe sends the number of each new head to heads until ctx is done or the subscription fails. Heads may
+	// be skipped, the watcher processes every block up to the last head it received.
+	Subscribe(ctx context.Context, heads chan<- uint64) error
+}
+
+// PollHeads polls eth_blockNumber, for endpoints without websocket.
+type PollHeads struct {
+	client   jsonrpc.Client
+	interval time.Duration
+}
+
+func NewPollHeads(client jsonrpc.Client, interval time.Duration) *PollHeads {
+	return &PollHeads{
+		client:   client,
+		interval: interval,
+	}
+}
+
+func (p *PollHeads) Subscribe(ctx context.Context, heads chan<- uint64) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	var last uint64
+	for {
+		var head hexutil.Uint64
+		if err := p.client.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// transient failures are retried on the next tick
+			logger.Warnw("could not get block number", "error", err)
+		} else if uint64(head) > last {
+			last = uint64(head)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case heads <- last:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SubscribeHeads subscribes to newHeads with eth_subscribe, the client must be connected over websocket or IPC.
+type SubscribeHeads struct {
+	client *rpc.Client
+}
+
+func NewSubscribeHeads(client *rpc.Client) *SubscribeHeads {
+	return &SubscribeHeads{
+		client: client,
+	}
+}
+
+func (s *SubscribeHeads) Subscribe(ctx context.Context, heads chan<- uint64) error {
+	headers := make(chan *ethtypes.Header)
+	sub, err := s.client.EthSubscribe(ctx, headers, "newHeads")
+	if err != nil {
+		return fmt.Errorf("could not subscribe to new heads: %w", err)
+	}
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return fmt.Errorf("new heads subscription failed: %w", err)
+		case header := <-headers:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case heads <- header.Number.Uint64():
+			}
+		}
+	}
+}
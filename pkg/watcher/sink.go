@@ -0,0 +1,124 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+// Result is the classification of a new token.
+type Result struct {
+	Chain       string          `json:"chain"`
+	Token       common.Address  `json:"token"`
+	Source      Source          `json:"source"`
+	BlockNumber uint64          `json:"blockNumber"`
+	TxHash      common.Hash     `json:"txHash"`
+	Pool        *common.Address `json:"pool,omitempty"`
+	IsErc20     bool            `json:"isErc20"`
+	// IsFeeOnTransfer is only meaningful if Error is empty
+	IsFeeOnTransfer bool `json:"isFeeOnTransfer"`
+	// FeeBps is the largest fee of the simulated transfers in basis points
+	FeeBps int `json:"feeBps"`
+	// IsHoneypot is nil if the token has no pool or its pool holds none of it yet
+	IsHoneypot   *bool `json:"isHoneypot,omitempty"`
+	NumScenarios int   `json:"numScenarios"`
+	NumEqual     int   `json:"numEqual"`
+	NumLess      int   `json:"numLess"`
+	NumFailed    int   `json:"numFailed"`
+	// Override is set if the token is overridden, an ERC20 token was then not classified
+	Override *server.Override `json:"override,omitempty"`
+	// Error is set if the token could not be classified
+	Error      string    `json:"error,omitempty"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// Sink receives the results of a Watcher, Put is called concurrently.
+type Sink interface {
+	Put(ctx context.Context, result *Result) error
+}
+
+// SinkFunc is a Sink calling a function.
+type SinkFunc func(ctx context.Context, result *Result) error
+
+func (f SinkFunc) Put(ctx context.Context, result *Result) error {
+	return f(ctx, result)
+}
+
+// MultiSink puts results in all its sinks.
+type MultiSink []Sink
+
+func (m MultiSink) Put(ctx context.Context, result *Result) error {
+	for _, sink := range m {
+		if err := sink.Put(ctx, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONSink writes results as JSON lines.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{
+		enc: json.NewEncoder(w),
+	}
+}
+
+func (s *JSONSink) Put(ctx context.Context, result *Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(result); err != nil {
+		return fmt.Errorf("could not write result: %w", err)
+	}
+	return nil
+}
+
+// StoreSink stores the verdicts of classified results in a server.Store, so the HTTP API serves them.
+type StoreSink struct {
+	store server.Store
+}
+
+func NewStoreSink(store server.Store) *StoreSink {
+	return &StoreSink{
+		store: store,
+	}
+}
+
+func (s *StoreSink) Put(ctx context.Context, result *Result) error {
+	if result.Error != "" || result.Override != nil {
+		// an undecided token is classified again on request, an overridden one was not classified
+		return nil
+	}
+	verdict := &server.Verdict{
+		Chain:           result.Chain,
+		Token:           result.Token,
+		IsErc20:         result.IsErc20,
+		IsFeeOnTransfer: result.IsFeeOnTransfer,
+		FeeBps:          result.FeeBps,
+		Pool:            result.Pool,
+		IsHoneypot:      result.IsHoneypot,
+		Method:          server.MethodScenarios,
+		BlockNumber:     result.BlockNumber,
+		// the transaction deploying or pairing the token
+		Evidence:  []string{"tx:" + result.TxHash.Hex()},
+		UpdatedAt: result.DetectedAt,
+	}
+	if !result.IsErc20 {
+		verdict.Method = server.MethodCode
+	}
+	if err := s.store.PutVerdict(verdict); err != nil {
+		return fmt.Errorf("could not store verdict: %w", err)
+	}
+	return nil
+}
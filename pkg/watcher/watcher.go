@@ -0,0 +1,272 @@
+// Package watcher classifies new tokens as soon as they are deployed or paired. A Watcher follows the chain heads,
+// finds the contracts created in each block and the tokens paired by Uniswap V2 PairCreated and V3 PoolCreated events,
+// and classifies them by simulating transfers from their likely holders. Results go to a Sink.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+// Config configures a Watcher.
+type Config struct {
+	// FromBlock is the first block processed, the first confirmed head when 0
+	FromBlock uint64
+	// Confirmations is the number of blocks on top of a block before it is processed, so the tokens of blocks
+	// reorged out are not reported
+	Confirmations uint64
+	// Factories restricts the PairCreated and PoolCreated events to the ones of these factories, any emitter when empty
+	Factories []common.Address
+	// IgnoreTokens are not classified when paired, e.g. the wrapped native token and the stablecoins
+	IgnoreTokens []common.Address
+	// Workers is the number of tokens of a block classified concurrently
+	Workers int
+	// ClassifyTimeout cancels the classifications of a token running longer
+	ClassifyTimeout time.Duration
+	// Overrides pin the verdicts of tokens, overridden tokens are not classified. Optional.
+	Overrides server.Overrides
+}
+
+var DefaultConfig = Config{
+	Confirmations:   3,
+	Workers:         4,
+	ClassifyTimeout: 2 * time.Minute,
+}
+
+// Watcher classifies the new tokens of a chain.
+type Watcher struct {
+	name   string
+	chain  *server.Chain
+	heads  Heads
+	sink   Sink
+	config Config
+	ignore map[common.Address]bool
+}
+
+// NewWatcher name is the name of the chain in the results. The nodes of chain must support debug_traceBlockByNumber
+// and debug_traceCall with callTracer and prestateTracer, unless its profile tells they don't.
+func NewWatcher(name string, chain *server.Chain, heads Heads, sink Sink, config Config) *Watcher {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.ClassifyTimeout <= 0 {
+		config.ClassifyTimeout = DefaultConfig.ClassifyTimeout
+	}
+	ignore := make(map[common.Address]bool, len(config.IgnoreTokens))
+	for _, token := range config.IgnoreTokens {
+		ignore[token] = true
+	}
+	return &Watcher{
+		name:   name,
+		chain:  chain,
+		heads:  heads,
+		sink:   sink,
+		config: config,
+		ignore: ignore,
+	}
+}
+
+// Run processes every block from FromBlock up to Confirmations blocks below the heads until ctx is done or the heads
+// subscription fails. A block that could not be fetched is processed again on the next head.
+func (w *Watcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		heads = make(chan uint64)
+		errc  = make(chan error, 1)
+		next  = w.config.FromBlock
+	)
+	go func() {
+		errc <- w.heads.Subscribe(ctx, heads)
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		case head := <-heads:
+			if head < w.config.Confirmations {
+				continue
+			}
+			confirmed := head - w.config.Confirmations
+			if next == 0 {
+				next = confirmed
+			}
+			for ; next <= confirmed; next++ {
+				if err := w.ProcessBlock(ctx, next); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					logger.Warnw("could not process block", "block", next, "error", err)
+					break
+				}
+			}
+		}
+	}
+}
+
+// ProcessBlock classifies the new tokens of a block and puts their results in the sink. Contracts created in the
+// block that are not ERC20 are skipped. An error is returned only if the block could not be fetched, classification
+// failures are reported in the results.
+func (w *Watcher) ProcessBlock(ctx context.Context, blockNumber uint64) error {
+	candidates, err := w.candidates(ctx, blockNumber)
+	if err != nil {
+		return err
+	}
+	if len(candidates) > 0 {
+		logger.Infow("new token candidates", "block", blockNumber, "candidates", len(candidates))
+	}
+
+	var (
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, w.config.Workers)
+	)
+	for _, c := range candidates {
+		select {
+		case <-ctx.Done():
+		case workers <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(c *Candidate) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			result := w.classify(ctx, c)
+			if result == nil {
+				return
+			}
+			if err := w.sink.Put(ctx, result); err != nil {
+				logger.Errorw("could not put result", "token", c.Token, "error", err)
+			}
+		}(c)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// candidates returns the contracts created in a block and the tokens paired in it. Contract creations are only found
+// on blocks the nodes can trace, the other blocks only yield paired tokens.
+func (w *Watcher) candidates(ctx context.Context, blockNumber uint64) ([]*Candidate, error) {
+	blockNumberHex := hexutil.EncodeUint64(blockNumber)
+
+	var (
+		candidates []*Candidate
+		senders    []common.Address
+	)
+	if profile := w.chain.Profile; profile.Tracers.CallTracer && profile.CanTrace(blockNumber) {
+		var traces []jsonrpc.TxTraceResult
+		tracer := &jsonrpc.DebugTraceCallTracerConfigParam{
+			Tracer: "callTracer",
+		}
+		if err := w.chain.Client.CallContext(ctx, &traces, "debug_traceBlockByNumber", blockNumberHex, tracer); err != nil {
+			return nil, fmt.Errorf("could not trace block: %w", err)
+		}
+		frames, err := decodeTraces(traces)
+		if err != nil {
+			return nil, err
+		}
+		txHashes := make([]common.Hash, len(traces))
+		senders = make([]common.Address, len(frames))
+		for i := range traces {
+			txHashes[i] = traces[i].TxHash
+			senders[i] = frames[i].From
+		}
+		candidates = contractCreations(blockNumber, txHashes, frames)
+	}
+
+	query := map[string]interface{}{
+		"fromBlock": blockNumberHex,
+		"toBlock":   blockNumberHex,
+		"topics":    [][]common.Hash{{pairCreatedID, poolCreatedID}},
+	}
+	if len(w.config.Factories) > 0 {
+		query["address"] = w.config.Factories
+	}
+	var logs []ethtypes.Log
+	if err := w.chain.Client.CallContext(ctx, &logs, "eth_getLogs", query); err != nil {
+		return nil, fmt.Errorf("could not get pool creations: %w", err)
+	}
+
+	return append(candidates, poolCreations(logs, senders, w.ignore)...), nil
+}
+
+// classify classifies a candidate with synthetic scenarios unless it is overridden, it returns nil for created contracts
+// that are not ERC20.
+func (w *Watcher) classify(ctx context.Context, c *Candidate) *Result {
+	ctx, cancel := context.WithTimeout(ctx, w.config.ClassifyTimeout)
+	defer cancel()
+
+	result := &Result{
+		Chain:       w.name,
+		Token:       c.Token,
+		Source:      c.Source,
+		BlockNumber: c.BlockNumber,
+		TxHash:      c.TxHash,
+		Pool:        c.Pool,
+		DetectedAt:  time.Now(),
+	}
+	override, err := server.ActiveOverride(w.config.Overrides, w.name, c.Token, result.DetectedAt)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Override = override
+	// an override pins the fee of a token, whether it is ERC20 is still told by its code
+	isErc20, err := w.chain.IsErc20(c.Token)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !isErc20 {
+		if c.Source == SourceContractCreation {
+			return nil
+		}
+		return result
+	}
+	result.IsErc20 = true
+	if override != nil {
+		result.IsFeeOnTransfer = override.IsFeeOnTransfer
+		result.FeeBps = override.FeeBps
+		return result
+	}
+
+	scenarios, err := w.chain.SyntheticScenarios(c.Token, c.Holders, c.BlockNumber)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	batch := w.chain.ClassifyScenarios(ctx, c.Token, scenarios)
+	result.NumScenarios = batch.NumScenarios
+	result.NumEqual = batch.NumEqual
+	result.NumLess = batch.NumLess
+	result.NumFailed = batch.NumFailed
+	if batch.Err != nil {
+		result.Error = batch.Err.Error()
+	} else {
+		result.IsFeeOnTransfer = batch.IsFeeOnTransfer
+		result.FeeBps = batch.FeeBps
+	}
+	if c.Pool != nil {
+		// a transfer from the pool is a buy, the receiver then sells back to the pool
+		result.IsHoneypot = w.chain.ProbeHoneypot(*c.Pool, scenarios)
+	}
+	return result
+}
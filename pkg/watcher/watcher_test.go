@@ -0,0 +1,228 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/classifier/jsonrpc"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/fetcher"
+	"github.com/KyberNetwork/erc20-contract-classification/pkg/server"
+)
+
+var (
+	deployer = common.HexToAddress("0xd0")
+	factory  = common.HexToAddress("0xfa")
+	created  = common.HexToAddress("0xc1")
+	reverted = common.HexToAddress("0xc2")
+	newToken = common.HexToAddress("0x70")
+	weth     = common.HexToAddress("0xee")
+	pair     = common.HexToAddress("0xbb")
+)
+
+func address(a common.Address) *common.Address { return &a }
+
+// blockTraces is a block whose first tx deploys a contract through a factory and whose second tx reverts a creation
+var blockTraces = []*jsonrpc.CallFrame{
+	{
+		Type: "CALL",
+		From: deployer,
+		To:   address(factory),
+		Calls: []jsonrpc.CallFrame{
+			{Type: "CREATE2", From: factory, To: address(created)},
+		},
+	},
+	{
+		Type:  "CREATE",
+		From:  deployer,
+		To:    address(reverted),
+		Error: "execution reverted",
+	},
+}
+
+func pairCreatedLog() ethtypes.Log {
+	return ethtypes.Log{
+		Address:     factory,
+		Topics:      []common.Hash{pairCreatedID, common.BytesToHash(newToken.Bytes()), common.BytesToHash(weth.Bytes())},
+		Data:        append(common.BytesToHash(pair.Bytes()).Bytes(), common.BigToHash(common.Big1).Bytes()...),
+		BlockNumber: 10,
+		TxIndex:     1,
+	}
+}
+
+// blockClient serves a block with blockTraces and a PairCreated event, and returns a code that is not ERC20
+type blockClient struct{}
+
+func (blockClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	var value interface{}
+	switch method {
+	case "debug_traceBlockByNumber":
+		var traces []jsonrpc.TxTraceResult
+		for _, frame := range blockTraces {
+			raw, err := json.Marshal(frame)
+			if err != nil {
+				return err
+			}
+			traces = append(traces, jsonrpc.TxTraceResult{TxHash: common.BigToHash(common.Big1), Result: raw})
+		}
+		value = traces
+	case "eth_getLogs":
+		value = []ethtypes.Log{pairCreatedLog()}
+	case "eth_getCode":
+		value = "0x6000"
+	default:
+		return fmt.Errorf("unexpected method %s", method)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, result)
+}
+
+func (c blockClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for i := range b {
+		b[i].Error = c.CallContext(ctx, b[i].Result, b[i].Method, b[i].Args...)
+	}
+	return nil
+}
+
+func TestContractCreations(t *testing.T) {
+	candidates := contractCreations(10, make([]common.Hash, len(blockTraces)), blockTraces)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, created, candidates[0].Token)
+	assert.Equal(t, SourceContractCreation, candidates[0].Source)
+	assert.Equal(t, []common.Address{deployer, created}, candidates[0].Holders)
+}
+
+func TestPoolCreations(t *testing.T) {
+	poolCreated := ethtypes.Log{
+		Topics: []common.Hash{poolCreatedID, common.BytesToHash(newToken.Bytes()), common.BytesToHash(weth.Bytes()), common.BigToHash(common.Big3)},
+		Data:   append(common.BigToHash(common.Big1).Bytes(), common.BytesToHash(pair.Bytes()).Bytes()...),
+	}
+	tests := []struct {
+		name       string
+		log        ethtypes.Log
+		ignore     map[common.Address]bool
+		wantTokens []common.Address
+		wantSource Source
+	}{
+		{"pair created", pairCreatedLog(), nil, []common.Address{newToken, weth}, SourcePairCreated},
+		{"ignored token", pairCreatedLog(), map[common.Address]bool{weth: true}, []common.Address{newToken}, SourcePairCreated},
+		{"pool created", poolCreated, nil, []common.Address{newToken, weth}, SourcePoolCreated},
+		{"other event", ethtypes.Log{Topics: []common.Hash{{}, {}, {}}, Data: make([]byte, 64)}, nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := poolCreations([]ethtypes.Log{tt.log}, nil, tt.ignore)
+			var tokens []common.Address
+			for _, c := range candidates {
+				tokens = append(tokens, c.Token)
+				assert.Equal(t, tt.wantSource, c.Source)
+				assert.Equal(t, pair, *c.Pool)
+			}
+			assert.Equal(t, tt.wantTokens, tokens)
+		})
+	}
+}
+
+func TestProcessBlock(t *testing.T) {
+	var (
+		chain   = server.NewChain(blockClient{}, nil, fetcher.DefaultConfig, classifier.DefaultBatchClassifierConfig, 100)
+		mu      sync.Mutex
+		results []*Result
+		sink    = SinkFunc(func(ctx context.Context, result *Result) error {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, result)
+			return nil
+		})
+		config = DefaultConfig
+	)
+	config.IgnoreTokens = []common.Address{weth}
+	w := NewWatcher("ethereum", chain, nil, sink, config)
+
+	require.NoError(t, w.ProcessBlock(context.Background(), 10))
+	// the created contract is not ERC20 so it is skipped, the paired token is reported
+	require.Len(t, results, 1)
+	assert.Equal(t, newToken, results[0].Token)
+	assert.Equal(t, SourcePairCreated, results[0].Source)
+	assert.Equal(t, "ethereum", results[0].Chain)
+	assert.False(t, results[0].IsErc20)
+	assert.Empty(t, results[0].Error)
+
+	// an overridden token is reported with its override, which does not make it ERC20 nor fee-on-transfer
+	overrides, err := server.NewOverrideList([]*server.Override{
+		{Chain: "ethereum", Token: newToken, IsFeeOnTransfer: true, FeeBps: 300, Reason: "tax"},
+	})
+	require.NoError(t, err)
+	config.Overrides = overrides
+	results = nil
+	w = NewWatcher("ethereum", chain, nil, sink, config)
+	require.NoError(t, w.ProcessBlock(context.Background(), 10))
+	require.Len(t, results, 1)
+	assert.False(t, results[0].IsErc20)
+	assert.False(t, results[0].IsFeeOnTransfer)
+	require.NotNil(t, results[0].Override)
+	assert.Equal(t, "tax", results[0].Override.Reason)
+}
+
+// tracedBlocks records the blocks traced through blockClient
+type tracedBlocks struct {
+	blockClient
+	mu     sync.Mutex
+	blocks []string
+}
+
+func (c *tracedBlocks) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if method == "debug_traceBlockByNumber" {
+		c.mu.Lock()
+		c.blocks = append(c.blocks, args[0].(string))
+		c.mu.Unlock()
+	}
+	return c.blockClient.CallContext(ctx, result, method, args...)
+}
+
+func (c *tracedBlocks) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for i := range b {
+		b[i].Error = c.CallContext(ctx, b[i].Result, b[i].Method, b[i].Args...)
+	}
+	return nil
+}
+
+// headList sends its heads, the send of the last one returning once the head before it is processed
+type headList []uint64
+
+func (l headList) Subscribe(ctx context.Context, heads chan<- uint64) error {
+	for _, head := range l {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case heads <- head:
+		}
+	}
+	return errors.New("no more heads")
+}
+
+func TestRunConfirmations(t *testing.T) {
+	client := &tracedBlocks{}
+	chain := server.NewChain(client, nil, fetcher.DefaultConfig, classifier.DefaultBatchClassifierConfig, 100)
+	sink := SinkFunc(func(ctx context.Context, result *Result) error { return nil })
+	config := DefaultConfig
+	config.Confirmations = 2
+
+	w := NewWatcher("ethereum", chain, headList{1, 12, 13, 16, 16}, sink, config)
+	assert.EqualError(t, w.Run(context.Background()), "no more heads")
+	// head 1 has no confirmed block yet, blocks are processed from the first confirmed head up to 2 blocks below the last
+	assert.Equal(t, []string{"0xa", "0xb", "0xc", "0xd", "0xe"}, client.blocks)
+}